@@ -9,9 +9,10 @@ import (
 )
 
 type ToolsCli struct {
-	User             *security.UserProfile
-	Log              *log.Logger
-	WinMowerRegistry *pkg.WinMowerRegistry
-	BundleRegistry   *pkg.BundleRegistry
-	Client           *http.Client
+	User                  *security.UserProfile
+	Log                   *log.Logger
+	WinMowerRegistry      *pkg.WinMowerRegistry
+	BundleRegistry        *pkg.BundleRegistry
+	TifDefinitionRegistry *pkg.TifDefinitionRegistry
+	Client                *http.Client
 }
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/Tifufu/tools-cli/pkg"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DefinitionOptions select the definition a command uses, either a local file
+// or the definition of a firmware version fetched from the bundle registry.
+type DefinitionOptions struct {
+	// Path of the definition file, set to the fetched definition once resolved
+	Filepath string
+	Platform pkg.Platform
+	Version  string
+}
+
+// AddFlags adds the definition flags to flags of cmd, and resolves the definition
+// of a firmware version to a file before cmd or any of its subcommands run.
+// The flags are optional, commands that need a definition mark them required.
+func (opts *DefinitionOptions) AddFlags(tCli *ToolsCli, cmd *cobra.Command, flags *pflag.FlagSet) {
+	flags.StringVarP(&opts.Filepath, "def", "d", "", "Path to the tif definition file")
+	flags.Var(&opts.Platform, "platform", "Platform to fetch the tif definition of, instead of using --def")
+	flags.StringVar(&opts.Version, "version", "", "Firmware version to fetch the tif definition of, such as 47.35 (default: latest)")
+	cmd.MarkFlagsMutuallyExclusive("def", "platform")
+	cmd.MarkFlagFilename("def", "json")
+
+	cmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		err := opts.resolve(cmd, tCli)
+		if err != nil {
+			tCli.Log.Fatal("failed to fetch tif definition", "platform", opts.Platform, "version", opts.Version, "error", err)
+		}
+	}
+}
+
+func (opts *DefinitionOptions) resolve(cmd *cobra.Command, tCli *ToolsCli) error {
+	if opts.Platform == "" {
+		if opts.Version != "" {
+			return errors.New("--version requires --platform")
+		}
+		return nil
+	}
+
+	def, err := tCli.TifDefinitionRegistry.FetchDefinition(cmd.Context(), opts.Platform, opts.Version)
+	if err != nil {
+		return err
+	}
+	tCli.Log.Debug("Fetched tif definition", "platform", def.Platform, "version", def.Version, "path", def.Path)
+	opts.Filepath = def.Path
+	return nil
+}
+
+// LoadIndex loads and indexes a definition, reusing the index cached in the config dir
+// from an earlier load of the same definition.
+func LoadIndex(logger *log.Logger, path string) (*tif.Index, error) {
+	if filepath.IsLocal(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(wd, path)
+		logger.Debug("local path provided, combined into absolute", "result", path)
+	}
+
+	start := time.Now()
+	idx, cached, err := tif.LoadIndex(path, filepath.Join(ConfigDir(), "tifdef-cache"))
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("tif-definition loaded", "took", time.Since(start).Round(time.Millisecond), "cached", cached)
+
+	return idx, nil
+}
@@ -14,7 +14,12 @@ func NewDeviceCommand(tCli *cli.ToolsCli) *cobra.Command {
 		},
 	}
 
-	cmd.AddCommand(newOpenCommand(tCli))
+	cmd.AddCommand(
+		newOpenCommand(tCli),
+		newReplayCommand(tCli),
+		newProxyCommand(tCli),
+		newEmulateCommand(tCli),
+	)
 
 	return cmd
 }
@@ -0,0 +1,75 @@
+package device
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/emulator"
+	"github.com/spf13/cobra"
+)
+
+type emulateOptions struct {
+	cli.DefinitionOptions
+	listen     string
+	scriptPath string
+}
+
+func newEmulateCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &emulateOptions{}
+	cmd := &cobra.Command{
+		Use:   "emulate",
+		Short: "Emulate a device for offline development",
+		Long: `Emulate a device for offline development.
+
+The emulator answers link manager commands on its own. Methods in the tif definition
+are answered with the responses in the script, or with an empty response with status 0.
+The script can also send periodic broadcasts, and give connections a login level that
+restricts the methods they may call until they log in. See emulator.Script for its format.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEmulate(tCli, *opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.listen, "listen", "l", ":4250", "Address to accept connections on")
+	opts.DefinitionOptions.AddFlags(tCli, cmd, cmd.Flags())
+	cmd.Flags().StringVarP(&opts.scriptPath, "script", "s", "", "Path to a JSON script with responses and broadcasts")
+	cmd.MarkFlagFilename("script", "json")
+
+	return cmd
+}
+
+func runEmulate(tCli *cli.ToolsCli, opts emulateOptions) error {
+	var emulatorOpts []emulator.Option
+	if opts.Filepath != "" {
+		idx, err := cli.LoadIndex(tCli.Log, opts.Filepath)
+		if err != nil {
+			return err
+		}
+		emulatorOpts = append(emulatorOpts, emulator.WithDefinition(idx.Definition()))
+	}
+	if opts.scriptPath != "" {
+		script, err := emulator.LoadScript(opts.scriptPath)
+		if err != nil {
+			return err
+		}
+		scriptOpts, err := script.Options()
+		if err != nil {
+			return err
+		}
+		emulatorOpts = append(emulatorOpts, scriptOpts...)
+	}
+
+	listener, err := net.Listen("tcp", opts.listen)
+	if err != nil {
+		return err
+	}
+	tCli.Log.Info("Emulating device", "listen", listener.Addr())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	return emulator.NewEmulator(tCli.Log, emulatorOpts...).Serve(ctx, listener)
+}
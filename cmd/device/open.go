@@ -2,19 +2,32 @@ package device
 
 import (
 	"context"
-	"net"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/Tifufu/tools-cli/cmd/cli"
 	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
 	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/serial"
+	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 )
 
 type openOptions struct {
-	address string
-	network string
+	address    string
+	network    string
+	baudRate   int
+	timeout    time.Duration
+	broadcasts bool
+	families   []uint
+	senders    []uint
+	channels   []uint
+	statsEvery time.Duration
+	bufferSize int
+	overflow   automower.OverflowPolicy
+	recordPath string
 }
 
 func newOpenCommand(tCli *cli.ToolsCli) *cobra.Command {
@@ -23,7 +36,12 @@ func newOpenCommand(tCli *cli.ToolsCli) *cobra.Command {
 		Use:   "open",
 		Short: "Open a device and print its data",
 		Run: func(cmd *cobra.Command, args []string) {
-			conn, err := net.Dial(opts.network, opts.address)
+			err := automower.CheckPacketBuffer(opts.bufferSize, opts.overflow)
+			if err != nil {
+				tCli.Log.Fatal("Invalid --buffer", "err", err)
+			}
+
+			conn, err := automower.OpenStream(opts.network, opts.address, opts.baudRate)
 			if err != nil {
 				tCli.Log.Fatal("Error opening device", "err", err)
 			}
@@ -32,10 +50,26 @@ func newOpenCommand(tCli *cli.ToolsCli) *cobra.Command {
 			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 			defer cancel()
 
-			device := automower.NewDevice(conn, ctx)
+			// The device outlives ctx so that links can be closed after an interrupt
+			deviceCtx, deviceCancel := context.WithCancel(context.Background())
+			defer deviceCancel()
+			device := automower.NewDevice(conn, deviceCtx, automower.WithPacketBuffer(opts.bufferSize, opts.overflow))
 			defer device.Close()
 
 			linkMux := linking.NewLinkMux(device, tCli.Log)
+			if opts.recordPath != "" {
+				recorder, err := capture.Create(opts.recordPath)
+				if err != nil {
+					tCli.Log.Fatal("Error creating capture", "err", err)
+				}
+				defer func() {
+					err := recorder.Close()
+					if err != nil {
+						tCli.Log.Error("Error closing capture", "err", err)
+					}
+				}()
+				linkMux.Record(recorder)
+			}
 			go func() {
 				err := linkMux.Start()
 				if err != nil && err != linking.ErrLinkMuxShuttingDown {
@@ -43,26 +77,113 @@ func newOpenCommand(tCli *cli.ToolsCli) *cobra.Command {
 				}
 			}()
 
-			// time.Sleep(2 * time.Second)
-			// _, err = linkMux.DefaultLink.SendLinkedRequest(linking.ControlLinkManagerCommand, []byte{0x12, 0x01, 0x00, 0x00, 0x00})
-			// if err != nil {
-			// 	tCli.Log.Error("err sending link manager command", "err", err)
-			// }
-			_, err = linkMux.DefaultLink.SendLinkedRequest(linking.ControlLinkManagerCommand, []byte{0x08, 0x01})
+			reqCtx, reqCancel := context.WithTimeout(ctx, opts.timeout)
+			link, err := linkMux.OpenLink(reqCtx)
+			if err != nil {
+				reqCancel()
+				tCli.Log.Error("Error opening link", "err", err)
+				linkMux.Stop()
+				return
+			}
+			err = link.SetProtocol(reqCtx, linking.RoboticsProtocol2)
+			reqCancel()
 			if err != nil {
-				tCli.Log.Error("err sending link manager command", "err", err)
+				tCli.Log.Error("Error setting link protocol", "linkId", link.Id(), "err", err)
+			} else {
+				tCli.Log.Info("Opened link", "linkId", link.Id())
 			}
 
-			// linkMux.Write([]byte{0x02, 0xFD, 0x0D, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x63, 0x12, 0x9F, 0x57, 0xF7, 0x70, 0x8A, 0x03})
+			if opts.broadcasts {
+				sub, err := linkMux.Subscribe(opts.broadcastFilter())
+				if err != nil {
+					tCli.Log.Fatal("Error subscribing to broadcasts", "err", err)
+				}
+				go printBroadcasts(tCli.Log, sub)
+			}
+
+			if opts.statsEvery > 0 {
+				go func() {
+					ticker := time.NewTicker(opts.statsEvery)
+					defer ticker.Stop()
+					for {
+						select {
+						case <-ticker.C:
+							logFrameStats(tCli.Log, linkMux.Stats(), device)
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+			}
 
 			<-ctx.Done()
+
+			closeCtx, closeCancel := context.WithTimeout(context.Background(), opts.timeout)
+			defer closeCancel()
+			for _, link := range linkMux.Links() {
+				err := link.Close(closeCtx)
+				if err != nil {
+					tCli.Log.Error("Error closing link", "linkId", link.Id(), "err", err)
+				}
+			}
 			linkMux.Stop()
+			logFrameStats(tCli.Log, linkMux.Stats(), device)
 		},
 	}
 
 	// todo: Add defaults to config
-	openCmd.Flags().StringVarP(&opts.address, "address", "a", "127.0.0.1:4250", "Network address of the device")
-	openCmd.Flags().StringVarP(&opts.network, "network", "n", "tcp", "Network type of the device")
+	openCmd.Flags().StringVarP(&opts.address, "address", "a", "127.0.0.1:4250", "Network address of the device, or the serial port when the network is serial")
+	openCmd.Flags().StringVarP(&opts.network, "network", "n", "tcp", "Network type of the device, serial for serial ports")
+	openCmd.Flags().IntVar(&opts.baudRate, "baud", serial.DefaultBaudRate, "Baud rate of the serial port")
+	openCmd.Flags().DurationVarP(&opts.timeout, "timeout", "t", 5*time.Second, "Time to wait for a response from the device")
+
+	openCmd.Flags().BoolVarP(&opts.broadcasts, "broadcasts", "b", false, "Print broadcasts sent by the device")
+	openCmd.Flags().UintSliceVar(&opts.families, "family", nil, "Only print broadcasts from these message families")
+	openCmd.Flags().UintSliceVar(&opts.senders, "sender", nil, "Only print broadcasts from these sender ids")
+	openCmd.Flags().UintSliceVar(&opts.channels, "channel", nil, "Only print broadcasts on these broadcast channels")
+	openCmd.Flags().IntVar(&opts.bufferSize, "buffer", automower.DefaultPacketBufferSize, "Number of received packets to buffer")
+	openCmd.Flags().Var(&opts.overflow, "overflow", "What to do when the packet buffer is full: block, drop-oldest or drop-newest")
+	openCmd.Flags().StringVar(&opts.recordPath, "record", "", "Record every frame to a capture file")
+	openCmd.Flags().DurationVar(&opts.statsEvery, "stats", 0, "Interval at which to print frame statistics, disabled when 0")
 
 	return openCmd
 }
+
+func (opts openOptions) broadcastFilter() linking.BroadcastFilter {
+	filter := linking.BroadcastFilter{}
+	for _, family := range opts.families {
+		filter.Families = append(filter.Families, uint16(family))
+	}
+	for _, sender := range opts.senders {
+		filter.Senders = append(filter.Senders, byte(sender))
+	}
+	for _, channel := range opts.channels {
+		filter.Channels = append(filter.Channels, byte(channel))
+	}
+	return filter
+}
+
+func printBroadcasts(logger *log.Logger, sub *linking.Subscription) {
+	for b := range sub.C() {
+		logger.Info("Broadcast",
+			"family", b.MessageFamily,
+			"sender", b.SenderId,
+			"channel", b.BroadcastChannel,
+			"payload", b.Payload.String(),
+			"dropped", sub.Dropped(),
+		)
+	}
+}
+
+func logFrameStats(logger *log.Logger, stats linking.FrameStats, device *automower.Device) {
+	logger.Info("Frame statistics",
+		"bufferDropped", device.Dropped(),
+		"received", stats.Received,
+		"corrupted", stats.Corrupted,
+		"headerCrc", stats.HeaderCrcErrors,
+		"footerCrc", stats.FooterCrcErrors,
+		"length", stats.LengthErrors,
+		"dropped", stats.Dropped,
+		"unknownType", stats.UnknownTypes,
+	)
+}
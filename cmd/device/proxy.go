@@ -0,0 +1,99 @@
+package device
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/protocol/proxy"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type proxyOptions struct {
+	listen     string
+	upstream   string
+	recordPath string
+	quiet      bool
+}
+
+func newProxyCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &proxyOptions{}
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Proxy traffic to a device and print every frame",
+		Long: `Proxy traffic to a device and print every frame.
+
+Point a client, such as TifConsole, at the listen address instead of the device.
+Frames sent by the client are printed with ->, frames sent by the device with <-.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runProxy(tCli, *opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.listen, "listen", "l", ":4251", "Address to accept client connections on")
+	cmd.Flags().StringVarP(&opts.upstream, "upstream", "u", "127.0.0.1:4250", "Network address of the device")
+	cmd.Flags().StringVar(&opts.recordPath, "record", "", "Record every frame to a capture file")
+	cmd.Flags().BoolVarP(&opts.quiet, "quiet", "q", false, "Do not print frames")
+
+	return cmd
+}
+
+func runProxy(tCli *cli.ToolsCli, opts proxyOptions) error {
+	var recorder *capture.Writer
+	if opts.recordPath != "" {
+		var err error
+		recorder, err = capture.Create(opts.recordPath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := recorder.Close()
+			if err != nil {
+				tCli.Log.Error("Error closing capture", "err", err)
+			}
+		}()
+	}
+
+	onFrame := func(connId uint64, direction capture.Direction, frame []byte) {
+		if recorder != nil {
+			err := recorder.Write(capture.Record{Time: time.Now(), Direction: direction, Frame: frame})
+			if err != nil {
+				tCli.Log.Error("Error recording frame", "err", err)
+			}
+		}
+		if !opts.quiet {
+			printFrame(tCli.Log, connId, direction, frame)
+		}
+	}
+
+	listener, err := net.Listen("tcp", opts.listen)
+	if err != nil {
+		return err
+	}
+	tCli.Log.Info("Listening for clients", "listen", listener.Addr(), "upstream", opts.upstream)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	return proxy.NewProxy(opts.upstream, onFrame, tCli.Log).Serve(ctx, listener)
+}
+
+func printFrame(logger *log.Logger, connId uint64, direction capture.Direction, rawFrame []byte) {
+	arrow := "<-"
+	if direction == capture.Sent {
+		arrow = "->"
+	}
+
+	frame, err := linking.ParseFrame(rawFrame)
+	if err != nil {
+		logger.Warn(arrow, "conn", connId, "err", err, "frame", linking.Payload(rawFrame).String())
+		return
+	}
+	logger.Info(arrow, "conn", connId, "frame", frame.String())
+}
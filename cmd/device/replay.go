@@ -0,0 +1,84 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/spf13/cobra"
+)
+
+type replayOptions struct {
+	realtime bool
+}
+
+func newReplayCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &replayOptions{}
+	cmd := &cobra.Command{
+		Use:   "replay <capture>",
+		Short: "Replay a capture recorded with device open --record as if the device was attached",
+		Long: `Replay a capture recorded with device open --record as if the device was attached.
+
+The received frames are fed through the link layer, printing broadcasts
+and frame statistics. Run with --debug to also see every parsed linked packet.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReplay(tCli, args[0], *opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.realtime, "realtime", "r", false, "Replay frames with the same delays as when they were captured")
+
+	return cmd
+}
+
+func runReplay(tCli *cli.ToolsCli, path string, opts replayOptions) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := capture.NewReader(file)
+	if err != nil {
+		return err
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	tCli.Log.Debugf("Replaying %d records", len(records))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	device := automower.NewDevice(capture.NewReplayStream(records, opts.realtime), ctx)
+	linkMux := linking.NewLinkMux(device, tCli.Log)
+	defer linkMux.Stop()
+
+	sub, err := linkMux.Subscribe(linking.BroadcastFilter{})
+	if err != nil {
+		return err
+	}
+	printed := make(chan struct{})
+	go func() {
+		printBroadcasts(tCli.Log, sub)
+		close(printed)
+	}()
+
+	err = linkMux.Start()
+	linkMux.Stop()
+	<-printed
+	logFrameStats(tCli.Log, linkMux.Stats(), device)
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, linking.ErrLinkMuxShuttingDown) {
+		return err
+	}
+	return nil
+}
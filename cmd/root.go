@@ -77,7 +77,10 @@ func init() {
 		func() {
 			tCli.WinMowerRegistry = pkg.NewWinMowerRegistry(filepath.Join(cli.ConfigDir(), "winmowers"), tCli.BundleRegistry, tCli.Log)
 
+			tCli.TifDefinitionRegistry = pkg.NewTifDefinitionRegistry(filepath.Join(cli.ConfigDir(), "tifdefs"), tCli.BundleRegistry, tCli.WinMowerRegistry, tCli.Log)
+
 			tCli.WinMowerRegistry.WithClient(*tCli.Client)
+			tCli.TifDefinitionRegistry.WithClient(*tCli.Client)
 			tCli.BundleRegistry.WithClient(*tCli.Client)
 		},
 	)
@@ -0,0 +1,95 @@
+package tifdefinition
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/Tifufu/tools-cli/internal/tifclient"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type attrOptions struct {
+	connectOptions
+	definitionOptions
+	json bool
+}
+
+func newAttrCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &attrOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "attr",
+		Short: "Read and write attributes on a device",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.PersistentFlags())
+	cmd.PersistentFlags().BoolVar(&opts.json, "json", false, "Print the response as JSON")
+	opts.connectOptions.addFlags(cmd.PersistentFlags())
+
+	getCmd := &cobra.Command{
+		Use:     "get <Family.Name> [read argument...]",
+		Short:   "Read an attribute through its read command",
+		Example: `  tools tifdef attr get --def main.json Mower.CuttingHeight`,
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := runAttr(tCli.Log, *opts, func(ctx context.Context, client *tifclient.Client) (tif.MethodDefinition, tif.Response, error) {
+				return client.ReadAttribute(ctx, args[0], args[1:])
+			})
+			exitOnError(tCli.Log, err)
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <Family.Name> <value...>",
+		Short: "Write an attribute through its write command",
+		Long: `Write an attribute through its write command.
+
+Takes one value for each parameter of the attribute. Every value is checked
+against the type and range of its parameter before it is sent to the device.`,
+		Example: `  tools tifdef attr set --def main.json Mower.CuttingHeight 40`,
+		Args:    cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := runAttr(tCli.Log, *opts, func(ctx context.Context, client *tifclient.Client) (tif.MethodDefinition, tif.Response, error) {
+				return client.WriteAttribute(ctx, args[0], args[1:])
+			})
+			exitOnError(tCli.Log, err)
+		},
+	}
+
+	cmd.AddCommand(getCmd, setCmd)
+	return cmd
+}
+
+func runAttr(logger *log.Logger, opts attrOptions, do func(context.Context, *tifclient.Client) (tif.MethodDefinition, tif.Response, error)) error {
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	client, closeClient, err := connect(logger, idx, opts.connectOptions)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	method, response, err := do(context.Background(), client)
+	if err != nil && response.Status == tif.StatusOk {
+		return err
+	}
+
+	logger.Debug("Attribute accessed", "method", method.Name())
+	printErr := printResponse(method, response, opts.json)
+	if err != nil && opts.json && printErr == nil {
+		return errReported
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", method.Name(), err)
+	}
+	return printErr
+}
@@ -0,0 +1,203 @@
+package tifdefinition
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type callOptions struct {
+	connectOptions
+	definitionOptions
+	json     bool
+	dryRun   bool
+	response string
+}
+
+func newCallCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &callOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "call <Family.Command(param: value, ...)>",
+		Short: "Call a method on a device and print its out parameters",
+		Long: `Call a method on a device and print its out parameters.
+
+Values containing spaces or commas, such as strings, are written in
+double quotes, for example 'Mower.SetName(name: "Front lawn")'.
+
+Exits with a non-zero status when the device does not respond in time
+or responds with an error status. Use --dry-run to print the encoded
+request without connecting to a device.`,
+		Example: `  tools tifdef call --def main.json --address 127.0.0.1:4250 'Battery.GetCharge(cell: 1)'`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(tCli.Log, runCall(tCli.Log, args[0], *opts))
+		},
+	}
+
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.Flags())
+	opts.connectOptions.addFlags(cmd.Flags())
+
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print the response as JSON")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the encoded request instead of sending it")
+	cmd.Flags().StringVarP(&opts.response, "response", "r", "", "With --dry-run, hex encoded response payload to decode, for example from the debug log")
+
+	return cmd
+}
+
+func runCall(logger *log.Logger, call string, opts callOptions) error {
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		return runDryCall(idx, call, opts)
+	}
+
+	client, closeClient, err := connect(logger, idx, opts.connectOptions)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	method, response, err := client.CallString(context.Background(), call)
+	if err != nil && response.Status == tif.StatusOk {
+		return err
+	}
+
+	// Responses with an error status are printed before failing
+	printErr := printResponse(method, response, opts.json)
+	if err != nil && opts.json && printErr == nil {
+		return errReported
+	}
+	if err != nil {
+		return err
+	}
+	return printErr
+}
+
+func runDryCall(idx *tif.Index, call string, opts callOptions) error {
+	name, args, err := tif.ParseCall(call)
+	if err != nil {
+		return err
+	}
+	method, ok := idx.Method(name)
+	if !ok {
+		return fmt.Errorf("could not find method for %s", name)
+	}
+	printUsage(method)
+
+	encoder := tif.NewEncoder(idx.Definition())
+	values, err := encoder.ParseArguments(method, args)
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		fmt.Printf("%s: %v\n", method.InParams[i].Name, value)
+	}
+
+	payload, err := encoder.EncodeCall(method, values)
+	if err != nil {
+		return err
+	}
+	control := "payload"
+	if method.IsLinked() {
+		control = "link-manager"
+	}
+	fmt.Printf("Payload (%s, %d bytes): % X\n", control, len(payload), payload)
+
+	if opts.response == "" {
+		return nil
+	}
+	responsePayload, err := hex.DecodeString(strings.ReplaceAll(opts.response, " ", ""))
+	if err != nil {
+		return fmt.Errorf("malformed response; expected hex encoded payload: %w", err)
+	}
+	response, err := tif.NewDecoder(idx.Definition()).DecodeResponse(method, responsePayload)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	return printResponse(method, response, opts.json)
+}
+
+type responseOutput struct {
+	Method string        `json:"method"`
+	Status byte          `json:"status"`
+	Values []valueOutput `json:"values"`
+}
+
+type valueOutput struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Value   any    `json:"value"`
+	Enum    string `json:"enum,omitempty"`
+	Postfix string `json:"postfix,omitempty"`
+}
+
+func printResponse(method tif.MethodDefinition, response tif.Response, asJson bool) error {
+	if !asJson {
+		fmt.Printf("Status: %d\n", response.Status)
+		for _, value := range response.Values {
+			fmt.Printf("%s: %s\n", value.Name, value)
+		}
+		return nil
+	}
+
+	output := responseOutput{
+		Method: method.Name(),
+		Status: response.Status,
+		Values: make([]valueOutput, len(response.Values)),
+	}
+	for i, value := range response.Values {
+		v := value.Value
+		switch v.(type) {
+		case tif.TypeByteArray, tif.TypeBit, tif.TypeDateTime, tif.TypeRoboticsVersion:
+			v = tif.FormatType(v)
+		}
+		output.Values[i] = valueOutput{
+			Name:    value.Name,
+			Type:    value.Type,
+			Value:   v,
+			Enum:    value.Enum,
+			Postfix: value.Postfix,
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+func printUsage(method tif.MethodDefinition) {
+	fmt.Println("Method found")
+	fmt.Printf("Family: %s\n", method.Family)
+	fmt.Printf("Command: %s\n", method.Command)
+	fmt.Printf("Description: %s\n", method.Description)
+	fmt.Printf("ElementType: %s\n", method.ElementType)
+	if len(method.InParams) > 0 {
+		fmt.Printf("InParams: %v\n", method.InParams)
+	}
+	if len(method.OutParams) > 0 {
+		fmt.Printf("OutParams: %v\n", method.OutParams)
+	}
+	fmt.Printf("Tags: %v\n", method.Tags)
+	fmt.Printf("LoginLevels: %v\n", method.LoginLevels)
+
+	args := make([]string, len(method.InParams))
+	for i, arg := range method.InParams {
+		args[i] = fmt.Sprintf("%s: %s", arg.Name, arg.Type)
+	}
+	argsStr := strings.Join(args, ", ")
+
+	fmt.Printf("Usage: %s.%s(%s)\n\n", method.Family, method.Command, argsStr)
+}
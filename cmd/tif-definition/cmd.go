@@ -1,10 +1,40 @@
 package tifdefinition
 
 import (
+	"errors"
+	"os"
+
 	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// definitionOptions are the definition flags of commands that can not run without a definition.
+type definitionOptions struct {
+	cli.DefinitionOptions
+}
+
+func (opts *definitionOptions) addFlags(tCli *cli.ToolsCli, cmd *cobra.Command, flags *pflag.FlagSet) {
+	opts.AddFlags(tCli, cmd, flags)
+	cmd.MarkFlagsOneRequired("def", "platform")
+}
+
+// errReported is returned by commands that already printed why they failed as JSON,
+// and must exit with a non-zero status without writing anything more to stdout.
+var errReported = errors.New("failure already reported")
+
+// exitOnError exits with a non-zero status if err is not nil, logging err unless it was already reported.
+func exitOnError(logger *log.Logger, err error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, errReported) {
+		os.Exit(1)
+	}
+	logger.Fatal(err)
+}
+
 func NewTifDefinitionCommand(tCli *cli.ToolsCli) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "tifdef",
@@ -16,7 +46,13 @@ func NewTifDefinitionCommand(tCli *cli.ToolsCli) *cobra.Command {
 
 	cmd.AddCommand(
 		newListCommand(tCli),
-		newTestCommand(tCli),
+		newCallCommand(tCli),
+		newAttrCommand(tCli),
+		newReplCommand(tCli),
+		newValidateCommand(tCli),
+		newDiffCommand(tCli),
+		newCodegenCommand(tCli),
+		newDocsCommand(tCli),
 	)
 
 	return cmd
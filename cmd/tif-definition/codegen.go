@@ -0,0 +1,114 @@
+package tifdefinition
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/tifgen"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type codegenLanguage string
+
+const (
+	codegenLanguageGo codegenLanguage = "go"
+)
+
+func (l codegenLanguage) String() string {
+	return string(l)
+}
+
+func (l *codegenLanguage) Set(s string) error {
+	switch codegenLanguage(s) {
+	case codegenLanguageGo:
+		*l = codegenLanguage(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid language: %s. Must be one of [go]", s)
+	}
+}
+
+func (l *codegenLanguage) Type() string {
+	return "language"
+}
+
+type codegenOptions struct {
+	definitionOptions
+	lang codegenLanguage
+	pkg  string
+	out  string
+}
+
+func newCodegenCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &codegenOptions{lang: codegenLanguageGo}
+
+	cmd := &cobra.Command{
+		Use:   "codegen",
+		Short: "Generate a typed client from a tif definition",
+		Long: `Generate a typed client from a tif definition.
+
+Each method family becomes a struct with a function per method, taking its
+in parameters as typed arguments and returning its out parameters as a
+response struct. Enum types become named types with a constant per
+enumerator. The ids and parameters of methods are generated into the code,
+which encodes calls and sends them on a Link, such as a link of the link
+mux. It only imports the standard library, so it compiles in any module.
+
+The generated code is written to stdout unless --out is given.`,
+		Example: `  tools tifdef codegen --def main.json --lang go --package mower --out mower/client.go`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := runCodegen(tCli.Log, *opts)
+			if err != nil {
+				tCli.Log.Fatal(err)
+			}
+		},
+	}
+
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.Flags())
+	cmd.Flags().VarP(&opts.lang, "lang", "l", "Language to generate, one of go")
+	cmd.Flags().StringVarP(&opts.pkg, "package", "p", "", "Package name of the generated code (default: name of the output directory, or tifclient)")
+	cmd.Flags().StringVarP(&opts.out, "out", "o", "", "Path to write the generated code to")
+
+	return cmd
+}
+
+func runCodegen(logger *log.Logger, opts codegenOptions) error {
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	pkg := opts.pkg
+	if pkg == "" {
+		pkg = "tifclient"
+		if opts.out != "" {
+			if dir, err := filepath.Abs(filepath.Dir(opts.out)); err == nil {
+				pkg = filepath.Base(dir)
+			}
+		}
+	}
+
+	code, err := tifgen.GenerateGo(idx, pkg)
+	if err != nil {
+		return err
+	}
+
+	if opts.out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(opts.out), 0o755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(opts.out, code, 0o644)
+	if err != nil {
+		return err
+	}
+	logger.Info("Generated client", "lang", opts.lang, "package", pkg, "file", opts.out)
+	return nil
+}
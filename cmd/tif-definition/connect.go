@@ -0,0 +1,145 @@
+package tifdefinition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/serial"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/Tifufu/tools-cli/internal/tifclient"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/pflag"
+)
+
+type loginCheck string
+
+const (
+	loginCheckOff     loginCheck = "off"
+	loginCheckWarn    loginCheck = "warn"
+	loginCheckEnforce loginCheck = "enforce"
+)
+
+func (c loginCheck) String() string {
+	return string(c)
+}
+
+func (c *loginCheck) Set(s string) error {
+	switch loginCheck(s) {
+	case loginCheckOff, loginCheckWarn, loginCheckEnforce:
+		*c = loginCheck(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid login check: %s. Must be one of [off warn enforce]", s)
+	}
+}
+
+func (c *loginCheck) Type() string {
+	return "check"
+}
+
+func (c loginCheck) clientCheck() tifclient.LoginCheck {
+	switch c {
+	case loginCheckOff:
+		return tifclient.LoginCheckOff
+	case loginCheckEnforce:
+		return tifclient.LoginCheckEnforce
+	default:
+		return tifclient.LoginCheckWarn
+	}
+}
+
+type connectOptions struct {
+	address    string
+	network    string
+	baudRate   int
+	timeout    time.Duration
+	loginLevel string
+	login      string
+	loginCheck loginCheck
+}
+
+func (opts *connectOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&opts.address, "address", "a", "127.0.0.1:4250", "Network address of the device, or the serial port when the network is serial")
+	flags.StringVarP(&opts.network, "network", "n", "tcp", "Network type of the device, serial for serial ports")
+	flags.IntVar(&opts.baudRate, "baud", serial.DefaultBaudRate, "Baud rate of the serial port")
+	flags.DurationVarP(&opts.timeout, "timeout", "t", tifclient.DefaultResponseTimeout, "Time to wait for a response to methods without a maximum response time")
+	flags.StringVar(&opts.loginLevel, "login-level", "", "Login level the session is at, or logs in at with --login. Calls are not checked without one")
+	flags.StringVar(&opts.login, "login", "", "Call that logs the session in at --login-level, such as 'Security.Login(code: 1234)'")
+	opts.loginCheck = loginCheckWarn
+	flags.Var(&opts.loginCheck, "login-check", "What to do before calling methods above the login level, one of off, warn or enforce")
+}
+
+// connect opens a tif client on the device. The returned function closes the
+// client and everything beneath it, and must be called when done.
+func connect(logger *log.Logger, idx *tif.Index, opts connectOptions) (*tifclient.Client, func(), error) {
+	conn, err := automower.OpenStream(opts.network, opts.address, opts.baudRate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deviceCtx, deviceCancel := context.WithCancel(context.Background())
+	device := automower.NewDevice(conn, deviceCtx)
+	mux := linking.NewLinkMux(device, logger)
+	go func() {
+		err := mux.Start()
+		if err != nil && err != linking.ErrLinkMuxShuttingDown {
+			logger.Error("Link host error", "err", err)
+		}
+	}()
+	stop := func() {
+		mux.Stop()
+		deviceCancel()
+		conn.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+	client, err := tifclient.NewClient(ctx, mux, idx)
+	if err != nil {
+		stop()
+		return nil, nil, err
+	}
+	client.ResponseTimeout = opts.timeout
+	client.LoginCheck = opts.loginCheck.clientCheck()
+	client.Logger = logger
+
+	closeClient := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+		defer cancel()
+		err := client.Close(ctx)
+		if err != nil {
+			logger.Error("Error closing link", "err", err)
+		}
+		stop()
+	}
+
+	err = login(logger, client, opts)
+	if err != nil {
+		closeClient()
+		return nil, nil, err
+	}
+	return client, closeClient, nil
+}
+
+// login sets the login level of the session, performing the login handshake first if there is one.
+func login(logger *log.Logger, client *tifclient.Client, opts connectOptions) error {
+	if opts.login == "" {
+		return client.SetLoginLevel(opts.loginLevel)
+	}
+	if opts.loginLevel == "" {
+		return errors.New("--login requires the --login-level it logs in at")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+	err := client.Login(ctx, opts.login, opts.loginLevel)
+	if err != nil {
+		return err
+	}
+	logger.Debug("Logged in", "level", client.LoginLevel)
+	return nil
+}
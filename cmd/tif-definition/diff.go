@@ -0,0 +1,185 @@
+package tifdefinition
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type diffFormat string
+
+const (
+	diffFormatText     diffFormat = "text"
+	diffFormatJson     diffFormat = "json"
+	diffFormatMarkdown diffFormat = "md"
+)
+
+func (f diffFormat) String() string {
+	return string(f)
+}
+
+func (f *diffFormat) Set(s string) error {
+	switch diffFormat(s) {
+	case diffFormatText, diffFormatJson, diffFormatMarkdown:
+		*f = diffFormat(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid format: %s. Must be one of [text json md]", s)
+	}
+}
+
+func (f *diffFormat) Type() string {
+	return "format"
+}
+
+type diffOptions struct {
+	format       diffFormat
+	failBreaking bool
+}
+
+func newDiffCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &diffOptions{format: diffFormatText}
+
+	cmd := &cobra.Command{
+		Use:   "diff <old.json> <new.json>",
+		Short: "Report the methods, attributes and types that differ between two tif definitions",
+		Long: `Report the methods, attributes and types that differ between two tif definitions.
+
+Changes that may make clients of the old definition fail with the new one,
+such as removed methods, parameter type changes and removed login levels,
+are marked as breaking. Each side is identified by the tif versions in its header.`,
+		Example: `  tools tifdef diff v1.json v2.json --format md > release-notes.md`,
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(tCli.Log, runDiff(tCli.Log, args[0], args[1], *opts))
+		},
+	}
+
+	cmd.Flags().VarP(&opts.format, "format", "f", "Output format, one of text, json or md")
+	cmd.Flags().BoolVar(&opts.failBreaking, "fail-on-breaking", false, "Exit with a non-zero status if any change is breaking")
+
+	return cmd
+}
+
+func runDiff(logger *log.Logger, oldPath, newPath string, opts diffOptions) error {
+	oldIdx, err := cli.LoadIndex(logger, oldPath)
+	if err != nil {
+		return err
+	}
+	newIdx, err := cli.LoadIndex(logger, newPath)
+	if err != nil {
+		return err
+	}
+
+	diff := tif.DiffDefinitions(oldIdx.Definition(), newIdx.Definition())
+	switch opts.format {
+	case diffFormatJson:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	case diffFormatMarkdown:
+		err = writeDiffMarkdown(os.Stdout, diff)
+	default:
+		err = writeDiffText(os.Stdout, diff)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.failBreaking && diff.HasBreaking() {
+		if opts.format == diffFormatJson {
+			return errReported
+		}
+		return fmt.Errorf("definitions have breaking changes")
+	}
+	return nil
+}
+
+func versionsOf(versions []string) string {
+	if len(versions) == 0 {
+		return "unknown version"
+	}
+	return strings.Join(versions, ", ")
+}
+
+func writeDiffText(w io.Writer, diff tif.DefinitionDiff) error {
+	fmt.Fprintf(w, "%s -> %s\n", versionsOf(diff.OldVersions), versionsOf(diff.NewVersions))
+	if len(diff.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+
+	for _, change := range diff.Changes {
+		marker := ""
+		if change.Breaking {
+			marker = " [breaking]"
+		}
+		fmt.Fprintf(w, "%s %s %s%s\n", change.Kind, change.Element, change.Name, marker)
+		for _, detail := range change.Details {
+			marker := ""
+			if detail.Breaking {
+				marker = " [breaking]"
+			}
+			fmt.Fprintf(w, "  %s%s\n", detail.Description, marker)
+		}
+	}
+	return nil
+}
+
+func writeDiffMarkdown(w io.Writer, diff tif.DefinitionDiff) error {
+	fmt.Fprintf(w, "# Definition changes %s to %s\n", versionsOf(diff.OldVersions), versionsOf(diff.NewVersions))
+	if len(diff.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "\nNo changes.")
+		return err
+	}
+
+	sections := []struct {
+		title   string
+		element string
+	}{
+		{"Methods", tif.ElementMethod},
+		{"Attributes", tif.ElementAttribute},
+		{"Types", tif.ElementType},
+	}
+	for _, section := range sections {
+		var changes []tif.Change
+		for _, change := range diff.Changes {
+			if change.Element == section.element {
+				changes = append(changes, change)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n## %s\n\n", section.title)
+		fmt.Fprintln(w, "| Change | Name | Details | Breaking |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- |")
+		for _, change := range changes {
+			details := make([]string, len(change.Details))
+			for i, detail := range change.Details {
+				details[i] = escapeMarkdownCell(detail.Description)
+				if detail.Breaking {
+					details[i] = "**" + details[i] + "**"
+				}
+			}
+			breaking := ""
+			if change.Breaking {
+				breaking = "yes"
+			}
+			fmt.Fprintf(w, "| %s | `%s` | %s | %s |\n", change.Kind, change.Name, strings.Join(details, "<br>"), breaking)
+		}
+	}
+	return nil
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
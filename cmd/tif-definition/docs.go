@@ -0,0 +1,100 @@
+package tifdefinition
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/tifgen"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type docsFormat string
+
+func (f docsFormat) String() string {
+	return string(f)
+}
+
+func (f *docsFormat) Set(s string) error {
+	switch tifgen.DocsFormat(s) {
+	case tifgen.DocsHTML, tifgen.DocsMarkdown:
+		*f = docsFormat(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid format: %s. Must be one of [html md]", s)
+	}
+}
+
+func (f *docsFormat) Type() string {
+	return "format"
+}
+
+type docsOptions struct {
+	definitionOptions
+	out    string
+	format docsFormat
+}
+
+func newDocsCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &docsOptions{format: docsFormat(tifgen.DocsHTML)}
+
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate browsable documentation from a tif definition",
+		Long: `Generate browsable documentation from a tif definition.
+
+Writes an index, a page per family with its methods and attributes, and a
+page of types to the output directory. Parameters link to their types,
+attributes to their commands, and types to the methods using them.
+
+HTML sites can be searched from every page, also when opened from disk.
+Markdown sites come with the same search index as search-index.json.`,
+		Example: `  tools tifdef docs --def main.json --out site/
+  tools tifdef docs --def main.json --out docs/ --format md`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := runDocs(tCli.Log, *opts)
+			if err != nil {
+				tCli.Log.Fatal(err)
+			}
+		},
+	}
+
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.Flags())
+	cmd.Flags().StringVarP(&opts.out, "out", "o", "", "Directory to write the documentation to")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagDirname("out")
+	cmd.Flags().VarP(&opts.format, "format", "f", "Output format, one of html or md")
+
+	return cmd
+}
+
+func runDocs(logger *log.Logger, opts docsOptions) error {
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	files, err := tifgen.GenerateDocs(idx, tifgen.DocsFormat(opts.format))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		path := filepath.Join(opts.out, filepath.FromSlash(file.Path))
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(path, file.Content, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	index := filepath.Join(opts.out, "index."+string(opts.format))
+	logger.Info("Generated documentation", "files", len(files), "index", index)
+	return nil
+}
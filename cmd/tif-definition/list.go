@@ -1,29 +1,108 @@
 package tifdefinition
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/Tifufu/tools-cli/cmd/cli"
 	"github.com/Tifufu/tools-cli/internal/tif"
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+type listKind string
+
+const (
+	listKindAttributes listKind = "attributes"
+	listKindMethods    listKind = "methods"
+	listKindTypes      listKind = "types"
+)
+
+func (k listKind) String() string {
+	return string(k)
+}
+
+func (k *listKind) Set(s string) error {
+	switch listKind(s) {
+	case listKindAttributes, listKindMethods, listKindTypes:
+		*k = listKind(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid kind: %s. Must be one of [attributes methods types]", s)
+	}
+}
+
+func (k *listKind) Type() string {
+	return "kind"
+}
+
+type listFormat string
+
+const (
+	listFormatTable listFormat = "table"
+	listFormatJson  listFormat = "json"
+	listFormatYaml  listFormat = "yaml"
+	listFormatCsv   listFormat = "csv"
+)
+
+func (f listFormat) String() string {
+	return string(f)
+}
+
+func (f *listFormat) Set(s string) error {
+	switch listFormat(s) {
+	case listFormatTable, listFormatJson, listFormatYaml, listFormatCsv:
+		*f = listFormat(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid format: %s. Must be one of [table json yaml csv]", s)
+	}
+}
+
+func (f *listFormat) Type() string {
+	return "format"
+}
+
 type listOptions struct {
-	filepath     string
+	definitionOptions
+	kind         listKind
+	format       listFormat
 	familyFilter string
 	nameFilter   string
+	regex        bool
+	tag          string
+	operation    string
+	loginLevel   string
+	search       string
 }
 
 func newListCommand(tCli *cli.ToolsCli) *cobra.Command {
-	opts := &listOptions{}
+	opts := &listOptions{kind: listKindAttributes, format: listFormatTable}
 
 	cmd := &cobra.Command{
 		Use:   "list",
-		Short: "List tif definitions",
+		Short: "List the attributes, methods or types of a tif definition",
+		Long: `List the attributes, methods or types of a tif definition.
+
+The family and name filters are globs, such as 'Mower.*' or '*Height',
+or regular expressions with --regex. Names match with or without their
+family. All filters and the search ignore case.
+
+Types have no family, tags, operations or login levels, and methods have
+no operations, so filtering them by those lists nothing. Methods and
+attributes without login levels are listed at every login level.`,
+		Example: `  tools tifdef list --def main.json --family 'Mow*' --operation write
+  tools tifdef list --def main.json --kind methods --tag status --output csv
+  tools tifdef list --def main.json --kind types --search 'cutting height' --output json
+  tools tifdef list --platform P21 --version 47.35 --kind methods`,
+		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			err := runList(tCli.Log, *opts)
 			if err != nil {
@@ -32,64 +111,279 @@ func newListCommand(tCli *cli.ToolsCli) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&opts.filepath, "def", "d", "", "Path to the tif definition file")
-	cmd.MarkFlagRequired("def")
-	cmd.MarkFlagFilename("def", "json")
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.Flags())
 
+	cmd.Flags().VarP(&opts.kind, "kind", "k", "What to list, one of attributes, methods or types")
+	cmd.Flags().VarP(&opts.format, "output", "o", "Output format, one of table, json, yaml or csv")
 	cmd.Flags().StringVarP(&opts.familyFilter, "family", "f", "", "Family to filter output by")
-	cmd.Flags().StringVarP(&opts.nameFilter, "name", "n", "", "Attribute name to filter output by")
+	cmd.Flags().StringVarP(&opts.nameFilter, "name", "n", "", "Name to filter output by")
+	cmd.Flags().BoolVar(&opts.regex, "regex", false, "Filter by family and name with regular expressions instead of globs")
+	cmd.Flags().StringVarP(&opts.tag, "tag", "t", "", "Tag to filter output by")
+	cmd.Flags().StringVar(&opts.operation, "operation", "", "Operation of attributes to filter output by, such as read, write or list")
+	cmd.Flags().StringVar(&opts.loginLevel, "login-level", "", "Login level to filter output by")
+	cmd.Flags().StringVarP(&opts.search, "search", "s", "", "Words that must all be in the name or description")
 
 	return cmd
 }
 
 func runList(logger *log.Logger, opts listOptions) error {
-	if filepath.IsLocal(opts.filepath) {
-		wd, err := os.Getwd()
+	filter, err := opts.filter()
+	if err != nil {
+		return err
+	}
+
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	var rows listRows
+	switch opts.kind {
+	case listKindMethods:
+		rows = newMethodRows(filter.Methods(idx))
+	case listKindTypes:
+		rows = newTypeRows(filter.Types(idx))
+	default:
+		rows = newAttributeRows(filter.Attributes(idx))
+	}
+	return writeList(os.Stdout, rows, opts.format)
+}
+
+func (opts listOptions) filter() (tif.Filter, error) {
+	filter := tif.Filter{
+		Tag:        opts.tag,
+		Operation:  opts.operation,
+		LoginLevel: opts.loginLevel,
+		Search:     opts.search,
+	}
+	newPattern := tif.NewGlobPattern
+	if opts.regex {
+		newPattern = tif.NewRegexPattern
+	}
+
+	var err error
+	if opts.familyFilter != "" {
+		filter.Family, err = newPattern(opts.familyFilter)
 		if err != nil {
-			return err
+			return tif.Filter{}, err
+		}
+	}
+	if opts.nameFilter != "" {
+		filter.Name, err = newPattern(opts.nameFilter)
+		if err != nil {
+			return tif.Filter{}, err
 		}
+	}
+	return filter, nil
+}
+
+// listRows are rows of any kind, listed as a table or CSV by their columns and as JSON or YAML as they are.
+type listRows interface {
+	columns() []string
+	// values returns the cells of each row, joining lists with sep
+	values(sep string) [][]string
+}
+
+type attributeRow struct {
+	Name        string   `json:"name" yaml:"name"`
+	Family      string   `json:"family" yaml:"family"`
+	Description string   `json:"description" yaml:"description"`
+	Operations  []string `json:"operations" yaml:"operations"`
+	Read        string   `json:"read,omitempty" yaml:"read,omitempty"`
+	Write       string   `json:"write,omitempty" yaml:"write,omitempty"`
+	List        string   `json:"list,omitempty" yaml:"list,omitempty"`
+	Tags        []string `json:"tags" yaml:"tags"`
+	LoginLevels []string `json:"loginLevels" yaml:"loginLevels"`
+}
 
-		opts.filepath = filepath.Join(wd, opts.filepath)
-		logger.Debug("local path provided, combined into absolute", "result", opts.filepath)
+type attributeRows []attributeRow
+
+func newAttributeRows(attributes []tif.AttributeV2Definition) attributeRows {
+	rows := attributeRows{}
+	for _, attr := range attributes {
+		row := attributeRow{
+			Name:        attr.FullName(),
+			Family:      attr.Family,
+			Description: attr.Description,
+			Operations:  orEmpty(attr.Operations),
+			Tags:        orEmpty(attr.Tags),
+			LoginLevels: orEmpty(attr.Protocol.Read.LoginLevels),
+		}
+		row.Read, _ = attr.ReadCommand()
+		row.Write, _ = attr.WriteCommand()
+		if attr.List.Family != "" || attr.List.Name != "" {
+			row.List = fmt.Sprintf("%s.%s", attr.List.Family, attr.List.Name)
+		}
+		rows = append(rows, row)
 	}
+	return rows
+}
 
-	file, err := os.Open(opts.filepath)
-	if err != nil {
-		return err
+func (rows attributeRows) columns() []string {
+	return []string{"NAME", "OPERATIONS", "READ", "WRITE", "LIST", "TAGS", "LOGIN LEVELS", "DESCRIPTION"}
+}
+
+func (rows attributeRows) values(sep string) [][]string {
+	values := make([][]string, len(rows))
+	for i, row := range rows {
+		values[i] = []string{
+			row.Name,
+			strings.Join(row.Operations, sep),
+			row.Read,
+			row.Write,
+			row.List,
+			strings.Join(row.Tags, sep),
+			strings.Join(row.LoginLevels, sep),
+			row.Description,
+		}
 	}
-	defer file.Close()
+	return values
+}
 
-	decoder := json.NewDecoder(file)
-	var def tif.TifDefinition
-	err = decoder.Decode(&def)
-	if err != nil {
-		return err
+type methodRow struct {
+	Name            string   `json:"name" yaml:"name"`
+	Family          string   `json:"family" yaml:"family"`
+	Command         string   `json:"command" yaml:"command"`
+	Description     string   `json:"description" yaml:"description"`
+	Signature       string   `json:"signature" yaml:"signature"`
+	Tags            []string `json:"tags" yaml:"tags"`
+	LoginLevels     []string `json:"loginLevels" yaml:"loginLevels"`
+	MaxResponseTime string   `json:"maxResponseTime,omitempty" yaml:"maxResponseTime,omitempty"`
+}
+
+type methodRows []methodRow
+
+func newMethodRows(methods []tif.MethodDefinition) methodRows {
+	rows := methodRows{}
+	for _, method := range methods {
+		rows = append(rows, methodRow{
+			Name:            method.Name(),
+			Family:          method.Family,
+			Command:         method.Command,
+			Description:     method.Description,
+			Signature:       method.Signature(),
+			Tags:            orEmpty(method.Tags),
+			LoginLevels:     orEmpty(method.LoginLevels),
+			MaxResponseTime: method.MaxResponseTime,
+		})
 	}
+	return rows
+}
+
+func (rows methodRows) columns() []string {
+	return []string{"NAME", "SIGNATURE", "TAGS", "LOGIN LEVELS", "DESCRIPTION"}
+}
 
-	methodIdmap := make(map[string]tif.MethodDefinition, len(def.Methods))
-	for _, method := range def.Methods {
-		key := fmt.Sprintf("%s.%s", method.Family, method.Command)
-		methodIdmap[key] = method
+func (rows methodRows) values(sep string) [][]string {
+	values := make([][]string, len(rows))
+	for i, row := range rows {
+		values[i] = []string{
+			row.Name,
+			row.Signature,
+			strings.Join(row.Tags, sep),
+			strings.Join(row.LoginLevels, sep),
+			row.Description,
+		}
 	}
+	return values
+}
 
-	filterFamily, filterName := opts.familyFilter != "", opts.nameFilter != ""
-	for _, attr := range def.AttributesV2 {
-		if filterFamily && attr.Family != opts.familyFilter {
-			continue
+type typeRow struct {
+	Name        string           `json:"name" yaml:"name"`
+	Type        string           `json:"type" yaml:"type"`
+	Description string           `json:"description" yaml:"description"`
+	Postfix     string           `json:"postfix,omitempty" yaml:"postfix,omitempty"`
+	Range       string           `json:"range,omitempty" yaml:"range,omitempty"`
+	Enums       []typeEnumerator `json:"enums,omitempty" yaml:"enums,omitempty"`
+}
+
+type typeEnumerator struct {
+	Key         string `json:"key" yaml:"key"`
+	Value       int    `json:"value" yaml:"value"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type typeRows []typeRow
+
+func newTypeRows(types []tif.TypeV2Definition) typeRows {
+	rows := typeRows{}
+	for _, t := range types {
+		row := typeRow{
+			Name:        t.Name,
+			Type:        t.Type,
+			Description: t.Description,
+			Postfix:     t.Postfix,
+		}
+		if t.Range.Type == "range" {
+			row.Range = fmt.Sprintf("%d..%d", t.Range.Start, t.Range.Stop)
 		}
+		for _, enum := range t.Range.Enums {
+			row.Enums = append(row.Enums, typeEnumerator{Key: enum.Key, Value: enum.Value, Description: enum.Description})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
 
-		if filterName && attr.Name != opts.nameFilter {
-			continue
+func (rows typeRows) columns() []string {
+	return []string{"NAME", "TYPE", "POSTFIX", "RANGE", "ENUMS", "DESCRIPTION"}
+}
+
+func (rows typeRows) values(sep string) [][]string {
+	values := make([][]string, len(rows))
+	for i, row := range rows {
+		enums := make([]string, len(row.Enums))
+		for j, enum := range row.Enums {
+			enums[j] = enum.Key + "=" + strconv.Itoa(enum.Value)
 		}
+		values[i] = []string{row.Name, row.Type, row.Postfix, row.Range, strings.Join(enums, sep), row.Description}
+	}
+	return values
+}
 
-		fmt.Printf("%s.%s, %s\n", attr.Family, attr.Name, attr.Description)
-		if cmd, ok := attr.ReadCommand(); ok {
-			fmt.Printf("  %s\n", cmd)
+func writeList(w io.Writer, rows listRows, format listFormat) error {
+	switch format {
+	case listFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case listFormatYaml:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		err := encoder.Encode(rows)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	case listFormatCsv:
+		writer := csv.NewWriter(w)
+		err := writer.Write(rows.columns())
+		if err != nil {
+			return err
 		}
-		if cmd, ok := attr.WriteCommand(); ok {
-			fmt.Printf("  %s\n", cmd)
+		err = writer.WriteAll(rows.values(";"))
+		if err != nil {
+			return err
 		}
-		fmt.Println()
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(rows.columns(), "\t"))
+		for _, values := range rows.values(", ") {
+			// Only the first line of descriptions, and no tabs, fit in a table
+			for i, value := range values {
+				value, _, _ = strings.Cut(value, "\n")
+				values[i] = strings.ReplaceAll(value, "\t", " ")
+			}
+			fmt.Fprintln(writer, strings.Join(values, "\t"))
+		}
+		return writer.Flush()
+	}
+}
+
+func orEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
 	}
-	return nil
+	return values
 }
@@ -0,0 +1,186 @@
+package tifdefinition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/lineedit"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/Tifufu/tools-cli/internal/tifclient"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type replOptions struct {
+	connectOptions
+	definitionOptions
+	json   bool
+	dryRun bool
+}
+
+func newReplCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &replOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "repl",
+		Short: "Call methods on a device from an interactive shell",
+		Long: `Call methods on a device from an interactive shell.
+
+The definition is loaded and the device connected once for the whole session.
+Press tab to complete families, commands, parameter names and enum values.
+Entered lines are kept in a history file in the config directory.
+
+Besides calls, the shell takes the commands:
+  usage <Family.Command>  Print the definition of a method
+  login <level> <call>    Log in at a login level with a call, such as
+                          login Service Security.Login(code: 1234)
+  level                   Print the login level of the session
+  help                    Print this help
+  exit                    Leave the shell, as does Ctrl+D`,
+		Example: `  tools tifdef repl --def main.json --address 127.0.0.1:4250`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := runRepl(tCli.Log, cmd.Long, *opts)
+			if err != nil {
+				tCli.Log.Fatal(err)
+			}
+		},
+	}
+
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.Flags())
+	opts.connectOptions.addFlags(cmd.Flags())
+
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print responses as JSON")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the encoded requests instead of connecting to a device")
+
+	return cmd
+}
+
+func runRepl(logger *log.Logger, help string, opts replOptions) error {
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	var client *tifclient.Client
+	if !opts.dryRun {
+		var closeClient func()
+		client, closeClient, err = connect(logger, idx, opts.connectOptions)
+		if err != nil {
+			return err
+		}
+		defer closeClient()
+	}
+
+	historyPath := filepath.Join(cli.ConfigDir(), "tifdef_history")
+	history, err := lineedit.LoadHistory(historyPath, lineedit.DefaultHistorySize)
+	if err != nil {
+		logger.Warn("Could not load history", "path", historyPath, "err", err)
+		history = lineedit.NewHistory(lineedit.DefaultHistorySize)
+	}
+
+	completer := tif.NewCompleter(idx)
+	editor := lineedit.NewTerminalEditor()
+	editor.Prompt = "tif> "
+	editor.History = history
+	editor.Complete = func(text string) lineedit.Completion {
+		completion := completer.Complete(text)
+		return lineedit.Completion{
+			Start:      completion.Start,
+			Candidates: completion.Candidates,
+			Hint:       completion.Hint,
+		}
+	}
+
+	for {
+		line, err := editor.ReadLine()
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		err = history.Add(line)
+		if err != nil {
+			logger.Warn("Could not save history", "path", historyPath, "err", err)
+		}
+
+		command, arg, _ := strings.Cut(line, " ")
+		switch command {
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Println(help)
+			continue
+		case "usage":
+			method, ok := idx.Method(strings.TrimSpace(arg))
+			if !ok {
+				fmt.Printf("Could not find method for %s\n", arg)
+				continue
+			}
+			printUsage(method)
+			continue
+		case "login", "level":
+			if client == nil {
+				fmt.Println("Not connected to a device")
+				continue
+			}
+			if command == "level" {
+				fmt.Println(orUnknown(client.LoginLevel))
+				continue
+			}
+			level, call, _ := strings.Cut(strings.TrimSpace(arg), " ")
+			err = client.Login(context.Background(), strings.TrimSpace(call), level)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+			}
+			continue
+		}
+
+		if client == nil {
+			err = runDryCall(idx, line, callOptions{json: opts.json})
+		} else {
+			err = replCall(client, line, opts.json)
+		}
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+		}
+	}
+}
+
+// replCall performs a call entered in the shell, printing the signature of the method before its response.
+func replCall(client *tifclient.Client, call string, asJson bool) error {
+	method, response, err := client.CallString(context.Background(), call)
+	if method.Command != "" {
+		fmt.Println(method.Signature())
+	}
+	if err != nil && response.Status == tif.StatusOk {
+		return err
+	}
+
+	printErr := printResponse(method, response, asJson)
+	if err != nil {
+		return err
+	}
+	return printErr
+}
+
+func orUnknown(level string) string {
+	if level == "" {
+		return "unknown"
+	}
+	return level
+}
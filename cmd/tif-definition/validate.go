@@ -0,0 +1,92 @@
+package tifdefinition
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Tifufu/tools-cli/cmd/cli"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+type validateOptions struct {
+	definitionOptions
+	json bool
+}
+
+func newValidateCommand(tCli *cli.ToolsCli) *cobra.Command {
+	opts := &validateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check a tif definition for broken references",
+		Long: `Check a tif definition for broken references.
+
+Reports attribute commands that do not exist, parameters and types of
+unknown types, methods and types defined more than once, enums with
+repeated values or keys or values their primitive can not hold, types
+that refer to themselves, and invalid maximum response times.
+
+Exits with a non-zero status when any issue is found.`,
+		Example: `  tools tifdef validate --def main.json --json`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(tCli.Log, runValidate(tCli.Log, *opts))
+		},
+	}
+
+	opts.definitionOptions.addFlags(tCli, cmd, cmd.Flags())
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print the issues as JSON")
+
+	return cmd
+}
+
+type validateOutput struct {
+	Definition string      `json:"definition"`
+	Valid      bool        `json:"valid"`
+	Issues     []tif.Issue `json:"issues"`
+}
+
+func runValidate(logger *log.Logger, opts validateOptions) error {
+	idx, err := cli.LoadIndex(logger, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	issues := tif.ValidateDefinition(idx)
+	if opts.json {
+		output := validateOutput{
+			Definition: opts.Filepath,
+			Valid:      len(issues) == 0,
+			Issues:     issues,
+		}
+		if output.Issues == nil {
+			output.Issues = []tif.Issue{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(output)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+
+	if opts.json {
+		if len(issues) > 0 {
+			return errReported
+		}
+		return nil
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("definition has %d issues", len(issues))
+	}
+	def := idx.Definition()
+	logger.Info("Definition is valid", "methods", len(def.Methods), "attributes", len(def.AttributesV2), "types", len(def.TypesV2))
+	return nil
+}
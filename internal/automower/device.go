@@ -1,75 +1,247 @@
 package automower
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+)
+
+const (
+	DefaultPacketBufferSize = 16
 )
 
+// OverflowPolicy decides what happens to a received packet
+// when the packet buffer is full because nobody is reading PacketChan.
+type OverflowPolicy int
+
 const (
-	packetStart = 0x02
-	packetEnd   = 0x03
+	// Wait until there is room in the buffer. No packets are lost,
+	// but the device stops reading from the stream while waiting.
+	OverflowBlock OverflowPolicy = iota
+	// Drop the oldest buffered packet to make room for the new one.
+	OverflowDropOldest
+	// Drop the new packet.
+	OverflowDropNewest
 )
 
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropNewest:
+		return "drop-newest"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+func (p *OverflowPolicy) Set(s string) error {
+	switch s {
+	case "block":
+		*p = OverflowBlock
+	case "drop-oldest":
+		*p = OverflowDropOldest
+	case "drop-newest":
+		*p = OverflowDropNewest
+	default:
+		return fmt.Errorf("invalid overflow policy: %s. Must be one of [block drop-oldest drop-newest]", s)
+	}
+	return nil
+}
+
+func (p *OverflowPolicy) Type() string {
+	return "OverflowPolicy"
+}
+
+// ErrPacketBuffer is returned for a packet buffer size the overflow policy can not work with.
+type ErrPacketBuffer struct {
+	Size   int
+	Policy OverflowPolicy
+}
+
+func (e *ErrPacketBuffer) Error() string {
+	if e.Size < 0 {
+		return fmt.Sprintf("invalid packet buffer size %d, must not be negative", e.Size)
+	}
+	return fmt.Sprintf("invalid packet buffer size %d, %s needs a buffer of at least 1 packet", e.Size, e.Policy)
+}
+
+// CheckPacketBuffer checks that a packet buffer of size packets can be used with policy.
+// Dropping the oldest packet needs a buffer to drop it from.
+func CheckPacketBuffer(size int, policy OverflowPolicy) error {
+	if size < 0 || (size < 1 && policy == OverflowDropOldest) {
+		return &ErrPacketBuffer{Size: size, Policy: policy}
+	}
+	return nil
+}
+
+type DeviceOption func(*Device)
+
+// WithPacketBuffer sets how many received packets are buffered
+// in PacketChan and what to do when the buffer is full. A buffer rejected
+// by CheckPacketBuffer is not used, the device reports the error on ErrChan
+// and stops without reading.
+func WithPacketBuffer(size int, policy OverflowPolicy) DeviceOption {
+	return func(d *Device) {
+		err := CheckPacketBuffer(size, policy)
+		if err != nil {
+			d.optionErr = err
+			return
+		}
+		d.bufferSize = size
+		d.overflow = policy
+	}
+}
+
 type Device struct {
 	stream     io.ReadWriteCloser
-	ErrChan    chan error
+	bufferSize int
+	overflow   OverflowPolicy
+	dropped    atomic.Uint64
+	cancel     context.CancelFunc
+	closeOnce  sync.Once
+	closeErr   error
+	done       chan struct{}
+	optionErr  error
+
+	// Receives at most one error, the reason the device stopped reading.
+	ErrChan chan error
+	// Receives every packet read from the device, without start and end bytes.
+	// Closed when the device stops reading.
 	PacketChan chan []byte
 }
 
-func NewDevice(stream io.ReadWriteCloser, ctx context.Context) *Device {
+// NewDevice starts reading packets from stream. The device is closed when ctx is done.
+func NewDevice(stream io.ReadWriteCloser, ctx context.Context, opts ...DeviceOption) *Device {
+	ctx, cancel := context.WithCancel(ctx)
 	device := &Device{
 		stream:     stream,
+		bufferSize: DefaultPacketBufferSize,
+		overflow:   OverflowBlock,
+		cancel:     cancel,
+		done:       make(chan struct{}),
 		ErrChan:    make(chan error, 1),
-		PacketChan: make(chan []byte),
 	}
+	for _, opt := range opts {
+		opt(device)
+	}
+	device.PacketChan = make(chan []byte, device.bufferSize)
+	if device.optionErr != nil {
+		device.ErrChan <- device.optionErr
+		close(device.PacketChan)
+		close(device.done)
+		cancel()
+		return device
+	}
+
 	go device.watch(ctx)
+	go func() {
+		<-ctx.Done()
+		device.Close()
+	}()
 	return device
 }
 
-func (d Device) Write(b []byte) (int, error) {
+func (d *Device) Write(b []byte) (int, error) {
 	return d.stream.Write(b)
 }
 
+// Dropped returns the number of packets dropped by the overflow policy.
+func (d *Device) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// Done is closed once the device has stopped reading from the stream.
+func (d *Device) Done() <-chan struct{} {
+	return d.done
+}
+
 func (d *Device) watch(ctx context.Context) {
-	bufReader := bufio.NewReader(d.stream)
+	defer close(d.done)
+	defer close(d.PacketChan)
+	// Nothing more can be read, release the stream
+	defer d.cancel()
+
+	decoder := framing.NewDecoder(d.stream)
 	for {
-		_, err := bufReader.ReadBytes(packetStart)
+		packet, err := decoder.ReadFrame()
 		if err != nil {
-			d.ErrChan <- fmt.Errorf("failed to read packet start: %w", err)
+			if ctx.Err() == nil {
+				d.ErrChan <- fmt.Errorf("failed to read frame: %w", err)
+			}
 			return
 		}
 
-		packet, err := bufReader.ReadBytes(packetEnd)
-		if err != nil {
-			d.ErrChan <- fmt.Errorf("failed to read to end of packet end: %w", err)
+		if !d.deliver(ctx, packet) {
 			return
 		}
-		if len(packet) < 2 {
-			continue
-		}
+	}
+}
 
-		packet = packet[:len(packet)-1]
+// deliver puts packet in PacketChan according to the overflow policy.
+// Returns false if ctx was done before the packet could be delivered.
+func (d *Device) deliver(ctx context.Context, packet []byte) bool {
+	switch d.overflow {
+	case OverflowDropNewest:
+		select {
+		case d.PacketChan <- packet:
+		default:
+			d.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case d.PacketChan <- packet:
+				return true
+			default:
+			}
 
+			select {
+			case <-d.PacketChan:
+				d.dropped.Add(1)
+			case <-ctx.Done():
+				return false
+			default:
+			}
+		}
+	default:
 		select {
 		case d.PacketChan <- packet:
 		case <-ctx.Done():
-			return
-		default:
+			return false
 		}
 	}
+	return true
 }
 
-func (d Device) Close() error {
-	if d.stream == nil {
-		return nil
-	}
+// Close closes the stream and waits for the device to stop reading from it.
+func (d *Device) Close() error {
+	d.closeOnce.Do(func() {
+		d.cancel()
+		if d.stream == nil {
+			return
+		}
 
-	err := d.stream.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close device stream: %w", err)
-	}
+		err := d.stream.Close()
+		if err != nil && !isClosedErr(err) {
+			d.closeErr = fmt.Errorf("failed to close device stream: %w", err)
+		}
+	})
 
-	return nil
+	<-d.done
+	return d.closeErr
+}
+
+// isClosedErr reports whether err is the result of closing an already closed stream.
+func isClosedErr(err error) bool {
+	return errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrClosed)
 }
@@ -0,0 +1,202 @@
+package automower
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+)
+
+// linkedFrame builds a linked packet frame on link 0 with a single byte payload.
+func linkedFrame(payload byte) []byte {
+	buf := []byte{framing.PacketStart, 0xFD}
+	buf = binary.LittleEndian.AppendUint16(buf, 9)
+	buf = append(buf, 0x00, 0x00, 0x00, 0x00, 0x01)
+	buf = append(buf, framing.Crc8(buf[1:framing.HeaderSize]))
+	buf = append(buf, payload)
+	buf = append(buf, framing.Crc8(buf[1:]))
+	return append(buf, framing.PacketEnd)
+}
+
+func payloadOf(packet []byte) byte {
+	return packet[framing.HeaderSize]
+}
+
+// writeFrames writes a frame for every payload to conn and waits until the device has read them all.
+func writeFrames(t *testing.T, conn net.Conn, payloads ...byte) {
+	t.Helper()
+	for _, payload := range payloads {
+		_, err := conn.Write(linkedFrame(payload))
+		if err != nil {
+			t.Fatalf("failed to write frame: %v", err)
+		}
+	}
+}
+
+func TestDeviceOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := map[OverflowPolicy]struct {
+		expected []byte
+		dropped  uint64
+	}{
+		OverflowDropNewest: {expected: []byte{0, 1}, dropped: 3},
+		OverflowDropOldest: {expected: []byte{3, 4}, dropped: 3},
+	}
+
+	for policy, test := range tests {
+		t.Run(policy.String(), func(t *testing.T) {
+			policy, test := policy, test
+			t.Parallel()
+
+			deviceConn, remote := net.Pipe()
+			device := NewDevice(deviceConn, context.Background(), WithPacketBuffer(2, policy))
+			defer device.Close()
+
+			// net.Pipe is unbuffered, the last write returns once the device has read it
+			writeFrames(t, remote, 0, 1, 2, 3, 4)
+			remote.Close()
+			<-device.Done()
+
+			var received []byte
+			for packet := range device.PacketChan {
+				received = append(received, payloadOf(packet))
+			}
+
+			if string(received) != string(test.expected) {
+				t.Errorf("expected payloads %v but got %v", test.expected, received)
+			}
+			if device.Dropped() != test.dropped {
+				t.Errorf("expected %d dropped packets but got %d", test.dropped, device.Dropped())
+			}
+		})
+	}
+}
+
+func TestDeviceBlockIsLossless(t *testing.T) {
+	t.Parallel()
+
+	deviceConn, remote := net.Pipe()
+	device := NewDevice(deviceConn, context.Background(), WithPacketBuffer(1, OverflowBlock))
+	defer device.Close()
+
+	const numPackets = 20
+	go func() {
+		for i := 0; i < numPackets; i++ {
+			remote.Write(linkedFrame(byte(i)))
+		}
+		remote.Close()
+	}()
+
+	var received []byte
+	for packet := range device.PacketChan {
+		time.Sleep(time.Millisecond) // slow consumer
+		received = append(received, payloadOf(packet))
+	}
+
+	if len(received) != numPackets {
+		t.Fatalf("expected %d packets but got %d", numPackets, len(received))
+	}
+	for i, payload := range received {
+		if payload != byte(i) {
+			t.Errorf("expected packet %d to have payload %d but got %d", i, i, payload)
+		}
+	}
+	if device.Dropped() != 0 {
+		t.Errorf("expected no dropped packets but got %d", device.Dropped())
+	}
+}
+
+func TestDeviceReportsStreamError(t *testing.T) {
+	t.Parallel()
+
+	deviceConn, remote := net.Pipe()
+	device := NewDevice(deviceConn, context.Background())
+	defer device.Close()
+
+	remote.Close()
+
+	select {
+	case err := <-device.ErrChan:
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected EOF but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an error after the stream was closed")
+	}
+	if _, ok := <-device.PacketChan; ok {
+		t.Error("expected PacketChan to be closed")
+	}
+}
+
+func TestDeviceCloseStopsWatcher(t *testing.T) {
+	t.Parallel()
+
+	deviceConn, remote := net.Pipe()
+	defer remote.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	device := NewDevice(deviceConn, ctx, WithPacketBuffer(0, OverflowBlock))
+
+	// Leave the watcher blocked on delivering a packet nobody reads
+	writeFrames(t, remote, 1)
+	cancel()
+
+	select {
+	case <-device.Done():
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not stop after the context was cancelled")
+	}
+	if err := device.Close(); err != nil {
+		t.Errorf("expected no error from close but got %v", err)
+	}
+	select {
+	case err := <-device.ErrChan:
+		t.Errorf("expected no error after close but got %v", err)
+	default:
+	}
+}
+
+func TestDeviceRejectsInvalidPacketBuffer(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		size   int
+		policy OverflowPolicy
+	}{
+		"negative size":          {size: -1, policy: OverflowBlock},
+		"unbuffered drop-oldest": {size: 0, policy: OverflowDropOldest},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			deviceConn, _ := net.Pipe()
+			device := NewDevice(deviceConn, context.Background(), WithPacketBuffer(test.size, test.policy))
+			defer device.Close()
+
+			var bufferErr *ErrPacketBuffer
+			select {
+			case err := <-device.ErrChan:
+				if !errors.As(err, &bufferErr) || bufferErr.Size != test.size {
+					t.Errorf("expected ErrPacketBuffer of size %d but got %v", test.size, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected an error for the invalid packet buffer")
+			}
+			if _, ok := <-device.PacketChan; ok {
+				t.Error("expected PacketChan to be closed")
+			}
+		})
+	}
+
+	if err := CheckPacketBuffer(0, OverflowDropNewest); err != nil {
+		t.Errorf("expected an unbuffered drop-newest device to be valid but got %v", err)
+	}
+}
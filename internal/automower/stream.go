@@ -0,0 +1,19 @@
+package automower
+
+import (
+	"io"
+	"net"
+
+	"github.com/Tifufu/tools-cli/internal/serial"
+)
+
+const NetworkSerial = "serial"
+
+// OpenStream connects to a device either through a serial port,
+// when network is "serial", or through any network supported by [net.Dial].
+func OpenStream(network, address string, baudRate int) (io.ReadWriteCloser, error) {
+	if network == NetworkSerial {
+		return serial.Open(address, baudRate)
+	}
+	return net.Dial(network, address)
+}
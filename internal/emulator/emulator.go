@@ -0,0 +1,455 @@
+// Package emulator implements a fake mower that speaks the linked and broadcast framing,
+// so that the protocol stack can be exercised without a running WinMower.
+package emulator
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+)
+
+// Link manager results
+const (
+	resultOk     byte = 0x00
+	resultFailed byte = 0x01
+)
+
+const (
+	DefaultNodeName        = "emulator"
+	DefaultNodeType uint32 = 0
+)
+
+// Status the emulator responds with to methods the connection is not logged in to call.
+const StatusLoginRequired byte = 0x05
+
+// Response is a reply to a method call. Params are the encoded out parameters,
+// sent after the status byte for payload methods and after the response id for linked methods.
+type Response struct {
+	Status byte
+	Params []byte
+	// Wait before replying
+	Delay time.Duration
+	// Do not reply at all, to let the caller time out
+	Drop bool
+}
+
+// PeriodicBroadcast is a broadcast sent on every connection at a fixed interval.
+type PeriodicBroadcast struct {
+	Broadcast linking.Broadcast
+	Interval  time.Duration
+}
+
+type Option func(*Emulator)
+
+// WithDefinition answers the methods in def. Methods without
+// scripted responses get an empty response with status 0.
+func WithDefinition(def *tif.TifDefinition) Option {
+	return func(e *Emulator) {
+		e.def = def
+	}
+}
+
+// WithResponses scripts the responses to a method, given by its Family.Command name.
+// The responses are used in order for consecutive calls, the last one is repeated.
+func WithResponses(method string, responses ...Response) Option {
+	return func(e *Emulator) {
+		e.responses[method] = responses
+	}
+}
+
+func WithBroadcast(b PeriodicBroadcast) Option {
+	return func(e *Emulator) {
+		e.broadcasts = append(e.broadcasts, b)
+	}
+}
+
+// WithLoginLevel makes every connection start at a login level, and refuses payload
+// methods that may not be called at the login level of the connection with StatusLoginRequired.
+// Without a login level, every method may be called.
+func WithLoginLevel(level string) Option {
+	return func(e *Emulator) {
+		e.loginLevel = level
+	}
+}
+
+// WithLogin makes method, given by its Family.Command name, log the connection in at level
+// whenever it responds with tif.StatusOk.
+func WithLogin(method string, level string) Option {
+	return func(e *Emulator) {
+		e.logins[method] = level
+	}
+}
+
+func WithNodeName(name string) Option {
+	return func(e *Emulator) {
+		e.nodeName = name
+	}
+}
+
+type Emulator struct {
+	logger     *log.Logger
+	def        *tif.TifDefinition
+	nodeName   string
+	broadcasts []PeriodicBroadcast
+	loginLevel string
+	// Login level of each login method
+	logins map[string]string
+
+	// Method lookup built from the definition
+	methods       map[tif.MethodId]tif.MethodDefinition
+	linkedMethods map[byte]tif.MethodDefinition
+
+	responsesMu sync.Mutex
+	responses   map[string][]Response
+	calls       map[string]int
+}
+
+func NewEmulator(logger *log.Logger, opts ...Option) *Emulator {
+	e := &Emulator{
+		logger:        logger,
+		nodeName:      DefaultNodeName,
+		methods:       make(map[tif.MethodId]tif.MethodDefinition),
+		linkedMethods: make(map[byte]tif.MethodDefinition),
+		responses:     make(map[string][]Response),
+		calls:         make(map[string]int),
+		logins:        make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.def != nil {
+		for _, method := range e.def.Methods {
+			if method.IsLinked() {
+				requestId, _, err := method.RequestId()
+				if err != nil {
+					logger.Warn("Skipping method", "method", method.Name(), "err", err)
+					continue
+				}
+				e.linkedMethods[requestId] = method
+				continue
+			}
+
+			id, err := method.MethodId()
+			if err != nil {
+				logger.Warn("Skipping method", "method", method.Name(), "err", err)
+				continue
+			}
+			e.methods[id] = method
+		}
+	}
+	return e
+}
+
+// Serve accepts connections on listener and emulates a device on each of them, until ctx is done.
+func (e *Emulator) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.logger.Info("Client connected", "client", conn.RemoteAddr())
+			err := e.ServeConn(ctx, conn)
+			if err != nil {
+				e.logger.Error("Error serving client", "client", conn.RemoteAddr(), "err", err)
+				return
+			}
+			e.logger.Info("Client disconnected", "client", conn.RemoteAddr())
+		}()
+	}
+}
+
+// ServeConn emulates a device on conn until the client disconnects or ctx is done.
+// Every connection has its own set of links, like a separate connection to WinMower.
+func (e *Emulator) ServeConn(ctx context.Context, conn io.ReadWriteCloser) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	s := &session{
+		emulator:   e,
+		ctx:        ctx,
+		conn:       conn,
+		links:      map[linking.LinkId]struct{}{linking.DefaultLinkId: {}},
+		loginLevel: e.loginLevel,
+	}
+	defer s.wg.Wait()
+
+	for _, b := range e.broadcasts {
+		if b.Interval <= 0 {
+			e.logger.Warn("Skipping broadcast without interval", "family", b.Broadcast.MessageFamily)
+			continue
+		}
+		s.wg.Add(1)
+		go s.broadcast(b)
+	}
+
+	decoder := framing.NewDecoder(conn)
+	for {
+		data, err := decoder.ReadFrame()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		frame, err := linking.ParseFrame(data)
+		if err != nil {
+			e.logger.Warn("Dropping malformed frame", "err", err, "frame", linking.Payload(data))
+			continue
+		}
+		if frame.PacketType != linking.LinkedPacketType {
+			e.logger.Debug("Ignoring broadcast from client", "frame", frame)
+			continue
+		}
+		s.handle(frame)
+	}
+}
+
+// nextResponse returns the scripted response to the next call of method.
+func (e *Emulator) nextResponse(method string) Response {
+	e.responsesMu.Lock()
+	defer e.responsesMu.Unlock()
+
+	responses := e.responses[method]
+	call := e.calls[method]
+	e.calls[method]++
+	if len(responses) == 0 {
+		return Response{}
+	}
+	return responses[min(call, len(responses)-1)]
+}
+
+type session struct {
+	emulator *Emulator
+	ctx      context.Context
+	wg       sync.WaitGroup
+
+	writeMu sync.Mutex
+	conn    io.ReadWriteCloser
+
+	linksMu sync.Mutex
+	links   map[linking.LinkId]struct{}
+
+	// Only used while handling requests, which are handled one at a time
+	loginLevel string
+}
+
+func (s *session) handle(frame linking.Frame) {
+	logger := s.emulator.logger
+	if !s.hasLink(frame.LinkId) {
+		logger.Warn("Ignoring request on unknown link", "linkId", frame.LinkId)
+		return
+	}
+	if len(frame.Payload) == 0 {
+		logger.Warn("Ignoring request without payload", "linkId", frame.LinkId)
+		return
+	}
+
+	switch frame.Control {
+	case linking.ControlLinkManagerCommand:
+		s.handleLinkManager(frame.LinkId, frame.Payload)
+	case linking.ControlPayloadCommand:
+		s.handleMethod(frame.LinkId, frame.Payload)
+	default:
+		logger.Warn("Ignoring request with unknown control", "linkId", frame.LinkId, "control", frame.Control)
+	}
+}
+
+func (s *session) handleLinkManager(linkId linking.LinkId, payload []byte) {
+	logger := s.emulator.logger
+
+	switch payload[0] {
+	case linking.LinkManagerConnectRequest:
+		if len(payload) < 5 {
+			logger.Warn("Malformed connect request", "payload", linking.Payload(payload))
+			return
+		}
+		newLinkId := linking.LinkId(binary.LittleEndian.Uint32(payload[1:5]))
+		s.linksMu.Lock()
+		s.links[newLinkId] = struct{}{}
+		s.linksMu.Unlock()
+		logger.Debug("Connected link", "linkId", newLinkId)
+
+		response := binary.LittleEndian.AppendUint32([]byte{linking.LinkManagerConnectResponse}, uint32(newLinkId))
+		s.reply(linkId, linking.ControlLinkManagerCommand, response)
+	case linking.LinkManagerDeleteLinkRequest:
+		if len(payload) < 5 {
+			logger.Warn("Malformed delete link request", "payload", linking.Payload(payload))
+			return
+		}
+		deleteLinkId := linking.LinkId(binary.LittleEndian.Uint32(payload[1:5]))
+		result := resultFailed
+		s.linksMu.Lock()
+		if _, ok := s.links[deleteLinkId]; ok && deleteLinkId != linking.DefaultLinkId {
+			delete(s.links, deleteLinkId)
+			result = resultOk
+		}
+		s.linksMu.Unlock()
+		logger.Debug("Deleted link", "linkId", deleteLinkId, "result", result)
+
+		response := binary.LittleEndian.AppendUint32([]byte{linking.LinkManagerDeleteLinkResponse, result}, uint32(deleteLinkId))
+		s.reply(linkId, linking.ControlLinkManagerCommand, response)
+	case linking.LinkManagerSetProtocolRequest:
+		if len(payload) < 2 {
+			logger.Warn("Malformed set protocol request", "payload", linking.Payload(payload))
+			return
+		}
+		protocol := payload[1]
+		result := resultOk
+		if protocol != linking.RoboticsProtocol1 && protocol != linking.RoboticsProtocol2 {
+			result = resultFailed
+		}
+		s.reply(linkId, linking.ControlLinkManagerCommand, []byte{linking.LinkManagerSetProtocolResponse, result, protocol})
+	case linking.LinkManagerPingRequest:
+		s.reply(linkId, linking.ControlLinkManagerCommand, []byte{linking.LinkManagerPingResponse})
+	case linking.LinkManagerDiscoverRequest:
+		if len(payload) < 5 {
+			logger.Warn("Malformed discover request", "payload", linking.Payload(payload))
+			return
+		}
+		response := append([]byte{linking.LinkManagerDiscoverResponse}, payload[1:5]...)
+		response = binary.LittleEndian.AppendUint32(response, DefaultNodeType)
+		response = append(response, s.emulator.nodeName...)
+		response = append(response, 0x00)
+		s.reply(linkId, linking.ControlLinkManagerCommand, response)
+	default:
+		method, ok := s.emulator.linkedMethods[payload[0]]
+		if !ok {
+			logger.Warn("Ignoring unknown link manager command", "linkId", linkId, "payload", linking.Payload(payload))
+			return
+		}
+		_, responseId, _ := method.RequestId()
+		s.respond(linkId, linking.ControlLinkManagerCommand, method.Name(), func(r Response) []byte {
+			return append([]byte{responseId}, r.Params...)
+		})
+	}
+}
+
+func (s *session) handleMethod(linkId linking.LinkId, payload []byte) {
+	logger := s.emulator.logger
+
+	id, _, err := tif.ParseMethodHeader(payload)
+	if err != nil {
+		logger.Warn("Ignoring malformed method call", "linkId", linkId, "err", err)
+		return
+	}
+	method, ok := s.emulator.methods[id]
+	if !ok {
+		logger.Warn("Ignoring call to unknown method", "linkId", linkId, "method", id)
+		return
+	}
+
+	if s.loginLevel != "" && !method.AllowsLoginLevel(s.loginLevel) {
+		logger.Debug("Refusing method above login level", "method", method.Name(), "linkId", linkId, "level", s.loginLevel)
+		s.reply(linkId, linking.ControlPayloadCommand, append(tif.AppendMethodHeader(nil, id, 1), StatusLoginRequired))
+		return
+	}
+
+	response := s.respond(linkId, linking.ControlPayloadCommand, method.Name(), func(r Response) []byte {
+		response := tif.AppendMethodHeader(nil, id, 1+len(r.Params))
+		response = append(response, r.Status)
+		return append(response, r.Params...)
+	})
+	if level, ok := s.emulator.logins[method.Name()]; ok && response.Status == tif.StatusOk && !response.Drop {
+		logger.Debug("Logged in", "linkId", linkId, "level", level)
+		s.loginLevel = level
+	}
+}
+
+// respond replies with the next scripted response to method, encoded by encode, and returns it.
+func (s *session) respond(linkId linking.LinkId, ctrl byte, method string, encode func(Response) []byte) Response {
+	response := s.emulator.nextResponse(method)
+	s.emulator.logger.Debug("Method called", "method", method, "linkId", linkId, "status", response.Status, "drop", response.Drop)
+	if response.Drop {
+		return response
+	}
+
+	payload := encode(response)
+	if response.Delay <= 0 {
+		s.reply(linkId, ctrl, payload)
+		return response
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case <-time.After(response.Delay):
+			s.reply(linkId, ctrl, payload)
+		case <-s.ctx.Done():
+		}
+	}()
+	return response
+}
+
+func (s *session) reply(linkId linking.LinkId, ctrl byte, payload []byte) {
+	frame, err := linking.EncodeLinkedFrame(linkId, ctrl, payload)
+	if err != nil {
+		s.emulator.logger.Error("Error encoding response", "linkId", linkId, "err", err)
+		return
+	}
+	s.write(frame)
+}
+
+func (s *session) broadcast(b PeriodicBroadcast) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	frame := linking.EncodeBroadcastFrame(b.Broadcast)
+	for {
+		select {
+		case <-ticker.C:
+			s.write(frame)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *session) write(frame []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_, err := s.conn.Write(frame)
+	if err != nil && s.ctx.Err() == nil {
+		s.emulator.logger.Debug("Error writing to client", "err", err)
+	}
+}
+
+func (s *session) hasLink(linkId linking.LinkId) bool {
+	s.linksMu.Lock()
+	defer s.linksMu.Unlock()
+	_, ok := s.links[linkId]
+	return ok
+}
@@ -0,0 +1,268 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testMethod(family, command string, protocol ...string) tif.MethodDefinition {
+	method := tif.MethodDefinition{Family: family, Command: command}
+	for i := 0; i+1 < len(protocol); i += 2 {
+		method.Protocol = append(method.Protocol, tif.ProtocolEntry{Key: protocol[i], Value: tif.FlexString(protocol[i+1])})
+	}
+	return method
+}
+
+var testDefinition = &tif.TifDefinition{
+	Methods: []tif.MethodDefinition{
+		testMethod("Battery", "GetCharge", "msgType", "4660", "subCmd", "1"),
+		testMethod("System", "Reset", "msgType", "0x1000", "subCmd", "2"),
+		testMethod("LinkManager", "GetNodeInfo", "linked", "true", "requestId", "32", "responseId", "33"),
+	},
+}
+
+// startEmulator serves an emulator over TCP and connects a started mux to it.
+func startEmulator(t *testing.T, opts ...Option) *linking.LinkMux {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	emulator := NewEmulator(log.New(io.Discard), opts...)
+	served := make(chan error, 1)
+	go func() {
+		served <- emulator.Serve(ctx, listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to emulator: %v", err)
+	}
+	device := automower.NewDevice(conn, context.Background())
+	mux := linking.NewLinkMux(device, log.New(io.Discard))
+	go mux.Start()
+
+	t.Cleanup(func() {
+		mux.Stop()
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("emulator stopped with error: %v", err)
+		}
+	})
+	return mux
+}
+
+func TestEmulatorLinkManager(t *testing.T) {
+	t.Parallel()
+	mux := startEmulator(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := mux.DefaultLink.Ping(ctx)
+	if err != nil {
+		t.Fatalf("expected no error pinging the default link but got %v", err)
+	}
+
+	link, err := mux.OpenLink(ctx)
+	if err != nil {
+		t.Fatalf("expected no error opening a link but got %v", err)
+	}
+	err = link.SetProtocol(ctx, linking.RoboticsProtocol2)
+	if err != nil {
+		t.Fatalf("expected no error setting the protocol but got %v", err)
+	}
+	err = link.SetProtocol(ctx, 0x42)
+	var resultErr *linking.ErrLinkManagerResult
+	if !errors.As(err, &resultErr) {
+		t.Fatalf("expected link manager result error for unknown protocol but got %v", err)
+	}
+
+	err = link.Close(ctx)
+	if err != nil {
+		t.Fatalf("expected no error closing the link but got %v", err)
+	}
+	err = link.Close(ctx)
+	if !errors.As(err, &resultErr) {
+		t.Errorf("expected link manager result error deleting a deleted link but got %v", err)
+	}
+}
+
+func TestEmulatorMethods(t *testing.T) {
+	t.Parallel()
+	mux := startEmulator(t,
+		WithDefinition(testDefinition),
+		WithResponses("Battery.GetCharge", Response{Params: []byte{0x64}}, Response{Status: 0x02}),
+		WithResponses("LinkManager.GetNodeInfo", Response{Params: []byte{0x01, 0x02}}),
+	)
+	batteryId := tif.MethodId{MsgType: 0x1234, SubCmd: 0x01}
+	resetId := tif.MethodId{MsgType: 0x1000, SubCmd: 0x02}
+
+	tests := []struct {
+		name     string
+		ctrl     byte
+		request  []byte
+		expected []byte
+	}{
+		{
+			name:     "first scripted response",
+			ctrl:     linking.ControlPayloadCommand,
+			request:  tif.AppendMethodHeader(nil, batteryId, 0),
+			expected: append(tif.AppendMethodHeader(nil, batteryId, 2), 0x00, 0x64),
+		},
+		{
+			name:     "last scripted response repeats",
+			ctrl:     linking.ControlPayloadCommand,
+			request:  tif.AppendMethodHeader(nil, batteryId, 0),
+			expected: append(tif.AppendMethodHeader(nil, batteryId, 1), 0x02),
+		},
+		{
+			name:     "last scripted response repeats again",
+			ctrl:     linking.ControlPayloadCommand,
+			request:  tif.AppendMethodHeader(nil, batteryId, 0),
+			expected: append(tif.AppendMethodHeader(nil, batteryId, 1), 0x02),
+		},
+		{
+			name:     "method without script",
+			ctrl:     linking.ControlPayloadCommand,
+			request:  append(tif.AppendMethodHeader(nil, resetId, 1), 0x01),
+			expected: append(tif.AppendMethodHeader(nil, resetId, 1), 0x00),
+		},
+		{
+			name:     "linked method",
+			ctrl:     linking.ControlLinkManagerCommand,
+			request:  []byte{0x20},
+			expected: []byte{0x21, 0x01, 0x02},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	link, err := mux.OpenLink(ctx)
+	if err != nil {
+		t.Fatalf("expected no error opening a link but got %v", err)
+	}
+
+	// Consecutive calls share the script, so they run in order
+	for _, test := range tests {
+		response, err := link.Request(ctx, test.ctrl, test.request, nil)
+		if err != nil {
+			t.Fatalf("%s: expected no error but got %v", test.name, err)
+		}
+		if !bytes.Equal(test.expected, response) {
+			t.Errorf("%s: expected response %X but got %X", test.name, test.expected, response)
+		}
+	}
+}
+
+func TestEmulatorDroppedResponse(t *testing.T) {
+	t.Parallel()
+	mux := startEmulator(t,
+		WithDefinition(testDefinition),
+		WithResponses("System.Reset", Response{Drop: true}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	request := tif.AppendMethodHeader(nil, tif.MethodId{MsgType: 0x1000, SubCmd: 0x02}, 0)
+	_, err := mux.DefaultLink.Request(ctx, linking.ControlPayloadCommand, request, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestEmulatorBroadcasts(t *testing.T) {
+	t.Parallel()
+
+	expected := linking.Broadcast{
+		MessageFamily:    0x1234,
+		SenderId:         0x01,
+		BroadcastChannel: 0x02,
+		Payload:          linking.Payload{0x03, 0x02, 0x03},
+	}
+	mux := startEmulator(t,
+		WithBroadcast(PeriodicBroadcast{Broadcast: expected, Interval: 5 * time.Millisecond}),
+		WithBroadcast(PeriodicBroadcast{Broadcast: linking.Broadcast{MessageFamily: 0x4321}, Interval: 5 * time.Millisecond}),
+	)
+
+	sub, err := mux.Subscribe(linking.BroadcastFilter{Families: []uint16{0x1234}})
+	if err != nil {
+		t.Fatalf("expected no error subscribing but got %v", err)
+	}
+	defer sub.Close()
+
+	for range 3 {
+		select {
+		case b := <-sub.C():
+			if !cmp.Equal(expected, b) {
+				t.Errorf("expected broadcast %+v but got %+v", expected, b)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for broadcast")
+		}
+	}
+}
+
+func TestScriptOptions(t *testing.T) {
+	t.Parallel()
+
+	script := Script{
+		NodeName:   "mower",
+		LoginLevel: "Operator",
+		Logins:     map[string]string{"Security.Login": "Service"},
+		Responses: map[string][]ScriptResponse{
+			"Battery.GetCharge": {{Status: 1, Params: "0A0B", Delay: "10ms"}},
+		},
+		Broadcasts: []ScriptBroadcast{{Family: 1, Payload: "FF", Interval: "1s"}},
+	}
+	opts, err := script.Options()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	emulator := NewEmulator(log.New(io.Discard), opts...)
+	if emulator.nodeName != "mower" {
+		t.Errorf("expected node name mower but got %s", emulator.nodeName)
+	}
+	if emulator.loginLevel != "Operator" || emulator.logins["Security.Login"] != "Service" {
+		t.Errorf("expected login level Operator and login Security.Login but got %s, %v", emulator.loginLevel, emulator.logins)
+	}
+	expectedResponses := map[string][]Response{
+		"Battery.GetCharge": {{Status: 1, Params: []byte{0x0A, 0x0B}, Delay: 10 * time.Millisecond}},
+	}
+	if !cmp.Equal(expectedResponses, emulator.responses) {
+		t.Errorf("expected responses %+v but got %+v", expectedResponses, emulator.responses)
+	}
+	expectedBroadcasts := []PeriodicBroadcast{{Broadcast: linking.Broadcast{MessageFamily: 1, Payload: linking.Payload{0xFF}}, Interval: time.Second}}
+	if !cmp.Equal(expectedBroadcasts, emulator.broadcasts) {
+		t.Errorf("expected broadcasts %+v but got %+v", expectedBroadcasts, emulator.broadcasts)
+	}
+
+	invalid := map[string]Script{
+		"params":   {Responses: map[string][]ScriptResponse{"A.B": {{Params: "XY"}}}},
+		"delay":    {Responses: map[string][]ScriptResponse{"A.B": {{Delay: "soon"}}}},
+		"payload":  {Broadcasts: []ScriptBroadcast{{Payload: "F", Interval: "1s"}}},
+		"interval": {Broadcasts: []ScriptBroadcast{{Interval: "0s"}}},
+	}
+	for name, script := range invalid {
+		_, err := script.Options()
+		if err == nil {
+			t.Errorf("expected error for invalid %s", name)
+		}
+	}
+}
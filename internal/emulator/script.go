@@ -0,0 +1,122 @@
+package emulator
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+)
+
+// Script describes the behaviour of an emulator in JSON. Params and payloads are hex
+// encoded and durations use the time.ParseDuration format, for example:
+//
+//	{
+//	  "nodeName": "mower",
+//	  "loginLevel": "Operator",
+//	  "logins": { "Security.Login": "Service" },
+//	  "responses": {
+//	    "Battery.GetCharge": [{ "status": 0, "params": "64" }],
+//	    "System.Reset": [{ "drop": true }]
+//	  },
+//	  "broadcasts": [
+//	    { "family": 4660, "sender": 1, "channel": 0, "payload": "0102", "interval": "1s" }
+//	  ]
+//	}
+type Script struct {
+	NodeName   string                      `json:"nodeName,omitempty"`
+	LoginLevel string                      `json:"loginLevel,omitempty"`
+	Logins     map[string]string           `json:"logins,omitempty"`
+	Responses  map[string][]ScriptResponse `json:"responses"`
+	Broadcasts []ScriptBroadcast           `json:"broadcasts"`
+}
+
+type ScriptResponse struct {
+	Status byte   `json:"status"`
+	Params string `json:"params"`
+	Delay  string `json:"delay,omitempty"`
+	Drop   bool   `json:"drop,omitempty"`
+}
+
+type ScriptBroadcast struct {
+	Family   uint16 `json:"family"`
+	Sender   byte   `json:"sender"`
+	Channel  byte   `json:"channel"`
+	Control  byte   `json:"control"`
+	Payload  string `json:"payload"`
+	Interval string `json:"interval"`
+}
+
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var script Script
+	err = json.Unmarshal(data, &script)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse emulator script %s: %w", path, err)
+	}
+	return &script, nil
+}
+
+// Options converts the script into emulator options.
+func (s Script) Options() ([]Option, error) {
+	var opts []Option
+	if s.NodeName != "" {
+		opts = append(opts, WithNodeName(s.NodeName))
+	}
+	if s.LoginLevel != "" {
+		opts = append(opts, WithLoginLevel(s.LoginLevel))
+	}
+	for method, level := range s.Logins {
+		opts = append(opts, WithLogin(method, level))
+	}
+
+	for method, scripted := range s.Responses {
+		responses := make([]Response, 0, len(scripted))
+		for i, r := range scripted {
+			params, err := hex.DecodeString(r.Params)
+			if err != nil {
+				return nil, fmt.Errorf("response %d of %s has invalid params: %w", i, method, err)
+			}
+			var delay time.Duration
+			if r.Delay != "" {
+				delay, err = time.ParseDuration(r.Delay)
+				if err != nil {
+					return nil, fmt.Errorf("response %d of %s has invalid delay: %w", i, method, err)
+				}
+			}
+			responses = append(responses, Response{Status: r.Status, Params: params, Delay: delay, Drop: r.Drop})
+		}
+		opts = append(opts, WithResponses(method, responses...))
+	}
+
+	for i, b := range s.Broadcasts {
+		payload, err := hex.DecodeString(b.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("broadcast %d has invalid payload: %w", i, err)
+		}
+		interval, err := time.ParseDuration(b.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("broadcast %d has invalid interval: %w", i, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("broadcast %d must have a positive interval, got %s", i, interval)
+		}
+		opts = append(opts, WithBroadcast(PeriodicBroadcast{
+			Broadcast: linking.Broadcast{
+				MessageFamily:    b.Family,
+				SenderId:         b.Sender,
+				BroadcastChannel: b.Channel,
+				Control:          b.Control,
+				Payload:          payload,
+			},
+			Interval: interval,
+		}))
+	}
+	return opts, nil
+}
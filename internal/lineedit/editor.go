@@ -0,0 +1,274 @@
+// Package lineedit reads lines from a terminal with editing, history and tab completion.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl+C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Completion is the result of completing the text before the cursor.
+type Completion struct {
+	// Index in the text where the token replaced by the candidates starts
+	Start      int
+	Candidates []string
+	// Shown below the line when the completion is not unique, such as a signature
+	Hint string
+}
+
+// Completer completes the text before the cursor.
+type Completer func(text string) Completion
+
+const (
+	keyCtrlA     = 0x01
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyBackspace = 0x08
+	keyTab       = 0x09
+	keyLineFeed  = 0x0A
+	keyCtrlL     = 0x0C
+	keyEnter     = 0x0D
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1B
+	keyDelete    = 0x7F
+)
+
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// Puts the terminal in raw mode while reading a line, nil if it already is or is not a terminal
+	raw func() (restore func() error, err error)
+
+	Prompt   string
+	Complete Completer
+	History  *History
+
+	line   []rune
+	cursor int
+	// Index in the history while browsing it, len(History.Lines()) when not
+	historyIdx int
+	// The line being edited before browsing the history
+	draft     []rune
+	lastWasCR bool
+}
+
+// NewEditor reads lines from in, echoing edits to out. The keys are interpreted
+// as sent by a terminal in raw mode, so in must not be a terminal in line mode.
+func NewEditor(in io.Reader, out io.Writer) *Editor {
+	return &Editor{
+		in:      bufio.NewReader(in),
+		out:     out,
+		History: NewHistory(DefaultHistorySize),
+	}
+}
+
+// NewTerminalEditor reads lines from stdin, switching the terminal to raw mode while reading.
+// When stdin is not a terminal, lines are read as they come without editing.
+func NewTerminalEditor() *Editor {
+	e := NewEditor(os.Stdin, os.Stdout)
+	e.raw = func() (func() error, error) {
+		return MakeRaw(os.Stdin, os.Stdout)
+	}
+	return e
+}
+
+// ReadLine shows the prompt and returns the next line entered, without its line ending.
+// It returns ErrInterrupted on Ctrl+C, and io.EOF on Ctrl+D on an empty line or at the end of the input.
+func (e *Editor) ReadLine() (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err == nil {
+			defer restore()
+		} else if !errors.Is(err, ErrNotTerminal) {
+			return "", err
+		}
+	}
+
+	e.line = e.line[:0]
+	e.cursor = 0
+	e.historyIdx = len(e.History.Lines())
+	e.draft = nil
+	fmt.Fprint(e.out, e.Prompt)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(e.line) > 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return string(e.line), nil
+			}
+			return "", err
+		}
+
+		wasCR := e.lastWasCR
+		e.lastWasCR = r == keyEnter
+		switch r {
+		case keyEnter, keyLineFeed:
+			// Some terminals send both for a single press
+			if r == keyLineFeed && wasCR {
+				continue
+			}
+			fmt.Fprint(e.out, "\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.cursor)
+		case keyBackspace, keyDelete:
+			if e.cursor > 0 {
+				e.cursor--
+				e.deleteAt(e.cursor)
+			}
+		case keyTab:
+			e.complete()
+		case keyCtrlA:
+			e.cursor = 0
+		case keyCtrlE:
+			e.cursor = len(e.line)
+		case keyCtrlU:
+			e.line = append(e.line[:0], e.line[e.cursor:]...)
+			e.cursor = 0
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyEscape:
+			e.escapeSequence()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+		e.redraw()
+	}
+}
+
+// escapeSequence handles the cursor keys, sent as ESC [ <key> or ESC O <key>.
+func (e *Editor) escapeSequence() {
+	prefix, err := e.in.ReadByte()
+	if err != nil || (prefix != '[' && prefix != 'O') {
+		return
+	}
+	key, err := e.in.ReadByte()
+	if err != nil {
+		return
+	}
+
+	switch key {
+	case 'A':
+		e.browseHistory(-1)
+	case 'B':
+		e.browseHistory(1)
+	case 'C':
+		e.cursor = min(e.cursor+1, len(e.line))
+	case 'D':
+		e.cursor = max(e.cursor-1, 0)
+	case 'H':
+		e.cursor = 0
+	case 'F':
+		e.cursor = len(e.line)
+	case '3':
+		// Delete is ESC [ 3 ~
+		if next, err := e.in.ReadByte(); err == nil && next == '~' {
+			e.deleteAt(e.cursor)
+		}
+	}
+}
+
+func (e *Editor) browseHistory(step int) {
+	lines := e.History.Lines()
+	idx := e.historyIdx + step
+	if idx < 0 || idx > len(lines) {
+		return
+	}
+	if e.historyIdx == len(lines) {
+		e.draft = append([]rune(nil), e.line...)
+	}
+
+	e.historyIdx = idx
+	if idx == len(lines) {
+		e.line = append(e.line[:0], e.draft...)
+	} else {
+		e.line = append(e.line[:0], []rune(lines[idx])...)
+	}
+	e.cursor = len(e.line)
+}
+
+func (e *Editor) complete() {
+	if e.Complete == nil {
+		return
+	}
+	text := string(e.line[:e.cursor])
+	completion := e.Complete(text)
+	start := len([]rune(text[:completion.Start]))
+	token := e.line[start:e.cursor]
+
+	switch len(completion.Candidates) {
+	case 0:
+		if completion.Hint != "" {
+			fmt.Fprintf(e.out, "\r\n%s\r\n", completion.Hint)
+		}
+	case 1:
+		e.replace(start, []rune(completion.Candidates[0]))
+	default:
+		prefix := []rune(commonPrefix(completion.Candidates))
+		if len(prefix) > len(token) {
+			e.replace(start, prefix)
+			return
+		}
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(completion.Candidates, "  "))
+		if completion.Hint != "" {
+			fmt.Fprintf(e.out, "%s\r\n", completion.Hint)
+		}
+	}
+}
+
+// replace replaces the text from start up to the cursor with text.
+func (e *Editor) replace(start int, text []rune) {
+	rest := append([]rune(nil), e.line[e.cursor:]...)
+	e.line = append(append(e.line[:start], text...), rest...)
+	e.cursor = start + len(text)
+}
+
+func (e *Editor) insert(text []rune) {
+	e.replace(e.cursor, text)
+}
+
+func (e *Editor) deleteAt(idx int) {
+	if idx < len(e.line) {
+		e.line = append(e.line[:idx], e.line[idx+1:]...)
+	}
+}
+
+// redraw rewrites the prompt and line, clears what is left of the previous line and places the cursor.
+func (e *Editor) redraw() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.Prompt, string(e.line))
+	if back := len(e.line) - e.cursor; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// commonPrefix returns the longest prefix shared by all candidates, ignoring case.
+func commonPrefix(candidates []string) string {
+	prefix := []rune(candidates[0])
+	for _, candidate := range candidates[1:] {
+		runes := []rune(candidate)
+		n := 0
+		for n < len(prefix) && n < len(runes) && unicode.ToLower(prefix[n]) == unicode.ToLower(runes[n]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
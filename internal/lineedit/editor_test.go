@@ -0,0 +1,155 @@
+package lineedit
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadLine(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		input   string
+		history []string
+		want    string
+		wantErr error
+	}
+
+	tests := map[string]Test{
+		"Plain line": {
+			input: "Battery.GetCharge()\r",
+			want:  "Battery.GetCharge()",
+		},
+		"CRLF": {
+			input: "abc\r\n",
+			want:  "abc",
+		},
+		"Backspace": {
+			input: "abd\x7fc\r",
+			want:  "abc",
+		},
+		"Cursor movement": {
+			input: "ac\x1b[Db\x1b[C\x1b[Cd\r",
+			want:  "abcd",
+		},
+		"Home and end": {
+			input: "bc\x01a\x05d\r",
+			want:  "abcd",
+		},
+		"Delete key": {
+			input: "abxc\x1b[D\x1b[D\x1b[3~\r",
+			want:  "abc",
+		},
+		"Kill to start": {
+			input: "xyzabc\x1b[D\x1b[D\x1b[D\x15\r",
+			want:  "abc",
+		},
+		"History up": {
+			input:   "\x1b[A\x1b[A\r",
+			history: []string{"first", "second"},
+			want:    "first",
+		},
+		"History back to draft": {
+			input:   "dra\x1b[A\x1b[Bft\r",
+			history: []string{"first"},
+			want:    "draft",
+		},
+		"Interrupt": {
+			input:   "abc\x03",
+			wantErr: ErrInterrupted,
+		},
+		"End of input on empty line": {
+			input:   "\x04",
+			wantErr: io.EOF,
+		},
+		"End of input after text": {
+			input: "abc",
+			want:  "abc",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			editor := NewEditor(strings.NewReader(test.input), io.Discard)
+			for _, line := range test.history {
+				editor.History.Add(line)
+			}
+
+			line, err := editor.ReadLine()
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, line); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestReadLineCompletion(t *testing.T) {
+	t.Parallel()
+
+	complete := func(text string) Completion {
+		start := strings.LastIndex(text, " ") + 1
+		var candidates []string
+		for _, word := range []string{"Battery", "Blade", "Bluetooth", "Charger", "Charging"} {
+			if strings.HasPrefix(word, text[start:]) {
+				candidates = append(candidates, word)
+			}
+		}
+		return Completion{Start: start, Candidates: candidates, Hint: "hint"}
+	}
+
+	type Test struct {
+		input   string
+		want    string
+		wantOut string
+	}
+
+	tests := map[string]Test{
+		"Unique": {
+			input: "call Bat\t\r",
+			want:  "call Battery",
+		},
+		"Common prefix": {
+			input: "Ch\t\r",
+			want:  "Charg",
+		},
+		"Ambiguous lists candidates": {
+			input:   "B\t\r",
+			want:    "B",
+			wantOut: "\r\nBattery  Blade  Bluetooth\r\nhint\r\n",
+		},
+		"Completes before cursor": {
+			input: "Ba x\x1b[D\x1b[D\t\r",
+			want:  "Battery x",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			out := &strings.Builder{}
+			editor := NewEditor(strings.NewReader(test.input), out)
+			editor.Complete = complete
+
+			line, err := editor.ReadLine()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, line); diff != "" {
+				t.Error(diff)
+			}
+			if test.wantOut != "" && !strings.Contains(out.String(), test.wantOut) {
+				t.Errorf("output %q does not contain %q", out.String(), test.wantOut)
+			}
+		})
+	}
+}
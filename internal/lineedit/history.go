@@ -0,0 +1,82 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DefaultHistorySize = 1000
+
+// History keeps the lines entered in an editor, oldest first,
+// optionally appending every added line to a file.
+type History struct {
+	lines []string
+	max   int
+	path  string
+}
+
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// LoadHistory reads the history from the file at path, which
+// does not have to exist, and appends added lines to it.
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{max: max, path: path}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.append(scanner.Text())
+	}
+	return h, scanner.Err()
+}
+
+// Add adds line to the history, unless it is empty or the same as the previous line.
+func (h *History) Add(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return nil
+	}
+	h.append(line)
+
+	if h.path == "" {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(h.path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(line + "\n")
+	return errors.Join(err, file.Close())
+}
+
+func (h *History) Lines() []string {
+	return h.lines
+}
+
+func (h *History) append(line string) {
+	if line == "" {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if h.max > 0 && len(h.lines) > h.max {
+		h.lines = h.lines[len(h.lines)-h.max:]
+	}
+}
@@ -0,0 +1,44 @@
+package lineedit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "history")
+
+	history, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a", "b", "b", " ", "c", "d"} {
+		err := history.Add(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]string{"b", "c", "d"}, history.Lines()); diff != "" {
+		t.Error(diff)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("a\nb\nc\nd\n", string(content)); diff != "" {
+		t.Error(diff)
+	}
+
+	loaded, err := LoadHistory(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"c", "d"}, loaded.Lines()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,15 @@
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrNotTerminal is returned by MakeRaw when the files are not a terminal, such as a pipe.
+var ErrNotTerminal = errors.New("not a terminal")
+
+// MakeRaw puts the terminal of in and out in raw mode, so that every key press can be read
+// as it happens, without echo. The returned function restores the previous mode.
+func MakeRaw(in, out *os.File) (restore func() error, err error) {
+	return makeRaw(in, out)
+}
@@ -0,0 +1,33 @@
+package lineedit
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// makeRaw is the equivalent of cfmakeraw, keeping output processing so that
+// the terminal still starts a new line at the start of the line.
+func makeRaw(in, _ *os.File) (func() error, error) {
+	fd := int(in.Fd())
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+	previous := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		return unix.IoctlSetTermios(fd, unix.TCSETS, &previous)
+	}, nil
+}
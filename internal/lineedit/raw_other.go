@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package lineedit
+
+import "os"
+
+func makeRaw(_, _ *os.File) (func() error, error) {
+	return nil, ErrNotTerminal
+}
@@ -0,0 +1,43 @@
+package lineedit
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// makeRaw disables line input and echo on the console, and enables virtual terminal
+// sequences both ways so that arrow keys and cursor movement work as on other platforms.
+func makeRaw(in, out *os.File) (func() error, error) {
+	inHandle := windows.Handle(in.Fd())
+	var inMode uint32
+	err := windows.GetConsoleMode(inHandle, &inMode)
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+	outHandle := windows.Handle(out.Fd())
+	var outMode uint32
+	err = windows.GetConsoleMode(outHandle, &outMode)
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+
+	rawIn := inMode&^(windows.ENABLE_ECHO_INPUT|windows.ENABLE_PROCESSED_INPUT|windows.ENABLE_LINE_INPUT) | windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	err = windows.SetConsoleMode(inHandle, rawIn)
+	if err != nil {
+		return nil, err
+	}
+	err = windows.SetConsoleMode(outHandle, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	if err != nil {
+		windows.SetConsoleMode(inHandle, inMode)
+		return nil, err
+	}
+
+	return func() error {
+		return errors.Join(
+			windows.SetConsoleMode(inHandle, inMode),
+			windows.SetConsoleMode(outHandle, outMode),
+		)
+	}, nil
+}
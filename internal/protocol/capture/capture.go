@@ -0,0 +1,203 @@
+// Package capture records frames exchanged with a device to a file and reads them back.
+//
+// A capture file starts with the magic bytes "TCAP" followed by a format version byte.
+// Each record that follows is laid out as:
+//
+//	timestamp  int64, unix nanoseconds, little endian
+//	direction  byte, see [Direction]
+//	length     uint32, little endian
+//	frame      length bytes, the frame without start and end bytes
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	magic   = "TCAP"
+	version = 1
+
+	recordHeaderSize = 8 + 1 + 4
+
+	// Larger than any frame, guards against allocating garbage lengths from a corrupted file
+	maxFrameSize = 1 << 17
+)
+
+var (
+	ErrNotCapture = errors.New("not a capture file")
+)
+
+type Direction byte
+
+const (
+	// Frame received from the device.
+	Received Direction = 0
+	// Frame sent to the device.
+	Sent Direction = 1
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Received:
+		return "received"
+	case Sent:
+		return "sent"
+	default:
+		return fmt.Sprintf("Direction(%d)", byte(d))
+	}
+}
+
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Frame     []byte
+}
+
+// Writer writes records to a capture. It is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+}
+
+// Create creates or truncates the capture file at path.
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating capture file: %w", err)
+	}
+
+	w, err := NewWriter(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.closer = file
+	return w, nil
+}
+
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	_, err := bw.WriteString(magic)
+	if err != nil {
+		return nil, fmt.Errorf("error writing capture header: %w", err)
+	}
+	err = bw.WriteByte(version)
+	if err != nil {
+		return nil, fmt.Errorf("error writing capture header: %w", err)
+	}
+
+	return &Writer{w: bw}, nil
+}
+
+// Write appends a record. Records are buffered until Flush or Close.
+func (w *Writer) Write(r Record) error {
+	var header [recordHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[0:8], uint64(r.Time.UnixNano()))
+	header[8] = byte(r.Direction)
+	binary.LittleEndian.PutUint32(header[9:13], uint32(len(r.Frame)))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := w.w.Write(header[:])
+	if err != nil {
+		return fmt.Errorf("error writing record: %w", err)
+	}
+	_, err = w.w.Write(r.Frame)
+	if err != nil {
+		return fmt.Errorf("error writing record: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Flush()
+}
+
+// Close flushes buffered records and closes the file if the writer was created with [Create].
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.closer != nil {
+		closeErr := w.closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Reader reads records from a capture.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+1)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, ErrNotCapture
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, ErrNotCapture
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported capture version %d", header[len(magic)])
+	}
+
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF when there are no more records.
+func (r *Reader) Next() (Record, error) {
+	var header [recordHeaderSize]byte
+	_, err := io.ReadFull(r.r, header[:])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, fmt.Errorf("truncated record header: %w", err)
+		}
+		return Record{}, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[9:13])
+	if length > maxFrameSize {
+		return Record{}, fmt.Errorf("record length %d exceeds maximum frame size", length)
+	}
+
+	frame := make([]byte, length)
+	_, err = io.ReadFull(r.r, frame)
+	if err != nil {
+		return Record{}, fmt.Errorf("truncated record: %w", io.ErrUnexpectedEOF)
+	}
+
+	return Record{
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(header[0:8]))),
+		Direction: Direction(header[8]),
+		Frame:     frame,
+	}, nil
+}
+
+// ReadAll reads every remaining record.
+func (r *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+	for {
+		record, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
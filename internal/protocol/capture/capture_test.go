@@ -0,0 +1,95 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 10, 6, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: start, Direction: Sent, Frame: []byte{0xFD, 0x0A, 0x00}},
+		{Time: start.Add(time.Millisecond), Direction: Received, Frame: []byte{0xFC, 0x03}},
+		{Time: start.Add(2 * time.Millisecond), Direction: Received, Frame: []byte{}},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatalf("expected no error but got %q", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	read, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	if !cmp.Equal(records, read) {
+		t.Errorf("expected records %v but got %v", records, read)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewReader(bytes.NewReader([]byte("PCAP\x01"))); err != ErrNotCapture {
+		t.Errorf("expected %v for bad magic but got %v", ErrNotCapture, err)
+	}
+
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf)
+	w.Write(Record{Time: time.Now(), Direction: Received, Frame: []byte{0x01, 0x02, 0x03}})
+	w.Flush()
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected %v for truncated record but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestReplayStream(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	records := []Record{
+		{Time: start, Direction: Received, Frame: []byte{0xFD, 0x01}},
+		{Time: start, Direction: Sent, Frame: []byte{0xFD, 0x02}},
+		{Time: start.Add(20 * time.Millisecond), Direction: Received, Frame: []byte{0xFC, 0x03}},
+	}
+
+	before := time.Now()
+	stream := NewReplayStream(records, true)
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	expected := []byte{0x02, 0xFD, 0x01, 0x03, 0x02, 0xFC, 0x03, 0x03}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected stream %X but got %X", expected, data)
+	}
+	if elapsed := time.Since(before); elapsed < 20*time.Millisecond {
+		t.Errorf("expected realtime replay to take at least 20ms but took %v", elapsed)
+	}
+}
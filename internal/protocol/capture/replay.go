@@ -0,0 +1,90 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+)
+
+// ReplayStream plays back the received frames of a capture as a device byte stream.
+// Frames are wrapped in start and end bytes again and anything written to the stream is discarded.
+// Reads return io.EOF once every frame has been played back.
+type ReplayStream struct {
+	records  []Record
+	realtime bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	next    int
+	pending bytes.Buffer
+	last    time.Time
+}
+
+// NewReplayStream replays the received records. When realtime is set the delays
+// between frames are the same as when they were captured, otherwise frames are replayed back to back.
+func NewReplayStream(records []Record, realtime bool) *ReplayStream {
+	var received []Record
+	for _, record := range records {
+		if record.Direction == Received {
+			received = append(received, record)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ReplayStream{
+		records:  received,
+		realtime: realtime,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (s *ReplayStream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return 0, io.ErrClosedPipe
+	}
+
+	if s.pending.Len() == 0 {
+		if s.next >= len(s.records) {
+			return 0, io.EOF
+		}
+
+		record := s.records[s.next]
+		s.next++
+		if s.realtime && !s.last.IsZero() {
+			select {
+			case <-time.After(record.Time.Sub(s.last)):
+			case <-s.ctx.Done():
+				return 0, io.ErrClosedPipe
+			}
+		}
+		s.last = record.Time
+
+		s.pending.WriteByte(framing.PacketStart)
+		s.pending.Write(record.Frame)
+		s.pending.WriteByte(framing.PacketEnd)
+	}
+
+	return s.pending.Read(p)
+}
+
+func (s *ReplayStream) Write(p []byte) (int, error) {
+	if s.ctx.Err() != nil {
+		return 0, io.ErrClosedPipe
+	}
+	return len(p), nil
+}
+
+func (s *ReplayStream) Close() error {
+	s.cancel()
+	return nil
+}
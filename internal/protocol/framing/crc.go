@@ -1,4 +1,4 @@
-package linking
+package framing
 
 /*****************************************************************/
 /* CRC LOOKUP TABLE                                              */
@@ -20,7 +20,8 @@ var crc8Table []byte = []byte{
 	117, 151, 201, 74, 20, 246, 168, 116, 42, 200, 150, 21, 75, 169, 247, 182, 232, 10, 84, 215, 137, 107, 53,
 }
 
-func calcCrc8(data []byte) byte {
+// Crc8 calculates the CRC-8 used for both the header and footer checksums of a frame.
+func Crc8(data []byte) byte {
 	var crc byte = crcInitValue
 	for _, b := range data {
 		crc = crc8Table[crc^b]
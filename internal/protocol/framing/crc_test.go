@@ -1,8 +1,8 @@
-package linking
+package framing
 
 import "testing"
 
-func TestCalcCrc8(t *testing.T) {
+func TestCrc8(t *testing.T) {
 	t.Parallel()
 
 	tests := map[string]struct {
@@ -31,9 +31,9 @@ func TestCalcCrc8(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			test := test
 			t.Parallel()
-			crc := calcCrc8(test.input)
+			crc := Crc8(test.input)
 			if crc != test.expectedCrc {
-				t.Errorf("calcCrc8(%v) returned %v; expected %v", test.input, crc, test.expectedCrc)
+				t.Errorf("Crc8(%v) returned %v; expected %v", test.input, crc, test.expectedCrc)
 			}
 		})
 	}
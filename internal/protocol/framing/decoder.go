@@ -0,0 +1,141 @@
+package framing
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+const (
+	PacketStart byte = 0x02
+	PacketEnd   byte = 0x03
+
+	// Size of the header of both linked and broadcast packets,
+	// starting on the packet type and ending on (including) the header CRC.
+	HeaderSize = 9
+
+	// Index of the header CRC within the header.
+	HeaderCrcIdx = HeaderSize - 1
+
+	// Bytes of the header that are counted by the length field.
+	headerLengthBytes = HeaderSize - 3 // packet type + length
+
+	// Smallest valid length field, a header without payload followed by the footer CRC and end byte.
+	minLength = headerLengthBytes + 2
+
+	// Largest possible frame, start and end bytes excluded.
+	maxFrameSize = 3 + 0xFFFF - 1
+)
+
+// Decoder reads frames from a byte stream.
+//
+// It follows the recommended approach to receiving a packet: the header is read first
+// and verified using the header CRC, after which the length field can be trusted and
+// exactly that many bytes are read. Bytes that do not start a valid header, such as line noise
+// or the rest of a corrupted frame, are skipped until the next packet start byte.
+//
+// The footer CRC is not verified by the decoder; the length field already decides where the frame ends.
+type Decoder struct {
+	r       *bufio.Reader
+	skipped atomic.Uint64
+	resyncs atomic.Uint64
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: bufio.NewReaderSize(r, 1+maxFrameSize+1),
+	}
+}
+
+// ReadFrame returns the next frame in the stream
+// without its start and end bytes. The returned slice is owned by the caller.
+func (d *Decoder) ReadFrame() ([]byte, error) {
+	for {
+		err := d.skipToStart()
+		if err != nil {
+			return nil, err
+		}
+
+		frame, ok, err := d.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return frame, nil
+		}
+
+		// Only the start byte has been consumed, search for the next one right after it
+		d.resyncs.Add(1)
+	}
+}
+
+// Skipped returns the number of bytes discarded while searching for a valid frame.
+func (d *Decoder) Skipped() uint64 {
+	return d.skipped.Load()
+}
+
+// Resyncs returns the number of times a packet start byte
+// was found that did not begin a valid frame.
+func (d *Decoder) Resyncs() uint64 {
+	return d.resyncs.Load()
+}
+
+// skipToStart consumes bytes up to and including the next packet start byte.
+func (d *Decoder) skipToStart() error {
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b == PacketStart {
+			return nil
+		}
+		d.skipped.Add(1)
+	}
+}
+
+// readFrame reads the frame following a start byte. If the bytes do not form a valid frame
+// ok is false and nothing but the start byte is consumed, so the caller can resync.
+func (d *Decoder) readFrame() (frame []byte, ok bool, err error) {
+	header, err := d.r.Peek(HeaderSize)
+	if err != nil {
+		return nil, false, unexpectedEOF(err)
+	}
+
+	if Crc8(header[:HeaderCrcIdx]) != header[HeaderCrcIdx] {
+		return nil, false, nil
+	}
+
+	length := int(binary.LittleEndian.Uint16(header[1:3]))
+	if length < minLength {
+		return nil, false, nil
+	}
+
+	// The length field counts from after itself up to and including the end byte
+	frameSize := 3 + length
+	data, err := d.r.Peek(frameSize)
+	if err != nil {
+		return nil, false, unexpectedEOF(err)
+	}
+	if data[frameSize-1] != PacketEnd {
+		return nil, false, nil
+	}
+
+	frame = make([]byte, frameSize-1)
+	copy(frame, data)
+	_, err = d.r.Discard(frameSize)
+	if err != nil {
+		return nil, false, err
+	}
+	return frame, true, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("stream ended inside a frame: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
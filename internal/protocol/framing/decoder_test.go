@@ -0,0 +1,172 @@
+package framing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Frames captured from TifApp talking to a WinMower, including start and end bytes.
+var (
+	capturedDiscover = []byte{0x02, 0xFD, 0x0D, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x63, 0x12, 0x9F, 0x57, 0xF7, 0x70, 0x8A, 0x03}
+	capturedSetProto = []byte{0x02, 0xFD, 0x0A, 0x00, 0x25, 0xAE, 0xEF, 0x06, 0x00, 0x74, 0x08, 0x01, 0x28, 0x03}
+)
+
+// linkedFrame builds a linked packet frame on link 0 with valid CRCs.
+func linkedFrame(control byte, payload ...byte) []byte {
+	buf := []byte{PacketStart, 0xFD}
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(headerLengthBytes+len(payload)+2))
+	buf = append(buf, 0x00, 0x00, 0x00, 0x00, control)
+	buf = append(buf, Crc8(buf[1:HeaderSize]))
+	buf = append(buf, payload...)
+	buf = append(buf, Crc8(buf[1:]))
+	return append(buf, PacketEnd)
+}
+
+func unframed(frame []byte) []byte {
+	return frame[1 : len(frame)-1]
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestDecoderReadFrame(t *testing.T) {
+	t.Parallel()
+
+	withEndBytes := linkedFrame(0x01, 0x03, 0x03, 0x02, 0x03)
+	badHeader := bytes.Clone(capturedDiscover)
+	badHeader[9] ^= 0xFF
+	badEnd := bytes.Clone(capturedSetProto)
+	badEnd[len(badEnd)-1] = 0x00
+
+	tests := map[string]struct {
+		stream   []byte
+		expected [][]byte
+		resyncs  uint64
+	}{
+		"captured frames": {
+			stream:   concat(capturedDiscover, capturedSetProto),
+			expected: [][]byte{unframed(capturedDiscover), unframed(capturedSetProto)},
+		},
+		"payload with start and end bytes": {
+			stream:   concat(withEndBytes, capturedSetProto),
+			expected: [][]byte{unframed(withEndBytes), unframed(capturedSetProto)},
+		},
+		"empty payload": {
+			stream:   linkedFrame(0x00),
+			expected: [][]byte{unframed(linkedFrame(0x00))},
+		},
+		"noise between frames": {
+			stream:   concat([]byte{0xFF, 0x03, 0x00}, capturedDiscover, []byte{0x03, 0x42}, capturedSetProto),
+			expected: [][]byte{unframed(capturedDiscover), unframed(capturedSetProto)},
+		},
+		"start byte in noise": {
+			stream:   concat([]byte{0x02, 0x02, 0xFD, 0x03}, capturedSetProto),
+			expected: [][]byte{unframed(capturedSetProto)},
+			resyncs:  2,
+		},
+		"bad header crc": {
+			stream:   concat(badHeader, capturedSetProto),
+			expected: [][]byte{unframed(capturedSetProto)},
+			resyncs:  1,
+		},
+		"missing end byte": {
+			stream:   concat(badEnd, capturedDiscover),
+			expected: [][]byte{unframed(capturedDiscover)},
+			resyncs:  1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			decoder := NewDecoder(bytes.NewReader(test.stream))
+			var frames [][]byte
+			for {
+				frame, err := decoder.ReadFrame()
+				if err != nil {
+					if !errors.Is(err, io.EOF) {
+						t.Fatalf("expected EOF but got %v", err)
+					}
+					break
+				}
+				frames = append(frames, frame)
+			}
+
+			if !cmp.Equal(test.expected, frames) {
+				t.Errorf("expected frames %X but got %X", test.expected, frames)
+			}
+			if decoder.Resyncs() != test.resyncs {
+				t.Errorf("expected %d resyncs but got %d", test.resyncs, decoder.Resyncs())
+			}
+		})
+	}
+}
+
+func TestDecoderTruncatedFrame(t *testing.T) {
+	t.Parallel()
+
+	decoder := NewDecoder(bytes.NewReader(capturedDiscover[:12]))
+	_, err := decoder.ReadFrame()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	f.Add(concat(capturedDiscover, capturedSetProto))
+	f.Add(concat([]byte{0x03, 0x02}, capturedSetProto, capturedDiscover[:9]))
+	f.Add(linkedFrame(0x01, 0x03, 0x02, 0x03))
+	f.Add(capturedDiscover[:12])
+
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		decoder := NewDecoder(bytes.NewReader(stream))
+		for {
+			frame, err := decoder.ReadFrame()
+			if err != nil {
+				return
+			}
+
+			if len(frame) < HeaderSize+1 {
+				t.Fatalf("frame shorter than header and footer: %X", frame)
+			}
+			if Crc8(frame[:HeaderCrcIdx]) != frame[HeaderCrcIdx] {
+				t.Fatalf("frame with bad header crc: %X", frame)
+			}
+			if length := int(binary.LittleEndian.Uint16(frame[1:3])); len(frame) != 3+length-1 {
+				t.Fatalf("frame size %d does not match length field %d: %X", len(frame), length, frame)
+			}
+		}
+	})
+}
+
+func FuzzDecoderFindsFrameAfterNoise(f *testing.F) {
+	f.Add([]byte{0xFF, 0x03, 0x00})
+	f.Add([]byte{0x02, 0xFD})
+
+	f.Fuzz(func(t *testing.T, noise []byte) {
+		// A start byte in the noise may begin a frame that swallows the captured one
+		if bytes.IndexByte(noise, PacketStart) >= 0 {
+			return
+		}
+
+		decoder := NewDecoder(bytes.NewReader(concat(noise, capturedSetProto)))
+		frame, err := decoder.ReadFrame()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if !bytes.Equal(frame, unframed(capturedSetProto)) {
+			t.Errorf("expected frame %X but got %X", unframed(capturedSetProto), frame)
+		}
+		if decoder.Skipped() != uint64(len(noise)) {
+			t.Errorf("expected %d skipped bytes but got %d", len(noise), decoder.Skipped())
+		}
+	})
+}
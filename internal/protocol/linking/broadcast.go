@@ -0,0 +1,165 @@
+package linking
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+)
+
+// Number of broadcasts buffered per subscription. When the buffer is full
+// the oldest broadcast is dropped to make room, so a slow subscriber never stalls the reader.
+const subscriptionBufferSize = 64
+
+// Broadcast is a broadcast packet received from the device.
+type Broadcast struct {
+	MessageFamily    uint16
+	SenderId         byte
+	BroadcastChannel byte
+	Control          byte
+	Payload          Payload
+}
+
+// BroadcastFilter selects which broadcasts a subscription receives.
+// An empty list matches any value for that field.
+type BroadcastFilter struct {
+	Families []uint16
+	Senders  []byte
+	Channels []byte
+}
+
+func (f BroadcastFilter) matches(b Broadcast) bool {
+	if len(f.Families) > 0 && !slices.Contains(f.Families, b.MessageFamily) {
+		return false
+	}
+	if len(f.Senders) > 0 && !slices.Contains(f.Senders, b.SenderId) {
+		return false
+	}
+	if len(f.Channels) > 0 && !slices.Contains(f.Channels, b.BroadcastChannel) {
+		return false
+	}
+	return true
+}
+
+type Subscription struct {
+	filter     BroadcastFilter
+	mux        *LinkMux
+	broadcasts chan Broadcast
+	dropped    atomic.Uint64
+}
+
+// C returns the channel broadcasts are delivered on.
+// It is closed when the subscription is closed or the mux is stopped.
+func (s *Subscription) C() <-chan Broadcast {
+	return s.broadcasts
+}
+
+// Dropped returns the number of broadcasts dropped because the subscriber did not keep up.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) Close() {
+	s.mux.unsubscribe(s)
+}
+
+// offer delivers b without blocking, dropping the oldest buffered broadcast when full.
+func (s *Subscription) offer(b Broadcast) {
+	for {
+		select {
+		case s.broadcasts <- b:
+			return
+		default:
+		}
+
+		select {
+		case <-s.broadcasts:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+type subscriptions struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscribe streams broadcasts matching filter until the subscription is closed or the mux is stopped.
+func (lh *LinkMux) Subscribe(filter BroadcastFilter) (*Subscription, error) {
+	lh.subscriptions.mu.Lock()
+	defer lh.subscriptions.mu.Unlock()
+
+	// Checked under the lock so that Stop can not miss the subscription
+	if lh.shuttingDown() {
+		return nil, ErrLinkMuxShuttingDown
+	}
+
+	sub := &Subscription{
+		filter:     filter,
+		mux:        lh,
+		broadcasts: make(chan Broadcast, subscriptionBufferSize),
+	}
+	lh.subscriptions.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (lh *LinkMux) unsubscribe(sub *Subscription) {
+	lh.subscriptions.mu.Lock()
+	defer lh.subscriptions.mu.Unlock()
+
+	if _, ok := lh.subscriptions.subs[sub]; !ok {
+		return
+	}
+	delete(lh.subscriptions.subs, sub)
+	close(sub.broadcasts)
+}
+
+func (lh *LinkMux) closeSubscriptions() {
+	lh.subscriptions.mu.Lock()
+	defer lh.subscriptions.mu.Unlock()
+
+	for sub := range lh.subscriptions.subs {
+		delete(lh.subscriptions.subs, sub)
+		close(sub.broadcasts)
+	}
+}
+
+func (lh *LinkMux) publishBroadcast(b Broadcast) int {
+	lh.subscriptions.mu.RLock()
+	defer lh.subscriptions.mu.RUnlock()
+
+	delivered := 0
+	for sub := range lh.subscriptions.subs {
+		if !sub.filter.matches(b) {
+			continue
+		}
+
+		// Every subscriber gets its own copy of the payload
+		b := b
+		b.Payload = bytes.Clone(b.Payload)
+		sub.offer(b)
+		delivered++
+	}
+	return delivered
+}
+
+// EncodeBroadcastFrame marshalls a broadcast packet, calculates its
+// header and footer CRCs and wraps it in the start and end bytes.
+func EncodeBroadcastFrame(b Broadcast) []byte {
+	packetSize := framing.HeaderSize + len(b.Payload) + footerCrcSize
+
+	buf := make([]byte, 0, packetSize+2)
+	buf = append(buf, PacketStart, BroadcastPacketType)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(packetSize-packetTypeSize-lengthSize+1))
+	buf = binary.LittleEndian.AppendUint16(buf, b.MessageFamily)
+	buf = append(buf, b.SenderId, b.BroadcastChannel, b.Control)
+	buf = append(buf, framing.Crc8(buf[1:framing.HeaderSize]))
+	buf = append(buf, b.Payload...)
+	buf = append(buf, framing.Crc8(buf[1:]))
+	buf = append(buf, PacketEnd)
+	return buf
+}
@@ -0,0 +1,122 @@
+package linking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func rawBroadcast(b Broadcast) []byte {
+	frame := EncodeBroadcastFrame(b)
+	return frame[1 : len(frame)-1]
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		filter   BroadcastFilter
+		expected int
+	}{
+		"no filter":         {filter: BroadcastFilter{}, expected: 3},
+		"family":            {filter: BroadcastFilter{Families: []uint16{0x1234}}, expected: 2},
+		"family and sender": {filter: BroadcastFilter{Families: []uint16{0x1234}, Senders: []byte{0x01}}, expected: 1},
+		"channel":           {filter: BroadcastFilter{Channels: []byte{0x07}}, expected: 1},
+		"no match":          {filter: BroadcastFilter{Senders: []byte{0x09}}, expected: 0},
+	}
+
+	broadcasts := []Broadcast{
+		{MessageFamily: 0x1234, SenderId: 0x01, BroadcastChannel: 0x05, Control: ControlPayloadCommand, Payload: Payload{0x10}},
+		{MessageFamily: 0x1234, SenderId: 0x04, BroadcastChannel: 0x05, Control: ControlPayloadCommand, Payload: Payload{0x11}},
+		{MessageFamily: 0x4321, SenderId: 0x04, BroadcastChannel: 0x07, Control: ControlPayloadCommand, Payload: Payload{0x12}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+			mux, _ := newTestMux(t)
+
+			sub, err := mux.Subscribe(test.filter)
+			if err != nil {
+				t.Fatalf("expected no error but got %q", err)
+			}
+			for _, b := range broadcasts {
+				mux.routePacket(context.Background(), rawBroadcast(b))
+			}
+
+			if len(sub.C()) != test.expected {
+				t.Errorf("expected %d broadcasts but got %d", test.expected, len(sub.C()))
+			}
+		})
+	}
+}
+
+func TestSubscribeDeliversPayload(t *testing.T) {
+	t.Parallel()
+	mux, _ := newTestMux(t)
+
+	sub, err := mux.Subscribe(BroadcastFilter{})
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	expected := Broadcast{MessageFamily: 0x1234, SenderId: 0x01, BroadcastChannel: 0x05, Control: ControlPayloadCommand, Payload: Payload{0x10, 0x20, 0x30}}
+	mux.routePacket(context.Background(), rawBroadcast(expected))
+
+	b := <-sub.C()
+	if !cmp.Equal(expected, b) {
+		t.Errorf("expected %+v but got %+v", expected, b)
+	}
+}
+
+func TestSubscriptionDropsOldest(t *testing.T) {
+	t.Parallel()
+	mux, _ := newTestMux(t)
+
+	slow, err := mux.Subscribe(BroadcastFilter{})
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	const overflow = 10
+	for i := 0; i < subscriptionBufferSize+overflow; i++ {
+		mux.publishBroadcast(Broadcast{Payload: Payload{byte(i)}})
+	}
+
+	if slow.Dropped() != overflow {
+		t.Errorf("expected %d dropped broadcasts but got %d", overflow, slow.Dropped())
+	}
+	first := <-slow.C()
+	if first.Payload[0] != overflow {
+		t.Errorf("expected oldest remaining broadcast to be %d but got %d", overflow, first.Payload[0])
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	t.Parallel()
+	mux, _ := newTestMux(t)
+
+	sub, err := mux.Subscribe(BroadcastFilter{})
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	sub.Close()
+	sub.Close()
+	if _, ok := <-sub.C(); ok {
+		t.Error("expected subscription channel to be closed")
+	}
+
+	other, err := mux.Subscribe(BroadcastFilter{})
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	mux.Stop()
+	if _, ok := <-other.C(); ok {
+		t.Error("expected subscription channel to be closed after stop")
+	}
+	if _, err := mux.Subscribe(BroadcastFilter{}); err != ErrLinkMuxShuttingDown {
+		t.Errorf("expected %v but got %v", ErrLinkMuxShuttingDown, err)
+	}
+}
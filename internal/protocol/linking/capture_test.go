@@ -0,0 +1,90 @@
+package linking
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
+	"github.com/charmbracelet/log"
+)
+
+// readCapture reads a capture from testdata.
+//
+// session.cap holds a link manager discover, connect and set protocol exchange
+// on link 0x06EFAE25, a payload request and response, and four broadcasts.
+func readCapture(t *testing.T, name string) []capture.Record {
+	t.Helper()
+
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("failed to open capture: %v", err)
+	}
+	defer file.Close()
+
+	r, err := capture.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to read capture: %v", err)
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read capture: %v", err)
+	}
+	return records
+}
+
+func TestParseCapturedFrames(t *testing.T) {
+	t.Parallel()
+
+	var linked, broadcasts int
+	for i, record := range readCapture(t, "testdata/session.cap") {
+		switch record.Frame[0] {
+		case LinkedPacketType:
+			if _, err := parseLinkedPacket(record.Frame); err != nil {
+				t.Errorf("record %d: parseLinkedPacket(%v) returned %v", i, record.Frame, err)
+			}
+			linked++
+		case BroadcastPacketType:
+			if _, _, err := parseBroadcastPacket(record.Frame); err != nil {
+				t.Errorf("record %d: parseBroadcastPacket(%v) returned %v", i, record.Frame, err)
+			}
+			broadcasts++
+		default:
+			t.Errorf("record %d: unexpected packet type %X", i, record.Frame[0])
+		}
+	}
+
+	if linked != 8 || broadcasts != 4 {
+		t.Errorf("expected 8 linked and 4 broadcast frames but got %d and %d", linked, broadcasts)
+	}
+}
+
+func TestReplayCaptureThroughMux(t *testing.T) {
+	t.Parallel()
+
+	records := readCapture(t, "testdata/session.cap")
+	device := automower.NewDevice(capture.NewReplayStream(records, false), context.Background())
+	mux := NewLinkMux(device, log.New(io.Discard))
+	defer mux.Stop()
+
+	sub, err := mux.Subscribe(BroadcastFilter{Families: []uint16{0x1234}})
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	err = mux.Start()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expected mux to stop with %v at the end of the capture but got %v", io.EOF, err)
+	}
+
+	if len(sub.C()) != 3 {
+		t.Errorf("expected 3 broadcasts but got %d", len(sub.C()))
+	}
+	stats := mux.Stats()
+	if stats.Received != 8 || stats.Corrupted != 0 {
+		t.Errorf("expected 8 received frames without corruption but got %+v", stats)
+	}
+}
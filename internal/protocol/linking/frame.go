@@ -0,0 +1,71 @@
+package linking
+
+import (
+	"fmt"
+)
+
+// Frame is a parsed linked or broadcast packet, used to inspect traffic
+// without routing it. Only the fields of the frames packet type are set.
+type Frame struct {
+	PacketType byte
+	Control    byte
+
+	// Linked packets
+	LinkId LinkId
+
+	// Broadcast packets
+	MessageFamily    uint16
+	SenderId         byte
+	BroadcastChannel byte
+
+	Payload Payload
+}
+
+// ParseFrame parses a frame without start and end bytes, verifying its length and CRCs.
+func ParseFrame(data []byte) (Frame, error) {
+	if len(data) == 0 {
+		return Frame{}, &ErrLengthMismatch{Expected: linkedPacketSize(0), Actual: 0}
+	}
+
+	switch data[0] {
+	case LinkedPacketType:
+		packet, err := parseLinkedPacket(data)
+		if err != nil {
+			return Frame{}, err
+		}
+		return Frame{
+			PacketType: packet.PacketType,
+			Control:    packet.Control,
+			LinkId:     packet.LinkId,
+			Payload:    packet.Payload,
+		}, nil
+	case BroadcastPacketType:
+		packet, payload, err := parseBroadcastPacket(data)
+		if err != nil {
+			return Frame{}, err
+		}
+		return Frame{
+			PacketType:       packet.PacketType,
+			Control:          packet.Control,
+			MessageFamily:    packet.MessageFamily,
+			SenderId:         packet.SenderId,
+			BroadcastChannel: packet.BroadcastChannel,
+			Payload:          payload,
+		}, nil
+	default:
+		return Frame{}, &ErrUnkownPacketType{Type: data[0]}
+	}
+}
+
+func (f Frame) String() string {
+	control := "payload"
+	if f.Control == ControlLinkManagerCommand {
+		control = "link-manager"
+	}
+
+	if f.PacketType == BroadcastPacketType {
+		return fmt.Sprintf("broadcast family=0x%04X sender=%d channel=%d %s [%s]",
+			f.MessageFamily, f.SenderId, f.BroadcastChannel, control, f.Payload)
+	}
+	return fmt.Sprintf("linked link=0x%08X %s [%s]", uint32(f.LinkId), control, f.Payload)
+}
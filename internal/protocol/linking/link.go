@@ -1,14 +1,16 @@
 package linking
 
-import "io"
+import (
+	"context"
 
-type LinkId uint32
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+)
 
-const ()
+type LinkId uint32
 
 type Link struct {
-	id     LinkId
-	writer io.Writer
+	id  LinkId
+	mux *LinkMux
 }
 
 func (l Link) Id() LinkId {
@@ -16,20 +18,74 @@ func (l Link) Id() LinkId {
 }
 
 func (l Link) write(data []byte) (n int, err error) {
-	return l.writer.Write(data)
+	return l.mux.Write(data)
+}
+
+// SendLinkedRequest writes a linked packet with the given control byte and payload on the link.
+//
+// The returned channel receives the payload of every inbound linked packet
+// on the same link id and with the same control byte, starting from before the
+// request is written. It is closed when ctx is done or the mux is stopped.
+func (l Link) SendLinkedRequest(ctx context.Context, ctrl byte, payload []byte) (<-chan []byte, error) {
+	responses, err := l.mux.listen(ctx, l.id, ctrl)
+	if err != nil {
+		return nil, err
+	}
+
+	packetBuf, err := EncodeLinkedFrame(l.id, ctrl, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = l.write(packetBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	return responses, nil
 }
 
-func (l Link) SendLinkedRequest(ctrl byte, payload []byte) (<-chan []byte, error) {
+// Request sends a linked request and waits for the first response on the link accepted by match,
+// which tells the responses to this request apart from responses to other requests on the link.
+// A nil match accepts any response. Use a context with a deadline to bound how long to wait.
+func (l Link) Request(ctx context.Context, ctrl byte, payload []byte, match func(response []byte) bool) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses, err := l.SendLinkedRequest(ctx, ctrl, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case response, ok := <-responses:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, ErrLinkMuxShuttingDown
+			}
+			if match == nil || match(response) {
+				return response, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// EncodeLinkedFrame marshalls a linked packet, calculates its
+// header and footer CRCs and wraps it in the start and end bytes.
+func EncodeLinkedFrame(linkId LinkId, ctrl byte, payload []byte) ([]byte, error) {
 	payloadSize := len(payload)
 	length := 1 + uint16(linkIdSize+controlSize+headerCrcSize+payloadSize+footerCrcSize)
 	packet := linkedPacket{
 		PacketType: LinkedPacketType,
 		Length:     length,
-		LinkId:     l.id,
+		LinkId:     linkId,
 		Control:    ctrl,
-		HeaderCrc:  0x63,
 		Payload:    payload,
-		FooterCrc:  0x74,
 	}
 
 	packetSize := linkedPacketSize(payloadSize)
@@ -39,22 +95,16 @@ func (l Link) SendLinkedRequest(ctrl byte, payload []byte) (<-chan []byte, error
 		return nil, err
 	}
 
-	const headerCrcIdx int = 8 // packetTypeSize + lengthSize + linkIdSize + controlSize
-	headerCrc := calcCrc8(buf[0:headerCrcIdx])
+	headerCrc := framing.Crc8(buf[0:headerCrcIdx])
 	buf[headerCrcIdx] = headerCrc // headerCrc comes right after control byte
 
-	footerCrc := calcCrc8(buf[0 : packetSize-1])
+	footerCrc := framing.Crc8(buf[0 : packetSize-1])
 	buf[packetSize-1] = footerCrc
 
 	packetBuf := make([]byte, packetSize+2)
-	packetBuf[0] = 0x02
+	packetBuf[0] = PacketStart
 	copy(packetBuf[1:], buf)
-	packetBuf[packetSize+1] = 0x03
-
-	_, err = l.write(packetBuf)
-	if err != nil {
-		return nil, err
-	}
+	packetBuf[packetSize+1] = PacketEnd
 
-	return nil, nil
+	return packetBuf, nil
 }
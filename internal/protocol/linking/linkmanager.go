@@ -0,0 +1,170 @@
+package linking
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Link manager request and response ids, the first byte of every
+// link manager command payload. See the LinkManager family in the tif definition.
+const (
+	LinkManagerDeleteLinkRequest   byte = 0x02
+	LinkManagerDeleteLinkResponse  byte = 0x03
+	LinkManagerSetProtocolRequest  byte = 0x08
+	LinkManagerSetProtocolResponse byte = 0x09
+	LinkManagerDiscoverRequest     byte = 0x12
+	LinkManagerDiscoverResponse    byte = 0x13
+	LinkManagerConnectRequest      byte = 0x14
+	LinkManagerConnectResponse     byte = 0x15
+	LinkManagerPingRequest         byte = 0x16
+	LinkManagerPingResponse        byte = 0x17
+)
+
+const (
+	// Node type sent when connecting a new link.
+	// The link manager does not interpret it.
+	nodeTypeTool uint32 = 0
+	nodeName            = "tools-cli"
+)
+
+type ErrLinkManagerResult struct {
+	Command string
+	Result  byte
+}
+
+func (e *ErrLinkManagerResult) Error() string {
+	return fmt.Sprintf("link manager %s failed with result %d", e.Command, e.Result)
+}
+
+// OpenLink creates a new logical link with a random link id.
+// The link can be used concurrently with any other open link.
+func (lh *LinkMux) OpenLink(ctx context.Context) (*Link, error) {
+	linkId := lh.newLinkId()
+
+	payload := make([]byte, 0, 1+4+4+len(nodeName)+1)
+	payload = append(payload, LinkManagerConnectRequest)
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(linkId))
+	payload = binary.LittleEndian.AppendUint32(payload, nodeTypeTool)
+	payload = append(payload, nodeName...)
+	payload = append(payload, 0x00)
+
+	_, err := lh.DefaultLink.Request(ctx, ControlLinkManagerCommand, payload, func(response []byte) bool {
+		return len(response) >= 5 &&
+			response[0] == LinkManagerConnectResponse &&
+			LinkId(binary.LittleEndian.Uint32(response[1:5])) == linkId
+	})
+	if err != nil {
+		lh.releaseLinkId(linkId)
+		return nil, fmt.Errorf("failed to connect link %d: %w", linkId, err)
+	}
+
+	link := &Link{
+		id:  linkId,
+		mux: lh,
+	}
+	lh.linksMu.Lock()
+	lh.links[linkId] = link
+	lh.linksMu.Unlock()
+
+	lh.logger.Debug("Opened link", "linkId", linkId)
+	return link, nil
+}
+
+// Links returns the links opened through the mux that have not yet been closed, ordered by id.
+func (lh *LinkMux) Links() []*Link {
+	lh.linksMu.Lock()
+	defer lh.linksMu.Unlock()
+
+	links := make([]*Link, 0, len(lh.links))
+	for _, link := range lh.links {
+		if link == nil {
+			continue
+		}
+		links = append(links, link)
+	}
+	slices.SortFunc(links, func(a, b *Link) int {
+		return cmp.Compare(a.id, b.id)
+	})
+	return links
+}
+
+// newLinkId reserves a random, non-zero link id that is not in use by another link.
+func (lh *LinkMux) newLinkId() LinkId {
+	lh.linksMu.Lock()
+	defer lh.linksMu.Unlock()
+
+	for {
+		linkId := LinkId(rand.Uint32())
+		if linkId == DefaultLinkId {
+			continue
+		}
+		if _, inUse := lh.links[linkId]; inUse {
+			continue
+		}
+
+		// Reserved until the link is connected or released
+		lh.links[linkId] = nil
+		return linkId
+	}
+}
+
+func (lh *LinkMux) releaseLinkId(linkId LinkId) {
+	lh.linksMu.Lock()
+	delete(lh.links, linkId)
+	lh.linksMu.Unlock()
+}
+
+// SetProtocol sets the robotics protocol used for payloads on the link,
+// either RoboticsProtocol1 or RoboticsProtocol2.
+func (l Link) SetProtocol(ctx context.Context, protocol byte) error {
+	response, err := l.Request(ctx, ControlLinkManagerCommand, []byte{LinkManagerSetProtocolRequest, protocol}, func(response []byte) bool {
+		return len(response) > 0 && response[0] == LinkManagerSetProtocolResponse
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set protocol on link %d: %w", l.id, err)
+	}
+	if len(response) < 2 {
+		return fmt.Errorf("malformed set protocol response on link %d: %s", l.id, Payload(response))
+	}
+	if result := response[1]; result != 0 {
+		return &ErrLinkManagerResult{Command: "SetProtocol", Result: result}
+	}
+	return nil
+}
+
+// Close deletes the link on the device. The link can not be used after it is closed.
+func (l Link) Close(ctx context.Context) error {
+	if l.id == DefaultLinkId {
+		return fmt.Errorf("the default link can not be closed")
+	}
+
+	payload := binary.LittleEndian.AppendUint32([]byte{LinkManagerDeleteLinkRequest}, uint32(l.id))
+	response, err := l.mux.DefaultLink.Request(ctx, ControlLinkManagerCommand, payload, func(response []byte) bool {
+		return len(response) >= 6 &&
+			response[0] == LinkManagerDeleteLinkResponse &&
+			LinkId(binary.LittleEndian.Uint32(response[2:6])) == l.id
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete link %d: %w", l.id, err)
+	}
+
+	l.mux.releaseLinkId(l.id)
+	if result := response[1]; result != 0 {
+		return &ErrLinkManagerResult{Command: "DeleteLink", Result: result}
+	}
+
+	l.mux.logger.Debug("Closed link", "linkId", l.id)
+	return nil
+}
+
+// Ping checks that the link manager on the other end of the link responds.
+func (l Link) Ping(ctx context.Context) error {
+	_, err := l.Request(ctx, ControlLinkManagerCommand, []byte{LinkManagerPingRequest}, func(response []byte) bool {
+		return len(response) > 0 && response[0] == LinkManagerPingResponse
+	})
+	return err
+}
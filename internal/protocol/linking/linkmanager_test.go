@@ -0,0 +1,142 @@
+package linking
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+)
+
+// serveLinkManager answers link manager commands read from conn until it is closed.
+// SetProtocol requests for protocols other than RoboticsProtocol1 and 2 fail with result 1.
+func serveLinkManager(conn net.Conn) {
+	decoder := framing.NewDecoder(conn)
+	for {
+		frame, err := decoder.ReadFrame()
+		if err != nil {
+			return
+		}
+		packet, err := parseLinkedPacket(frame)
+		if err != nil || packet.Control != ControlLinkManagerCommand {
+			continue
+		}
+
+		var response []byte
+		switch packet.Payload[0] {
+		case LinkManagerConnectRequest:
+			response = append([]byte{LinkManagerConnectResponse}, packet.Payload[1:5]...)
+		case LinkManagerSetProtocolRequest:
+			var result byte
+			if packet.Payload[1] > RoboticsProtocol2 {
+				result = 1
+			}
+			response = []byte{LinkManagerSetProtocolResponse, result, packet.Payload[1]}
+		case LinkManagerDeleteLinkRequest:
+			response = append([]byte{LinkManagerDeleteLinkResponse, 0x00}, packet.Payload[1:5]...)
+		case LinkManagerPingRequest:
+			response = []byte{LinkManagerPingResponse}
+		default:
+			continue
+		}
+
+		responseFrame, _ := EncodeLinkedFrame(packet.LinkId, ControlLinkManagerCommand, response)
+		conn.Write(responseFrame)
+	}
+}
+
+func TestDefaultLinkSetProtocol(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+	go serveLinkManager(remote)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := mux.DefaultLink.Ping(ctx); err != nil {
+		t.Fatalf("expected no error from ping but got %q", err)
+	}
+	if err := mux.DefaultLink.SetProtocol(ctx, RoboticsProtocol2); err != nil {
+		t.Errorf("expected no error but got %q", err)
+	}
+}
+
+func TestOpenLinksConcurrently(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+	go serveLinkManager(remote)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	const numLinks = 4
+	var wg sync.WaitGroup
+	errs := make(chan error, numLinks)
+	for i := 0; i < numLinks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			link, err := mux.OpenLink(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- link.SetProtocol(ctx, RoboticsProtocol2)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected no error but got %q", err)
+		}
+	}
+
+	links := mux.Links()
+	if len(links) != numLinks {
+		t.Fatalf("expected %d open links but got %d", numLinks, len(links))
+	}
+
+	for _, link := range links {
+		if err := link.Close(ctx); err != nil {
+			t.Errorf("failed to close link %d: %v", link.Id(), err)
+		}
+	}
+	if len(mux.Links()) != 0 {
+		t.Errorf("expected no open links after close but got %d", len(mux.Links()))
+	}
+}
+
+func TestSetProtocolResultError(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+	go serveLinkManager(remote)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := mux.DefaultLink.SetProtocol(ctx, 0x05)
+	var resultErr *ErrLinkManagerResult
+	if !errors.As(err, &resultErr) {
+		t.Fatalf("expected ErrLinkManagerResult but got %v", err)
+	}
+	if resultErr.Result != 1 {
+		t.Errorf("expected result 1 but got %d", resultErr.Result)
+	}
+}
+
+func TestCloseDefaultLink(t *testing.T) {
+	t.Parallel()
+	mux, _ := newTestMux(t)
+
+	if err := mux.DefaultLink.Close(context.Background()); err == nil {
+		t.Error("expected error when closing the default link")
+	}
+	if len(mux.Links()) != 0 {
+		t.Errorf("expected no open links but got %d", len(mux.Links()))
+	}
+}
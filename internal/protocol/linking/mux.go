@@ -1,18 +1,25 @@
 package linking
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"sync/atomic"
 
 	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
 	"github.com/charmbracelet/log"
 )
 
 const (
 	DefaultLinkId LinkId = 0
+
+	// Number of responses buffered per listener before
+	// the router waits for the listener to catch up.
+	responseBufferSize = 8
 )
 
 var (
@@ -23,30 +30,53 @@ type LinkMux struct {
 	device     *automower.Device
 	logger     *log.Logger
 	inShutdown atomic.Bool
+	done       chan struct{}
 	writeChan  chan []byte
 
-	linkIdCounter atomic.Uint32
-	DefaultLink   *Link
+	listenersMu sync.Mutex
+	listeners   map[LinkId]map[*responseListener]struct{}
+
+	linksMu sync.Mutex
+	links   map[LinkId]*Link
+
+	subscriptions subscriptions
+	counters      frameCounters
+	recorder      atomic.Pointer[capture.Writer]
+
+	DefaultLink *Link
+}
+
+// responseListener receives the payloads of inbound linked
+// packets for a single outstanding request.
+type responseListener struct {
+	ctrl      byte
+	done      <-chan struct{}
+	responses chan []byte
 }
 
 func NewLinkMux(device *automower.Device, logger *log.Logger) *LinkMux {
 	mux := &LinkMux{
-		device:        device,
-		logger:        logger,
-		writeChan:     make(chan []byte),
-		inShutdown:    atomic.Bool{},
-		linkIdCounter: atomic.Uint32{},
+		device:     device,
+		logger:     logger,
+		done:       make(chan struct{}),
+		writeChan:  make(chan []byte),
+		inShutdown: atomic.Bool{},
+		listeners:  make(map[LinkId]map[*responseListener]struct{}),
+		links:      make(map[LinkId]*Link),
+		subscriptions: subscriptions{
+			subs: make(map[*Subscription]struct{}),
+		},
 	}
 	mux.DefaultLink = &Link{
-		id:     DefaultLinkId,
-		writer: mux,
+		id:  DefaultLinkId,
+		mux: mux,
 	}
 	return mux
 }
 
 func (lh *LinkMux) Start() error {
 	go func() {
-		err := writeWorker(lh.writeChan, lh.device)
+		err := writeWorker(lh.writeChan, lh.done, lh.device)
 		if err != nil {
 			lh.logger.Error("err from writer worker", "err", err)
 		}
@@ -61,19 +91,31 @@ func (lh *LinkMux) Start() error {
 			return err
 		}
 
-		go lh.routePacket(context.TODO(), rawPacket)
+		// Routed in order so that responses reach their listeners in the order they were received
+		lh.routePacket(context.TODO(), rawPacket)
 	}
 }
 
 func (lh *LinkMux) Stop() error {
-	lh.inShutdown.Store(true)
-	close(lh.writeChan)
+	lh.subscriptions.mu.Lock()
+	stopping := lh.inShutdown.CompareAndSwap(false, true)
+	lh.subscriptions.mu.Unlock()
+	if !stopping {
+		return nil
+	}
+	close(lh.done)
+	lh.closeSubscriptions()
 	lh.device.Close()
 	return nil
 }
 
 func (lh *LinkMux) Write(data []byte) (n int, err error) {
-	lh.writeChan <- data
+	select {
+	case lh.writeChan <- data:
+	case <-lh.done:
+		return 0, ErrLinkMuxShuttingDown
+	}
+	lh.record(capture.Sent, data)
 	lh.logger.Debug("writing to device", "data", Payload(data))
 	return len(data), nil
 }
@@ -84,46 +126,159 @@ func (lh *LinkMux) shuttingDown() bool {
 
 func (lh *LinkMux) readFromDevice() (rawPacket []byte, err error) {
 	select {
-	case rawPacket = <-lh.device.PacketChan:
+	case rawPacket, ok := <-lh.device.PacketChan:
+		if !ok {
+			// The device reports why it stopped reading before closing PacketChan,
+			// so every packet read before the error is routed first
+			select {
+			case err = <-lh.device.ErrChan:
+				return nil, err
+			default:
+				return nil, io.EOF
+			}
+		}
 		lh.logger.Debug("received from device", "packet", Payload(rawPacket).String())
+		lh.record(capture.Received, rawPacket)
 		return rawPacket, nil
-	case err = <-lh.device.ErrChan:
-		return nil, err
+	case <-lh.done:
+		return nil, ErrLinkMuxShuttingDown
+	}
+}
+
+// listen registers a listener for inbound linked packets on linkId with the given control byte.
+// The listener is removed, and its channel closed, when ctx is done or the mux is stopped.
+func (lh *LinkMux) listen(ctx context.Context, linkId LinkId, ctrl byte) (<-chan []byte, error) {
+	if lh.shuttingDown() {
+		return nil, ErrLinkMuxShuttingDown
+	}
+
+	listener := &responseListener{
+		ctrl:      ctrl,
+		done:      ctx.Done(),
+		responses: make(chan []byte, responseBufferSize),
+	}
+
+	lh.listenersMu.Lock()
+	linkListeners, ok := lh.listeners[linkId]
+	if !ok {
+		linkListeners = make(map[*responseListener]struct{})
+		lh.listeners[linkId] = linkListeners
+	}
+	linkListeners[listener] = struct{}{}
+	lh.listenersMu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-lh.done:
+		}
+		lh.removeListener(linkId, listener)
+	}()
+
+	return listener.responses, nil
+}
+
+func (lh *LinkMux) removeListener(linkId LinkId, listener *responseListener) {
+	lh.listenersMu.Lock()
+	defer lh.listenersMu.Unlock()
+
+	linkListeners, ok := lh.listeners[linkId]
+	if !ok {
+		return
+	}
+	if _, ok := linkListeners[listener]; !ok {
+		return
+	}
+
+	delete(linkListeners, listener)
+	if len(linkListeners) == 0 {
+		delete(lh.listeners, linkId)
+	}
+	close(listener.responses)
+}
+
+// deliverLinked hands the packet payload to every listener waiting on the packets link.
+// Blocks until each listener accepts the payload or is done.
+func (lh *LinkMux) deliverLinked(packet linkedPacket) int {
+	lh.listenersMu.Lock()
+	defer lh.listenersMu.Unlock()
+
+	delivered := 0
+	for listener := range lh.listeners[packet.LinkId] {
+		if listener.ctrl != packet.Control {
+			continue
+		}
+
+		select {
+		case listener.responses <- bytes.Clone(packet.Payload):
+			delivered++
+		case <-listener.done:
+		case <-lh.done:
+			return delivered
+		}
 	}
+	return delivered
 }
 
 func (lh *LinkMux) routePacket(_ context.Context, rawPacket []byte) {
+	lh.counters.received.Add(1)
+	if len(rawPacket) == 0 {
+		lh.counters.countError(&ErrLengthMismatch{Expected: linkedPacketSize(0), Actual: 0})
+		return
+	}
+
 	switch rawPacket[0] {
 	case BroadcastPacketType:
 		packet, payload, err := parseBroadcastPacket(rawPacket)
 		if err != nil {
+			lh.counters.countError(err)
 			lh.logger.Error("Error parsing packet", "err", err)
 			return
 		}
 
 		lh.logger.Debug("Parsed broadcast packet", "channel", packet.BroadcastChannel, "control", packet.Control, "payloadSize", len(payload), "senderId", packet.SenderId, "familyId", packet.MessageFamily)
+		delivered := lh.publishBroadcast(Broadcast{
+			MessageFamily:    packet.MessageFamily,
+			SenderId:         packet.SenderId,
+			BroadcastChannel: packet.BroadcastChannel,
+			Control:          packet.Control,
+			Payload:          payload,
+		})
+		if delivered == 0 {
+			lh.counters.dropped.Add(1)
+		}
 	case LinkedPacketType:
 		packet, err := parseLinkedPacket(rawPacket)
 		if err != nil {
+			lh.counters.countError(err)
 			lh.logger.Error("Error parsing packet", "err", err)
 			return
 		}
 
 		lh.logger.Debug("Parsed linked packet", "linkId", packet.LinkId, "control", packet.Control, "payloadSize", len(packet.Payload), "payload", Payload(packet.Payload).String())
+		if lh.deliverLinked(packet) == 0 {
+			lh.counters.dropped.Add(1)
+			lh.logger.Debug("No listener for linked packet", "linkId", packet.LinkId, "control", packet.Control)
+		}
 	default:
+		lh.counters.countError(&ErrUnkownPacketType{Type: rawPacket[0]})
 		lh.logger.Debug("Skipping packet, neither linked or broadcast packet", "packet", Payload(rawPacket).String())
 	}
 }
 
-func writeWorker(input <-chan []byte, out io.Writer) error {
-	for data := range input {
-		n, err := out.Write(data)
-		if err != nil {
-			return err
-		}
-		if n != len(data) {
-			return fmt.Errorf("was unable to write entire data, expected to write %d bytes but only wrote %d bytes", len(data), n)
+func writeWorker(input <-chan []byte, done <-chan struct{}, out io.Writer) error {
+	for {
+		select {
+		case data := <-input:
+			n, err := out.Write(data)
+			if err != nil {
+				return err
+			}
+			if n != len(data) {
+				return fmt.Errorf("was unable to write entire data, expected to write %d bytes but only wrote %d bytes", len(data), n)
+			}
+		case <-done:
+			return nil
 		}
 	}
-	return nil
 }
@@ -0,0 +1,210 @@
+package linking
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+	"github.com/charmbracelet/log"
+)
+
+func newTestMux(t *testing.T) (*LinkMux, net.Conn) {
+	t.Helper()
+
+	deviceConn, remoteConn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	device := automower.NewDevice(deviceConn, ctx)
+	mux := NewLinkMux(device, log.New(io.Discard))
+	go mux.Start()
+
+	t.Cleanup(func() {
+		cancel()
+		mux.Stop()
+		remoteConn.Close()
+	})
+	return mux, remoteConn
+}
+
+func TestLinkRequestReceivesResponse(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+
+	go func() {
+		request, err := framing.NewDecoder(remote).ReadFrame()
+		if err != nil {
+			t.Errorf("failed to read request: %v", err)
+			return
+		}
+		packet, err := parseLinkedPacket(request)
+		if err != nil {
+			t.Errorf("device received malformed request: %v", err)
+			return
+		}
+
+		response, _ := EncodeLinkedFrame(packet.LinkId, packet.Control, []byte{0x09, 0x00, 0x01})
+		remote.Write(response)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := mux.DefaultLink.Request(ctx, ControlLinkManagerCommand, []byte{0x08, 0x01}, nil)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	expected := []byte{0x09, 0x00, 0x01}
+	if !bytes.Equal(response, expected) {
+		t.Errorf("Request returned %v; expected %v", response, expected)
+	}
+}
+
+func TestConcurrentRequestsMatchTheirResponses(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+
+	// Answers both requests once it has read them, the second one first
+	go func() {
+		decoder := framing.NewDecoder(remote)
+		var requests []linkedPacket
+		for range 2 {
+			request, err := decoder.ReadFrame()
+			if err != nil {
+				t.Errorf("failed to read request: %v", err)
+				return
+			}
+			packet, err := parseLinkedPacket(request)
+			if err != nil {
+				t.Errorf("device received malformed request: %v", err)
+				return
+			}
+			requests = append(requests, packet)
+		}
+		for i := len(requests) - 1; i >= 0; i-- {
+			response, _ := EncodeLinkedFrame(requests[i].LinkId, requests[i].Control, []byte{requests[i].Payload[0] + 1})
+			remote.Write(response)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	responses := make(chan []byte, 2)
+	for _, requestId := range []byte{0x20, 0x30} {
+		go func() {
+			response, err := mux.DefaultLink.Request(ctx, ControlPayloadCommand, []byte{requestId}, func(response []byte) bool {
+				return response[0] == requestId+1
+			})
+			if err != nil {
+				t.Errorf("request %X: expected no error but got %v", requestId, err)
+			}
+			if !bytes.Equal(response, []byte{requestId + 1}) {
+				t.Errorf("request %X: expected its own response but got %X", requestId, response)
+			}
+			responses <- response
+		}()
+	}
+	<-responses
+	<-responses
+}
+
+func TestLinkRequestTimesOut(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+
+	go io.Copy(io.Discard, remote)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := mux.DefaultLink.Request(ctx, ControlLinkManagerCommand, []byte{0x16}, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSendLinkedRequestClosedOnCancel(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+
+	go io.Copy(io.Discard, remote)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	responses, err := mux.DefaultLink.SendLinkedRequest(ctx, ControlLinkManagerCommand, []byte{0x16})
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-responses:
+		if ok {
+			t.Error("expected response channel to be closed without responses")
+		}
+	case <-time.After(time.Second):
+		t.Error("response channel was not closed after cancel")
+	}
+}
+
+func TestStatsCountsFrameErrors(t *testing.T) {
+	t.Parallel()
+	mux, _ := newTestMux(t)
+
+	valid := []byte{0xFD, 0x0D, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x63, 0x12, 0x5F, 0x4D, 0xB6, 0x16, 0x74}
+	badHeader := bytes.Clone(valid)
+	badHeader[8] = 0x00
+	badFooter := bytes.Clone(valid)
+	badFooter[14] = 0x00
+
+	for _, packet := range [][]byte{valid, badHeader, badFooter, valid[:12], {0xAB, 0x00}} {
+		mux.routePacket(context.Background(), packet)
+	}
+
+	expected := FrameStats{
+		Received:        5,
+		Corrupted:       3,
+		HeaderCrcErrors: 1,
+		FooterCrcErrors: 1,
+		LengthErrors:    1,
+		Dropped:         2, // valid packet without listener and unknown type
+		UnknownTypes:    1,
+	}
+	if stats := mux.Stats(); stats != expected {
+		t.Errorf("expected stats %+v but got %+v", expected, stats)
+	}
+}
+
+func TestLinkRequestResponseWithEndBytes(t *testing.T) {
+	t.Parallel()
+	mux, remote := newTestMux(t)
+
+	expected := []byte{PacketEnd, PacketStart, PacketEnd}
+	go func() {
+		request, err := framing.NewDecoder(remote).ReadFrame()
+		if err != nil {
+			t.Errorf("failed to read request: %v", err)
+			return
+		}
+		packet, err := parseLinkedPacket(request)
+		if err != nil {
+			t.Errorf("device received malformed request: %v", err)
+			return
+		}
+
+		response, _ := EncodeLinkedFrame(packet.LinkId, packet.Control, expected)
+		remote.Write(response)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := mux.DefaultLink.Request(ctx, ControlPayloadCommand, []byte{0x01}, nil)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	if !bytes.Equal(response, expected) {
+		t.Errorf("Request returned %v; expected %v", response, expected)
+	}
+}
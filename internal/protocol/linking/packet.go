@@ -4,17 +4,18 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
 )
 
 const (
 	RoboticsProtocol1 byte = 0x00
 	RoboticsProtocol2 byte = 0x01
 
-	PacketStart byte = 0x02
-	PacketEnd   byte = 0x03
+	PacketStart byte = framing.PacketStart
+	PacketEnd   byte = framing.PacketEnd
 
 	LinkedPacketType    byte = 0xFD
 	BroadcastPacketType byte = 0xFC
@@ -36,9 +37,74 @@ func linkedPacketSize(payloadSize int) int {
 	return packetTypeSize + lengthSize + linkIdSize + controlSize + headerCrcSize + payloadSize + footerCrcSize
 }
 
-var (
-	ErrUnkownPacketType = errors.New("unknown packet type")
-)
+// ErrUnkownPacketType is returned when a frame is neither of the packet type expected by the parser.
+type ErrUnkownPacketType struct {
+	Type byte
+}
+
+func (e *ErrUnkownPacketType) Error() string {
+	return fmt.Sprintf("unknown packet type 0x%02X", e.Type)
+}
+
+// ErrHeaderCrc is returned when the header CRC of a frame does not match its header.
+type ErrHeaderCrc struct {
+	Expected byte
+	Actual   byte
+}
+
+func (e *ErrHeaderCrc) Error() string {
+	return fmt.Sprintf("bad header crc, calculated 0x%02X but packet has 0x%02X", e.Expected, e.Actual)
+}
+
+// ErrFooterCrc is returned when the footer CRC of a frame does not match its content.
+type ErrFooterCrc struct {
+	Expected byte
+	Actual   byte
+}
+
+func (e *ErrFooterCrc) Error() string {
+	return fmt.Sprintf("bad footer crc, calculated 0x%02X but packet has 0x%02X", e.Expected, e.Actual)
+}
+
+// ErrLengthMismatch is returned when the size of a frame does not
+// match its length field, or is too short to hold a header and footer.
+type ErrLengthMismatch struct {
+	Expected int
+	Actual   int
+}
+
+func (e *ErrLengthMismatch) Error() string {
+	return fmt.Sprintf("malformed packet, expected %d bytes but got %d", e.Expected, e.Actual)
+}
+
+// Index of the header CRC in a packet, the same for both linked and broadcast packets.
+const headerCrcIdx int = framing.HeaderCrcIdx
+
+// verifyPacket checks the length field and both CRCs of a packet
+// without start and end bytes. The packet type is not checked.
+func verifyPacket(data []byte) error {
+	if len(data) < linkedPacketSize(0) {
+		return &ErrLengthMismatch{Expected: linkedPacketSize(0), Actual: len(data)}
+	}
+
+	packetLength := binary.LittleEndian.Uint16(data[1:3])
+	expectedSize := packetTypeSize + lengthSize + int(packetLength) - 1 // - 1 for end-of-packet
+	if len(data) != expectedSize {
+		return &ErrLengthMismatch{Expected: expectedSize, Actual: len(data)}
+	}
+
+	headerCrc := framing.Crc8(data[:headerCrcIdx])
+	if headerCrc != data[headerCrcIdx] {
+		return &ErrHeaderCrc{Expected: headerCrc, Actual: data[headerCrcIdx]}
+	}
+
+	footerCrc := framing.Crc8(data[:len(data)-1])
+	if footerCrc != data[len(data)-1] {
+		return &ErrFooterCrc{Expected: footerCrc, Actual: data[len(data)-1]}
+	}
+
+	return nil
+}
 
 type Payload []byte
 
@@ -133,22 +199,17 @@ func marshallLinkedPacket(lp linkedPacket, buf []byte) error {
 
 // todo: Check heap allocations of this function
 func parseLinkedPacket(data []byte) (packet linkedPacket, err error) {
-	if len(data) < linkedPacketSize(0) {
-		return linkedPacket{}, fmt.Errorf("malformed packet, not enough bytes for header+footer")
+	if len(data) > 0 && data[0] != LinkedPacketType {
+		return packet, &ErrUnkownPacketType{Type: data[0]}
+	}
+	if err := verifyPacket(data); err != nil {
+		return packet, err
 	}
 
 	buff := bytes.NewBuffer(data)
 
 	packetType, _ := buff.ReadByte()
-	if packetType != LinkedPacketType {
-		return packet, fmt.Errorf("invalid packet type, expected LinkedPacket %b but got %b", LinkedPacketType, packetType)
-	}
-
 	packetLength := binary.LittleEndian.Uint16(buff.Next(2))
-	if buff.Len() != int(packetLength)-1 {
-		return packet, fmt.Errorf("malformed packet, length header (%d) does not match size of data (%d - end-of-packet)", buff.Len(), packetLength)
-	}
-
 	linkId := binary.LittleEndian.Uint32(buff.Next(4))
 	control, _ := buff.ReadByte()
 	headerCrc, _ := buff.ReadByte()
@@ -213,22 +274,17 @@ type broadcastPacketFrame struct {
 }
 
 func parseBroadcastPacket(data []byte) (packet broadcastPacketFrame, payload Payload, err error) {
-	if len(data) < linkedPacketSize(0) {
-		return broadcastPacketFrame{}, nil, fmt.Errorf("malformed packet, not enough bytes for header+footer")
+	if len(data) > 0 && data[0] != BroadcastPacketType {
+		return packet, payload, &ErrUnkownPacketType{Type: data[0]}
+	}
+	if err := verifyPacket(data); err != nil {
+		return packet, payload, err
 	}
 
 	buff := bytes.NewBuffer(data)
 
 	packetType, _ := buff.ReadByte()
-	if packetType != BroadcastPacketType {
-		return packet, payload, fmt.Errorf("invalid packet type, expected BroadcastPacket %b but got %b", BroadcastPacketType, packetType)
-	}
-
 	packetLength := binary.LittleEndian.Uint16(buff.Next(2))
-	if buff.Len() != int(packetLength)-1 {
-		return packet, payload, fmt.Errorf("malformed packet, length header (%d) does not match size of data (%d - end-of-packet)", buff.Len(), packetLength)
-	}
-
 	messageFamily := binary.LittleEndian.Uint16(buff.Next(2))
 	senderId, _ := buff.ReadByte()
 	broadcastChannel, _ := buff.ReadByte()
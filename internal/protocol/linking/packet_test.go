@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -54,3 +55,115 @@ func TestMarshallLinkedPacket(t *testing.T) {
 		t.Errorf("marshallLinkedPacket(%+v) returned %v; expected %v", input, buf, expected)
 	}
 }
+
+func TestParseLinkedPacketErrors(t *testing.T) {
+	t.Parallel()
+
+	valid := []byte{0xFD, 0x0D, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x63, 0x12, 0x5F, 0x4D, 0xB6, 0x16, 0x74}
+	withByte := func(idx int, b byte) []byte {
+		data := bytes.Clone(valid)
+		data[idx] = b
+		return data
+	}
+
+	tests := map[string]struct {
+		input    []byte
+		expected error
+	}{
+		"bad header crc": {
+			input:    withByte(8, 0x64),
+			expected: &ErrHeaderCrc{Expected: 0x63, Actual: 0x64},
+		},
+		"bad footer crc": {
+			input:    withByte(14, 0x75),
+			expected: &ErrFooterCrc{Expected: 0x74, Actual: 0x75},
+		},
+		"corrupted payload": {
+			input:    withByte(10, 0x00),
+			expected: &ErrFooterCrc{Expected: framing.Crc8(withByte(10, 0x00)[:14]), Actual: 0x74},
+		},
+		"length mismatch": {
+			input:    valid[:14],
+			expected: &ErrLengthMismatch{Expected: 15, Actual: 14},
+		},
+		"too short": {
+			input:    valid[:5],
+			expected: &ErrLengthMismatch{Expected: 10, Actual: 5},
+		},
+		"broadcast type": {
+			input:    withByte(0, BroadcastPacketType),
+			expected: &ErrUnkownPacketType{Type: BroadcastPacketType},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+			_, err := parseLinkedPacket(test.input)
+			if !cmp.Equal(test.expected, err) {
+				t.Errorf("parseLinkedPacket(%v) returned %v; expected %v", test.input, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseBroadcastPacket(t *testing.T) {
+	t.Parallel()
+	input := rawBroadcast(Broadcast{
+		MessageFamily:    0x1234,
+		SenderId:         0x01,
+		BroadcastChannel: 0x05,
+		Control:          ControlPayloadCommand,
+		Payload:          Payload{0xAA, 0xBB},
+	})
+
+	packet, payload, err := parseBroadcastPacket(input)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	if packet.MessageFamily != 0x1234 || packet.SenderId != 0x01 || packet.BroadcastChannel != 0x05 {
+		t.Errorf("unexpected broadcast header %+v", packet)
+	}
+	if !bytes.Equal(payload, []byte{0xAA, 0xBB}) {
+		t.Errorf("expected payload %v but got %v", []byte{0xAA, 0xBB}, payload)
+	}
+
+	input[headerCrcIdx] ^= 0xFF
+	_, _, err = parseBroadcastPacket(input)
+	if _, ok := err.(*ErrHeaderCrc); !ok {
+		t.Errorf("expected ErrHeaderCrc but got %v", err)
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input    []byte
+		expected string
+	}{
+		"linked": {
+			input:    []byte{0xFD, 0x0A, 0x00, 0x25, 0xAE, 0xEF, 0x06, 0x00, 0x74, 0x08, 0x01, 0x28},
+			expected: "linked link=0x06EFAE25 link-manager [08 01]",
+		},
+		"broadcast": {
+			input:    rawBroadcast(Broadcast{MessageFamily: 0x1234, SenderId: 1, BroadcastChannel: 5, Control: ControlPayloadCommand, Payload: Payload{0xAB}}),
+			expected: "broadcast family=0x1234 sender=1 channel=5 payload [AB]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+			frame, err := ParseFrame(test.input)
+			if err != nil {
+				t.Fatalf("expected no error but got %q", err)
+			}
+			if frame.String() != test.expected {
+				t.Errorf("ParseFrame(%v) = %q; expected %q", test.input, frame.String(), test.expected)
+			}
+		})
+	}
+}
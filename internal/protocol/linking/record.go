@@ -0,0 +1,33 @@
+package linking
+
+import (
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
+)
+
+// Record writes every frame sent or received by the mux to w, until Record is called with nil.
+func (lh *LinkMux) Record(w *capture.Writer) {
+	lh.recorder.Store(w)
+}
+
+func (lh *LinkMux) record(direction capture.Direction, frame []byte) {
+	w := lh.recorder.Load()
+	if w == nil {
+		return
+	}
+
+	// Frames are recorded without start and end bytes, the same way they are received
+	if len(frame) >= 2 && frame[0] == PacketStart && frame[len(frame)-1] == PacketEnd {
+		frame = frame[1 : len(frame)-1]
+	}
+
+	err := w.Write(capture.Record{
+		Time:      time.Now(),
+		Direction: direction,
+		Frame:     frame,
+	})
+	if err != nil {
+		lh.logger.Error("Error recording frame", "err", err)
+	}
+}
@@ -0,0 +1,76 @@
+package linking
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// FrameStats counts the frames received by a LinkMux over its connection.
+type FrameStats struct {
+	// Frames read from the device, including corrupted ones.
+	Received uint64
+
+	// Frames that failed the length or CRC checks.
+	Corrupted       uint64
+	HeaderCrcErrors uint64
+	FooterCrcErrors uint64
+	LengthErrors    uint64
+
+	// Frames that were not delivered to anyone, either because of an
+	// unknown packet type or because no request or subscription matched.
+	Dropped      uint64
+	UnknownTypes uint64
+}
+
+type frameCounters struct {
+	received        atomic.Uint64
+	corrupted       atomic.Uint64
+	headerCrcErrors atomic.Uint64
+	footerCrcErrors atomic.Uint64
+	lengthErrors    atomic.Uint64
+	dropped         atomic.Uint64
+	unknownTypes    atomic.Uint64
+}
+
+// countError records why a frame could not be parsed.
+func (c *frameCounters) countError(err error) {
+	var (
+		headerCrcErr *ErrHeaderCrc
+		footerCrcErr *ErrFooterCrc
+		lengthErr    *ErrLengthMismatch
+		typeErr      *ErrUnkownPacketType
+	)
+	switch {
+	case errors.As(err, &headerCrcErr):
+		c.headerCrcErrors.Add(1)
+		c.corrupted.Add(1)
+	case errors.As(err, &footerCrcErr):
+		c.footerCrcErrors.Add(1)
+		c.corrupted.Add(1)
+	case errors.As(err, &lengthErr):
+		c.lengthErrors.Add(1)
+		c.corrupted.Add(1)
+	case errors.As(err, &typeErr):
+		c.unknownTypes.Add(1)
+		c.dropped.Add(1)
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+func (c *frameCounters) snapshot() FrameStats {
+	return FrameStats{
+		Received:        c.received.Load(),
+		Corrupted:       c.corrupted.Load(),
+		HeaderCrcErrors: c.headerCrcErrors.Load(),
+		FooterCrcErrors: c.footerCrcErrors.Load(),
+		LengthErrors:    c.lengthErrors.Load(),
+		Dropped:         c.dropped.Load(),
+		UnknownTypes:    c.unknownTypes.Load(),
+	}
+}
+
+// Stats returns the frame counters of the mux since it was created.
+func (lh *LinkMux) Stats() FrameStats {
+	return lh.counters.snapshot()
+}
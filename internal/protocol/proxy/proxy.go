@@ -0,0 +1,129 @@
+// Package proxy forwards device traffic between a client, such as TifConsole,
+// and a device, such as WinMower, while decoding every frame that passes through.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
+	"github.com/Tifufu/tools-cli/internal/protocol/framing"
+	"github.com/charmbracelet/log"
+)
+
+// FrameHandler is called for every frame decoded from a proxied connection. Frames sent by
+// the client to the device are capture.Sent, frames from the device are capture.Received.
+// Calls for the same connection and direction are made in order.
+type FrameHandler func(connId uint64, direction capture.Direction, frame []byte)
+
+type Proxy struct {
+	upstream string
+	logger   *log.Logger
+	onFrame  FrameHandler
+	connIds  atomic.Uint64
+}
+
+func NewProxy(upstream string, onFrame FrameHandler, logger *log.Logger) *Proxy {
+	return &Proxy{
+		upstream: upstream,
+		logger:   logger,
+		onFrame:  onFrame,
+	}
+}
+
+// Serve accepts client connections on listener and proxies each of them
+// to a new connection to the upstream device, until ctx is done.
+func (p *Proxy) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		client, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.handle(ctx, client)
+		}()
+	}
+}
+
+func (p *Proxy) handle(ctx context.Context, client net.Conn) {
+	connId := p.connIds.Add(1)
+	logger := p.logger.With("conn", connId)
+	defer client.Close()
+
+	var dialer net.Dialer
+	upstream, err := dialer.DialContext(ctx, "tcp", p.upstream)
+	if err != nil {
+		logger.Error("Error connecting to upstream", "upstream", p.upstream, "err", err)
+		return
+	}
+	defer upstream.Close()
+	logger.Info("Proxying connection", "client", client.RemoteAddr(), "upstream", p.upstream)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// Closing either side ends both copies
+		<-ctx.Done()
+		client.Close()
+		upstream.Close()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		p.pipe(connId, capture.Sent, upstream, client, logger)
+	}()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		p.pipe(connId, capture.Received, client, upstream, logger)
+	}()
+	wg.Wait()
+	logger.Info("Connection closed")
+}
+
+// pipe copies src to dst unmodified, decoding the copied bytes into frames on the side.
+func (p *Proxy) pipe(connId uint64, direction capture.Direction, dst io.Writer, src io.Reader, logger *log.Logger) {
+	pr, pw := io.Pipe()
+	decoded := make(chan struct{})
+	go func() {
+		defer close(decoded)
+		decoder := framing.NewDecoder(pr)
+		for {
+			frame, err := decoder.ReadFrame()
+			if err != nil {
+				// Keep draining so the copy never blocks on a stopped decoder
+				io.Copy(io.Discard, pr)
+				return
+			}
+			p.onFrame(connId, direction, frame)
+		}
+	}()
+
+	_, err := io.Copy(dst, io.TeeReader(src, pw))
+	pw.Close()
+	<-decoded
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Debug("Copy ended", "direction", direction, "err", err)
+	}
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/capture"
+	"github.com/charmbracelet/log"
+)
+
+var (
+	capturedDiscover = []byte{0x02, 0xFD, 0x0D, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x63, 0x12, 0x9F, 0x57, 0xF7, 0x70, 0x8A, 0x03}
+	capturedSetProto = []byte{0x02, 0xFD, 0x0A, 0x00, 0x25, 0xAE, 0xEF, 0x06, 0x00, 0x74, 0x08, 0x01, 0x28, 0x03}
+)
+
+type observedFrame struct {
+	direction capture.Direction
+	frame     []byte
+}
+
+func TestProxyForwardsAndDecodes(t *testing.T) {
+	t.Parallel()
+
+	// Upstream answers every request with the set protocol frame
+	upstreamListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer upstreamListener.Close()
+	go func() {
+		conn, err := upstreamListener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, len(capturedDiscover))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		conn.Write(capturedSetProto)
+	}()
+
+	var mu sync.Mutex
+	var observed []observedFrame
+	onFrame := func(_ uint64, direction capture.Direction, frame []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		observed = append(observed, observedFrame{direction: direction, frame: frame})
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	proxy := NewProxy(upstreamListener.Addr().String(), onFrame, log.New(io.Discard))
+	served := make(chan error)
+	go func() {
+		served <- proxy.Serve(ctx, listener)
+	}()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to proxy: %v", err)
+	}
+	client.Write(capturedDiscover)
+	response := make([]byte, len(capturedSetProto))
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(client, response); err != nil {
+		t.Fatalf("failed to read response through proxy: %v", err)
+	}
+	if !bytes.Equal(response, capturedSetProto) {
+		t.Errorf("proxy modified response: expected %X but got %X", capturedSetProto, response)
+	}
+	client.Close()
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("expected no error from serve but got %v", err)
+	}
+
+	expected := []observedFrame{
+		{direction: capture.Sent, frame: capturedDiscover[1 : len(capturedDiscover)-1]},
+		{direction: capture.Received, frame: capturedSetProto[1 : len(capturedSetProto)-1]},
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(observed) != len(expected) {
+		t.Fatalf("expected %d frames but observed %d", len(expected), len(observed))
+	}
+	// Directions are decoded independently, only the order within a direction is guaranteed
+	slices.SortFunc(observed, func(a, b observedFrame) int {
+		return cmp.Compare(b.direction, a.direction)
+	})
+	for i := range expected {
+		if observed[i].direction != expected[i].direction || !bytes.Equal(observed[i].frame, expected[i].frame) {
+			t.Errorf("frame %d: expected %v %X but got %v %X", i, expected[i].direction, expected[i].frame, observed[i].direction, observed[i].frame)
+		}
+	}
+}
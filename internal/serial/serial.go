@@ -0,0 +1,30 @@
+// Package serial opens serial ports, such as USB-serial adapters, as byte streams.
+package serial
+
+import (
+	"fmt"
+	"io"
+)
+
+const DefaultBaudRate = 115200
+
+// ErrUnsupportedBaudRate is returned when the platform can not configure the requested baud rate.
+type ErrUnsupportedBaudRate struct {
+	BaudRate int
+}
+
+func (e *ErrUnsupportedBaudRate) Error() string {
+	return fmt.Sprintf("unsupported baud rate %d", e.BaudRate)
+}
+
+// Open opens the serial port name, for example /dev/ttyUSB0 or COM3, in raw mode
+// with 8 data bits, no parity and one stop bit. Reads block until at least one byte is available.
+// Closing the port unblocks pending reads.
+func Open(name string, baudRate int) (*Port, error) {
+	if baudRate <= 0 {
+		return nil, &ErrUnsupportedBaudRate{BaudRate: baudRate}
+	}
+	return open(name, baudRate)
+}
+
+var _ io.ReadWriteCloser = (*Port)(nil)
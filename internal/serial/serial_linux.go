@@ -0,0 +1,68 @@
+package serial
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	1200:    unix.B1200,
+	2400:    unix.B2400,
+	4800:    unix.B4800,
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+}
+
+type Port struct {
+	*os.File
+}
+
+func open(name string, baudRate int) (*Port, error) {
+	speed, ok := baudRates[baudRate]
+	if !ok {
+		return nil, &ErrUnsupportedBaudRate{BaudRate: baudRate}
+	}
+
+	// Non-blocking so that reads go through the runtime poller and can be interrupted by Close
+	fd, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port %s: %w", name, err)
+	}
+
+	err = configure(fd, speed)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to configure serial port %s: %w", name, err)
+	}
+
+	return &Port{File: os.NewFile(uintptr(fd), name)}, nil
+}
+
+// configure puts the terminal in raw 8N1 mode at the given speed, the equivalent of cfmakeraw.
+func configure(fd int, speed uint32) error {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	termios.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	termios.Ispeed = speed
+	termios.Ospeed = speed
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+}
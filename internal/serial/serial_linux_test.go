@@ -0,0 +1,115 @@
+package serial
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty opens a pseudo-terminal pair, returning the master side and the path of the slave side.
+func openPty(t *testing.T) (*os.File, string) {
+	t.Helper()
+
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals not available: %v", err)
+	}
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(fd)
+		t.Fatalf("failed to unlock pty: %v", err)
+	}
+	ptyNum, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(fd)
+		t.Fatalf("failed to get pty number: %v", err)
+	}
+
+	master := os.NewFile(uintptr(fd), "/dev/ptmx")
+	t.Cleanup(func() { master.Close() })
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNum)
+}
+
+func TestPortReadWrite(t *testing.T) {
+	t.Parallel()
+	master, slavePath := openPty(t)
+
+	port, err := Open(slavePath, 115200)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+	defer port.Close()
+
+	// Raw mode must pass every byte through untouched, including line endings and control characters
+	data := []byte{0x02, 0xFD, 0x0A, 0x0D, 0x03, 0x11, 0x13, 0x7F, 0x00, 0xFF}
+
+	_, err = master.Write(data)
+	if err != nil {
+		t.Fatalf("failed to write to master: %v", err)
+	}
+	received := make([]byte, len(data))
+	port.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(port, received)
+	if err != nil {
+		t.Fatalf("failed to read from port: %v", err)
+	}
+	if !bytes.Equal(received, data) {
+		t.Errorf("port read %X; expected %X", received, data)
+	}
+
+	_, err = port.Write(data)
+	if err != nil {
+		t.Fatalf("failed to write to port: %v", err)
+	}
+	master.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(master, received)
+	if err != nil {
+		t.Fatalf("failed to read from master: %v", err)
+	}
+	if !bytes.Equal(received, data) {
+		t.Errorf("master read %X; expected %X", received, data)
+	}
+}
+
+func TestPortCloseUnblocksRead(t *testing.T) {
+	t.Parallel()
+	_, slavePath := openPty(t)
+
+	port, err := Open(slavePath, 9600)
+	if err != nil {
+		t.Fatalf("expected no error but got %q", err)
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := port.Read(make([]byte, 1))
+		readErr <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	port.Close()
+
+	select {
+	case err := <-readErr:
+		if !errors.Is(err, os.ErrClosed) {
+			t.Errorf("expected %v but got %v", os.ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read was not unblocked by close")
+	}
+}
+
+func TestOpenUnsupportedBaudRate(t *testing.T) {
+	t.Parallel()
+
+	_, err := Open("/dev/null", 12345)
+	var baudErr *ErrUnsupportedBaudRate
+	if !errors.As(err, &baudErr) {
+		t.Errorf("expected ErrUnsupportedBaudRate but got %v", err)
+	}
+}
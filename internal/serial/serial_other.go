@@ -0,0 +1,17 @@
+//go:build !linux && !windows
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+type Port struct {
+	*os.File
+}
+
+func open(name string, _ int) (*Port, error) {
+	return nil, fmt.Errorf("failed to open serial port %s: serial ports are not supported on %s", name, runtime.GOOS)
+}
@@ -0,0 +1,92 @@
+package serial
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	dcbBinary = 0x00000001 // fBinary, must always be set on windows
+
+	noParity   = 0
+	oneStopBit = 0
+
+	maxDword = 0xFFFFFFFF
+)
+
+type Port struct {
+	*os.File
+	handle windows.Handle
+}
+
+func open(name string, baudRate int) (*Port, error) {
+	path := name
+	if !strings.HasPrefix(path, `\\.\`) {
+		// Required for COM10 and above, harmless for the rest
+		path = `\\.\` + path
+	}
+
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := windows.CreateFile(pathPtr,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		0,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_ATTRIBUTE_NORMAL,
+		0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port %s: %w", name, err)
+	}
+
+	err = configure(handle, uint32(baudRate))
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, fmt.Errorf("failed to configure serial port %s: %w", name, err)
+	}
+
+	return &Port{
+		File:   os.NewFile(uintptr(handle), name),
+		handle: handle,
+	}, nil
+}
+
+func configure(handle windows.Handle, baudRate uint32) error {
+	var dcb windows.DCB
+	dcb.DCBlength = uint32(unsafe.Sizeof(dcb))
+	err := windows.GetCommState(handle, &dcb)
+	if err != nil {
+		return err
+	}
+
+	dcb.BaudRate = baudRate
+	dcb.Flags = dcbBinary
+	dcb.ByteSize = 8
+	dcb.Parity = noParity
+	dcb.StopBits = oneStopBit
+	err = windows.SetCommState(handle, &dcb)
+	if err != nil {
+		return err
+	}
+
+	// Block until at least one byte is available, then return what has arrived
+	timeouts := windows.CommTimeouts{
+		ReadIntervalTimeout:        maxDword,
+		ReadTotalTimeoutMultiplier: maxDword,
+		ReadTotalTimeoutConstant:   maxDword - 1,
+	}
+	return windows.SetCommTimeouts(handle, &timeouts)
+}
+
+// Close cancels pending reads and writes before closing the port.
+func (p *Port) Close() error {
+	_ = windows.CancelIoEx(p.handle, nil)
+	return p.File.Close()
+}
@@ -0,0 +1,78 @@
+package tif
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// An argument is a name and a value, which is either a double quoted string, as in Go,
+// or a run of letters, digits and the characters _.:+- covering numbers, versions and dates.
+const argumentPattern = `\s*([A-Za-z0-9_]+)\s*:\s*("(?:[^"\\]|\\.)*"|[A-Za-z0-9_.:+\-]+)\s*,?\s*`
+
+var (
+	// Dear lord, help the person who has to debug this in the future, Amen.
+	callPattern    = regexp.MustCompile(`^([A-Za-z0-9]+\.[A-Za-z0-9]+)\(((?:` + argumentPattern + `)*)\)$`)
+	argumentRegexp = regexp.MustCompile(argumentPattern)
+)
+
+// CallArgument is an argument of a call as written by the user.
+type CallArgument struct {
+	Name  string
+	Value string
+}
+
+// ParseCall splits a call written as Family.Command(param: value, ...)
+// into the name of the method and its arguments. Values containing spaces or
+// commas are written as double quoted strings, which are unquoted.
+func ParseCall(call string) (method string, args []CallArgument, err error) {
+	match := callPattern.FindStringSubmatch(strings.TrimSpace(call))
+	if match == nil {
+		return "", nil, fmt.Errorf("malformed call; expected format Family.Command(param: value), got: %s", call)
+	}
+
+	method = match[1]
+	args = []CallArgument{}
+	for _, argument := range argumentRegexp.FindAllStringSubmatch(match[2], -1) {
+		name, value := argument[1], argument[2]
+		if strings.HasPrefix(value, `"`) {
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("malformed value for parameter %s: %s", name, argument[2])
+			}
+		}
+		args = append(args, CallArgument{Name: name, Value: value})
+	}
+	return method, args, nil
+}
+
+// ParseArguments parses args for the parameters of method. The returned values are in
+// the order of the parameters, whatever order the arguments were given in.
+func (e *Encoder) ParseArguments(method MethodDefinition, args []CallArgument) ([]any, error) {
+	if len(args) != len(method.InParams) {
+		return nil, fmt.Errorf("mismatch on number of arguments provided and parameters in the command; expected %d but got %d", len(method.InParams), len(args))
+	}
+
+	values := make([]any, len(method.InParams))
+	for _, arg := range args {
+		inParamIdx := slices.IndexFunc(method.InParams, func(inParam InputParameter) bool {
+			return strings.EqualFold(inParam.Name, arg.Name)
+		})
+		if inParamIdx < 0 {
+			return nil, fmt.Errorf("method %s has no parameter named %s", method.Name(), arg.Name)
+		}
+		if values[inParamIdx] != nil {
+			return nil, fmt.Errorf("parameter %s provided more than once", arg.Name)
+		}
+
+		param := method.InParams[inParamIdx]
+		value, err := e.ParseArgument(param, arg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument for %s: %w", arg.Name, err)
+		}
+		values[inParamIdx] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,116 @@
+package tif
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCall(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		method string
+		args   []CallArgument
+	}{
+		"Battery.GetCharge()": {
+			method: "Battery.GetCharge",
+			args:   []CallArgument{},
+		},
+		" Mower.SetSettings(height: 50, offset:-2,name : abc) ": {
+			method: "Mower.SetSettings",
+			args: []CallArgument{
+				{Name: "height", Value: "50"},
+				{Name: "offset", Value: "-2"},
+				{Name: "name", Value: "abc"},
+			},
+		},
+		"LinkManager.Discover(tracebackId: 0x70F7579F,)": {
+			method: "LinkManager.Discover",
+			args:   []CallArgument{{Name: "tracebackId", Value: "0x70F7579F"}},
+		},
+		"Blade.SetSpeed(factor: 1.5, offset: -0.25)": {
+			method: "Blade.SetSpeed",
+			args: []CallArgument{
+				{Name: "factor", Value: "1.5"},
+				{Name: "offset", Value: "-0.25"},
+			},
+		},
+		"Software.Require(version: 1.2)": {
+			method: "Software.Require",
+			args:   []CallArgument{{Name: "version", Value: "1.2"}},
+		},
+		"Clock.SetTime(time: 2024-01-02T03:04:05)": {
+			method: "Clock.SetTime",
+			args:   []CallArgument{{Name: "time", Value: "2024-01-02T03:04:05"}},
+		},
+		`Mower.SetName(name: "Front lawn, north", note: "say \"hi\"")`: {
+			method: "Mower.SetName",
+			args: []CallArgument{
+				{Name: "name", Value: "Front lawn, north"},
+				{Name: "note", Value: `say "hi"`},
+			},
+		},
+	}
+
+	for call, test := range tests {
+		t.Run(call, func(t *testing.T) {
+			call, test := call, test
+			t.Parallel()
+
+			method, args, err := ParseCall(call)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if method != test.method {
+				t.Errorf("expected method %s but got %s", test.method, method)
+			}
+			if !cmp.Equal(test.args, args) {
+				t.Errorf("expected arguments %v but got %v", test.args, args)
+			}
+		})
+	}
+
+	for _, call := range []string{"GetCharge()", "Battery.GetCharge", "Battery.GetCharge(cell)", "Battery.GetCharge(cell: )", `Mower.SetName(name: "Front lawn)`, "Mower.SetName(name: Front lawn)"} {
+		_, _, err := ParseCall(call)
+		if err == nil {
+			t.Errorf("ParseCall(%s) returned no error; expected malformed call error", call)
+		}
+	}
+}
+
+func TestParseArguments(t *testing.T) {
+	t.Parallel()
+
+	encoder := NewEncoder(encodeTestDefinition)
+	method := MethodDefinition{
+		Family:  "Mower",
+		Command: "SetHeight",
+		InParams: []InputParameter{
+			{Name: "height", Type: "tCuttingHeight"},
+			{Name: "enabled", Type: "bool"},
+		},
+	}
+
+	values, err := encoder.ParseArguments(method, []CallArgument{{Name: "Enabled", Value: "true"}, {Name: "height", Value: "0x20"}})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := []any{TypeUint8(0x20), TypeBool(true)}
+	if !cmp.Equal(expected, values) {
+		t.Errorf("expected values %v in parameter order but got %v", expected, values)
+	}
+
+	invalid := map[string][]CallArgument{
+		"too few arguments":  {{Name: "height", Value: "1"}},
+		"unknown parameter":  {{Name: "height", Value: "1"}, {Name: "speed", Value: "1"}},
+		"repeated parameter": {{Name: "height", Value: "1"}, {Name: "height", Value: "2"}},
+		"out of range":       {{Name: "height", Value: "256"}, {Name: "enabled", Value: "true"}},
+	}
+	for name, args := range invalid {
+		_, err := encoder.ParseArguments(method, args)
+		if err == nil {
+			t.Errorf("%s: expected an error but got none", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package tif
+
+import (
+	"slices"
+	"strings"
+)
+
+// Completion is the result of completing the text before the cursor.
+type Completion struct {
+	// Index in the text where the token replaced by the candidates starts
+	Start      int
+	Candidates []string
+	// Signature of the method being called, if the text is inside its arguments
+	Hint string
+}
+
+// Completer completes calls written as Family.Command(param: value, ...) from an indexed definition.
+type Completer struct {
+	idx      *Index
+	types    *TypeRegistry
+	families []string
+}
+
+func NewCompleter(idx *Index) *Completer {
+	def := idx.Definition()
+	var families []string
+	for _, method := range def.Methods {
+		if !slices.Contains(families, method.Family) {
+			families = append(families, method.Family)
+		}
+	}
+	slices.Sort(families)
+
+	return &Completer{
+		idx:      idx,
+		types:    NewTypeRegistry(def),
+		families: families,
+	}
+}
+
+// Complete returns the candidates for the token that ends at the end of text:
+// a family, a command, a parameter name or an enumerator key.
+func (c *Completer) Complete(text string) Completion {
+	open := strings.Index(text, "(")
+	if open < 0 {
+		return c.completeMethod(text)
+	}
+
+	method, ok := c.idx.Method(strings.TrimSpace(text[:open]))
+	if !ok {
+		return Completion{Start: len(text)}
+	}
+	completion := Completion{Hint: method.Signature()}
+
+	argsStart := open + 1 + strings.LastIndex(text[open+1:], ",") + 1
+	arg := text[argsStart:]
+	name, value, hasValue := strings.Cut(arg, ":")
+	if !hasValue {
+		completion.Start = len(text) - len(strings.TrimLeft(arg, " "))
+		completion.Candidates = c.paramNames(method, text[open+1:argsStart], strings.TrimLeft(arg, " "))
+		return completion
+	}
+
+	token := strings.TrimLeft(value, " ")
+	completion.Start = len(text) - len(token)
+	idx := slices.IndexFunc(method.InParams, func(param InputParameter) bool {
+		return strings.EqualFold(param.Name, strings.TrimSpace(name))
+	})
+	if idx >= 0 {
+		completion.Candidates = c.values(method.InParams[idx], token)
+	}
+	return completion
+}
+
+func (c *Completer) completeMethod(text string) Completion {
+	token := strings.TrimLeft(text, " ")
+	completion := Completion{Start: len(text) - len(token)}
+
+	family, command, hasCommand := strings.Cut(token, ".")
+	if !hasCommand {
+		for _, f := range c.families {
+			if hasPrefixFold(f, family) {
+				completion.Candidates = append(completion.Candidates, f+".")
+			}
+		}
+		return completion
+	}
+
+	for _, method := range c.idx.Definition().Methods {
+		if strings.EqualFold(method.Family, family) && hasPrefixFold(method.Command, command) {
+			completion.Candidates = append(completion.Candidates, method.Name()+"(")
+		}
+	}
+	slices.Sort(completion.Candidates)
+	return completion
+}
+
+// paramNames returns the parameters of method starting with prefix that are not in the given arguments.
+func (c *Completer) paramNames(method MethodDefinition, given string, prefix string) []string {
+	var candidates []string
+	for _, param := range method.InParams {
+		if !hasPrefixFold(param.Name, prefix) || isArgumentGiven(given, param.Name) {
+			continue
+		}
+		candidates = append(candidates, param.Name+": ")
+	}
+	return candidates
+}
+
+func isArgumentGiven(given string, name string) bool {
+	for _, arg := range strings.Split(given, ",") {
+		argName, _, _ := strings.Cut(arg, ":")
+		if strings.EqualFold(strings.TrimSpace(argName), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the enumerator keys, or booleans, for param that start with prefix.
+func (c *Completer) values(param InputParameter, prefix string) []string {
+	var values []string
+	if enum, ok := c.types.Enum(param.Type); ok {
+		for _, enumerator := range enum.Enumerators {
+			values = append(values, enumerator.Key)
+		}
+	}
+	if primitive, err := c.types.Primitive(param.Type); err == nil && primitive == "bool" {
+		values = []string{"false", "true"}
+	}
+
+	var candidates []string
+	for _, value := range values {
+		if hasPrefixFold(value, prefix) {
+			candidates = append(candidates, value)
+		}
+	}
+	return candidates
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,114 @@
+package tif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const completeTestDefinitionJson = `{
+  "methods": [
+    {
+      "family": "Mower",
+      "command": "SetMode",
+      "inParams": [{ "name": "mode", "type": "tMode" }, { "name": "override", "type": "bool" }],
+      "outParams": [],
+      "protocol": [{ "key": "msgType", "value": "1" }, { "key": "subCmd", "value": "1" }]
+    },
+    {
+      "family": "Mower",
+      "command": "SetHeight",
+      "inParams": [{ "name": "height", "type": "uint8" }],
+      "outParams": [],
+      "protocol": [{ "key": "msgType", "value": "1" }, { "key": "subCmd", "value": "2" }]
+    },
+    {
+      "family": "Map",
+      "command": "GetZones",
+      "inParams": [],
+      "outParams": [{ "name": "zones", "type": "uint8" }],
+      "protocol": [{ "key": "msgType", "value": "2" }, { "key": "subCmd", "value": "1" }]
+    }
+  ],
+  "types-v2": [
+    {
+      "name": "tMode",
+      "type": "uint8",
+      "range": {
+        "type": "enum",
+        "enum": [
+          { "key": "Auto", "value": 0 },
+          { "key": "Manual", "value": 1 },
+          { "key": "Home", "value": 2 }
+        ]
+      }
+    }
+  ]
+}`
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	var def TifDefinition
+	err := json.Unmarshal([]byte(completeTestDefinitionJson), &def)
+	if err != nil {
+		t.Fatalf("failed to parse definition: %v", err)
+	}
+	completer := NewCompleter(NewIndex(&def))
+	setModeHint := "Mower.SetMode(mode: tMode, override: bool)"
+
+	tests := map[string]Completion{
+		"":                {Start: 0, Candidates: []string{"Map.", "Mower."}},
+		"m":               {Start: 0, Candidates: []string{"Map.", "Mower."}},
+		"mo":              {Start: 0, Candidates: []string{"Mower."}},
+		"  Mower.Set":     {Start: 2, Candidates: []string{"Mower.SetHeight(", "Mower.SetMode("}},
+		"Mower.SetM":      {Start: 0, Candidates: []string{"Mower.SetMode("}},
+		"Map.GetZones(":   {Start: 13, Hint: "Map.GetZones() -> (zones: uint8)"},
+		"Mower.SetMode(":  {Start: 14, Candidates: []string{"mode: ", "override: "}, Hint: setModeHint},
+		"Mower.SetMode(o": {Start: 14, Candidates: []string{"override: "}, Hint: setModeHint},
+		"Mower.SetMode(mode: Auto, ": {
+			Start: 26, Candidates: []string{"override: "}, Hint: setModeHint,
+		},
+		"Mower.SetMode(mode: ": {
+			Start: 20, Candidates: []string{"Auto", "Manual", "Home"}, Hint: setModeHint,
+		},
+		"Mower.SetMode(mode:ma": {
+			Start: 19, Candidates: []string{"Manual"}, Hint: setModeHint,
+		},
+		"Mower.SetMode(mode: Auto, override: t": {
+			Start: 36, Candidates: []string{"true"}, Hint: setModeHint,
+		},
+		"Mower.Unknown(": {Start: 14},
+	}
+
+	for text, expected := range tests {
+		t.Run(text, func(t *testing.T) {
+			text, expected := text, expected
+			t.Parallel()
+
+			completion := completer.Complete(text)
+			if diff := cmp.Diff(expected, completion); diff != "" {
+				t.Errorf("Complete(%q) mismatch (-expected +got):\n%s", text, diff)
+			}
+		})
+	}
+}
+
+func TestParseArgumentEnumKey(t *testing.T) {
+	t.Parallel()
+
+	var def TifDefinition
+	err := json.Unmarshal([]byte(completeTestDefinitionJson), &def)
+	if err != nil {
+		t.Fatalf("failed to parse definition: %v", err)
+	}
+
+	value, err := NewEncoder(&def).ParseArgument(InputParameter{Name: "mode", Type: "tMode"}, "manual")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if value != TypeUint8(1) {
+		t.Errorf("ParseArgument(manual) returned %v; expected %v", value, TypeUint8(1))
+	}
+}
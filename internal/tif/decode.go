@@ -0,0 +1,281 @@
+package tif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Status of a method response that succeeded.
+const StatusOk byte = 0x00
+
+type ErrResponseTooShort struct {
+	Method    string
+	Param     string
+	Needed    int
+	Remaining int
+}
+
+func (e *ErrResponseTooShort) Error() string {
+	return fmt.Sprintf("response to %s is too short for %s; needed %d bytes but %d remain", e.Method, e.Param, e.Needed, e.Remaining)
+}
+
+type ErrResponseTooLong struct {
+	Method string
+	Extra  int
+}
+
+func (e *ErrResponseTooLong) Error() string {
+	return fmt.Sprintf("response to %s has %d bytes after its last out parameter", e.Method, e.Extra)
+}
+
+// OutValue is a decoded out parameter.
+type OutValue struct {
+	Name  string
+	Type  string
+	Value any
+	// Key of the enumerator matching Value, if Type is an enum
+	Enum string
+	// Unit of Value, if Type is a range
+	Postfix string
+}
+
+func (v OutValue) String() string {
+	if v.Enum != "" {
+		return v.Enum
+	}
+
+	value := FormatType(v.Value)
+	if v.Postfix != "" {
+		return value + " " + v.Postfix
+	}
+	return value
+}
+
+// Response is a decoded method response.
+type Response struct {
+	// Status of a payload method, always StatusOk for linked methods
+	Status byte
+	Values []OutValue
+}
+
+// Decoder decodes method responses from a definition into their out parameters.
+type Decoder struct {
+	types *TypeRegistry
+}
+
+func NewDecoder(def *TifDefinition) *Decoder {
+	return &Decoder{types: NewTypeRegistry(def)}
+}
+
+// DecodeResponse decodes the response payload to a call of method.
+//
+// Responses to payload methods start with the method header and a status byte. Out parameters
+// are only decoded if the status is StatusOk or the response has bytes after the status.
+// Responses to linked methods start with the response id, which the definition may or
+// may not list as the first out parameter.
+func (d *Decoder) DecodeResponse(method MethodDefinition, payload []byte) (Response, error) {
+	if method.IsLinked() {
+		return d.decodeLinkedResponse(method, payload)
+	}
+
+	id, err := method.MethodId()
+	if err != nil {
+		return Response{}, err
+	}
+	responseId, params, err := ParseMethodHeader(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("malformed response to %s: %w", method.Name(), err)
+	}
+	if responseId != id {
+		return Response{}, fmt.Errorf("response to %s is for method %s, expected %s", method.Name(), responseId, id)
+	}
+	if len(params) == 0 {
+		return Response{}, &ErrResponseTooShort{Method: method.Name(), Param: "status", Needed: 1}
+	}
+
+	response := Response{Status: params[0]}
+	if response.Status != StatusOk && len(params) == 1 {
+		return response, nil
+	}
+	response.Values, err = d.DecodeParams(method.Name(), method.OutParams, params[1:])
+	return response, err
+}
+
+func (d *Decoder) decodeLinkedResponse(method MethodDefinition, payload []byte) (Response, error) {
+	_, responseId, err := method.RequestId()
+	if err != nil {
+		return Response{}, err
+	}
+	if len(payload) == 0 {
+		return Response{}, &ErrResponseTooShort{Method: method.Name(), Param: "responseId", Needed: 1}
+	}
+	if payload[0] != responseId {
+		return Response{}, fmt.Errorf("response to %s has response id %d, expected %d", method.Name(), payload[0], responseId)
+	}
+
+	values, err := d.DecodeParams(method.Name(), method.ResponseParams(), payload[1:])
+	return Response{Status: StatusOk, Values: values}, err
+}
+
+// ResponseParams returns the out parameters decoded from responses to the method, in order.
+// These are the OutParams, except the response id of linked methods that list it.
+func (m MethodDefinition) ResponseParams() []OutputParameter {
+	if m.IsLinked() && len(m.OutParams) > 0 && isResponseIdParam(m.OutParams[0]) {
+		return m.OutParams[1:]
+	}
+	return m.OutParams
+}
+
+func isResponseIdParam(param OutputParameter) bool {
+	return param.Type == "uint8" && (strings.EqualFold(param.Name, "responseId") || strings.EqualFold(param.Name, "messageId"))
+}
+
+// DecodeParams decodes data into one value for each of params, in little endian.
+// All of data must be used by the parameters.
+func (d *Decoder) DecodeParams(method string, params []OutputParameter, data []byte) ([]OutValue, error) {
+	values := make([]OutValue, 0, len(params))
+	for i, param := range params {
+		primitive, err := d.types.Primitive(param.Type)
+		if err != nil {
+			return nil, err
+		}
+		length, hasLength, err := parseLength(param.Name, param.Length)
+		if err != nil {
+			return nil, err
+		}
+		if takesRemaining(primitive, length, hasLength) && i != len(params)-1 {
+			return nil, fmt.Errorf("parameter %s takes up the rest of the payload but is not the last parameter", param.Name)
+		}
+
+		value, n, err := readValue(data, primitive, length, hasLength)
+		if err != nil {
+			if tooShort, ok := err.(*ErrResponseTooShort); ok {
+				tooShort.Method = method
+				tooShort.Param = param.Name
+			}
+			return nil, err
+		}
+		data = data[n:]
+
+		values = append(values, d.describe(OutValue{Name: param.Name, Type: param.Type, Value: value}))
+	}
+
+	if len(data) > 0 {
+		return values, &ErrResponseTooLong{Method: method, Extra: len(data)}
+	}
+	return values, nil
+}
+
+// describe sets the enumerator key or unit of a value from its types-v2 type.
+func (d *Decoder) describe(value OutValue) OutValue {
+	value.Postfix = d.types.Postfix(value.Type)
+	if enum, ok := d.types.Enum(value.Type); ok {
+		value.Enum, _ = enum.Key(value.Value)
+	}
+	return value
+}
+
+func integerOf(value any) (int64, bool) {
+	switch v := value.(type) {
+	case TypeUint8:
+		return int64(v), true
+	case TypeUint16:
+		return int64(v), true
+	case TypeUint32:
+		return int64(v), true
+	case TypeUint64:
+		return int64(v), true
+	case TypeInt8:
+		return int64(v), true
+	case TypeInt16:
+		return int64(v), true
+	case TypeInt32:
+		return int64(v), true
+	case TypeInt64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// readValue reads a value of primitive from the start of data, returning the number of bytes read.
+// A missing or short value is returned as an ErrResponseTooShort without method and parameter.
+func readValue(data []byte, primitive string, length int, hasLength bool) (any, int, error) {
+	if hasLength && length > 0 && !isArrayPrimitive(primitive) {
+		return nil, 0, fmt.Errorf("arrays of %s are not supported", primitive)
+	}
+
+	if p := primitives[primitive]; p.read != nil {
+		if len(data) < p.width {
+			return nil, 0, &ErrResponseTooShort{Needed: p.width, Remaining: len(data)}
+		}
+		return p.read(data), p.width, nil
+	}
+
+	switch primitive {
+	case "ascii":
+		encoded, n, err := readString(data, 1, length, hasLength)
+		return TypeAscii(encoded), n, err
+	case "tUCS2":
+		encoded, n, err := readString(data, 2, length, hasLength)
+		units := make([]uint16, len(encoded)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(encoded[2*i:])
+		}
+		return TypeUCS2(utf16.Decode(units)), n, err
+	case "byteArray":
+		if !hasLength || length <= 0 {
+			return TypeByteArray(bytes.Clone(data)), len(data), nil
+		}
+		if len(data) < length {
+			return nil, 0, &ErrResponseTooShort{Needed: length, Remaining: len(data)}
+		}
+		return TypeByteArray(bytes.Clone(data[:length])), length, nil
+	default:
+		return nil, 0, &ErrUnkownType{Type: primitive}
+	}
+}
+
+// readString reads a string of characters that are charSize bytes wide, returning it
+// without terminator or padding. See appendString for how the length is used.
+func readString(data []byte, charSize int, length int, hasLength bool) ([]byte, int, error) {
+	if hasLength && length == LengthRemaining {
+		// A partial character is left unread, so that the payload is reported as too long
+		n := len(data) - len(data)%charSize
+		return data[:n], n, nil
+	}
+
+	if !hasLength || length == LengthNullTerminated {
+		for i := 0; i+charSize <= len(data); i += charSize {
+			if isNullChar(data[i : i+charSize]) {
+				return data[:i], i + charSize, nil
+			}
+		}
+		return nil, 0, &ErrResponseTooShort{Needed: len(data) + charSize, Remaining: len(data)}
+	}
+
+	size := length * charSize
+	if len(data) < size {
+		return nil, 0, &ErrResponseTooShort{Needed: size, Remaining: len(data)}
+	}
+	str := data[:size]
+	for i := 0; i+charSize <= len(str); i += charSize {
+		if isNullChar(str[i : i+charSize]) {
+			str = str[:i]
+			break
+		}
+	}
+	return str, size, nil
+}
+
+func isNullChar(char []byte) bool {
+	for _, b := range char {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
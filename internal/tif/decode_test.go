@@ -0,0 +1,282 @@
+package tif
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func decodeTestDefinition() *TifDefinition {
+	def := &TifDefinition{
+		TypesV2: []TypeV2Definition{
+			{Name: "tPercent", Type: "uint8", Postfix: "%"},
+			{Name: "tChargeState", Type: "uint8"},
+		},
+		Methods: []MethodDefinition{
+			{
+				Family:  "Battery",
+				Command: "GetStatus",
+				OutParams: []OutputParameter{
+					{Name: "charge", Type: "tPercent"},
+					{Name: "state", Type: "tChargeState"},
+					{Name: "voltage", Type: "sint16"},
+					{Name: "serial", Type: "ascii", Length: "4"},
+					{Name: "owner", Type: "tUCS2"},
+					{Name: "raw", Type: "byteArray"},
+				},
+				Protocol: []ProtocolEntry{{Key: "msgType", Value: "0x1234"}, {Key: "subCmd", Value: "1"}},
+			},
+		},
+	}
+	def.TypesV2[1].Range.Type = "enum"
+	def.TypesV2[1].Range.Enums = []EnumDefinition{
+		{Key: "Idle", Value: 0},
+		{Key: "Charging", Value: 1},
+	}
+	return def
+}
+
+func TestDecodeResponse(t *testing.T) {
+	t.Parallel()
+
+	linkManager := loadTestDefinition(t, "testdata/linkmanager-def.json")
+	battery := decodeTestDefinition()
+
+	tests := map[string]struct {
+		def      *TifDefinition
+		method   string
+		payload  []byte
+		expected Response
+	}{
+		"linked with response id out parameter": {
+			def:     linkManager,
+			method:  "LinkManager.SetProtocol",
+			payload: []byte{0x09, 0x00, 0x01},
+			expected: Response{Values: []OutValue{
+				{Name: "result", Type: "uint8", Value: TypeUint8(0)},
+				{Name: "protocol", Type: "uint8", Value: TypeUint8(1)},
+			}},
+		},
+		"linked without response id out parameter": {
+			def:     linkManager,
+			method:  "LinkManager.DeleteLink",
+			payload: []byte{0x03, 0x01, 0x44, 0x33, 0x22, 0x11},
+			expected: Response{Values: []OutValue{
+				{Name: "result", Type: "uint8", Value: TypeUint8(1)},
+				{Name: "deleteLinkId", Type: "uint32", Value: TypeUint32(0x11223344)},
+			}},
+		},
+		"linked with null terminated string": {
+			def:    linkManager,
+			method: "LinkManager.Discover",
+			payload: []byte{
+				0x13,
+				0x9F, 0x57, 0xF7, 0x70,
+				0x00, 0x00, 0x00, 0x00,
+				'm', 'o', 'w', 'e', 'r', 0x00,
+			},
+			expected: Response{Values: []OutValue{
+				{Name: "tracebackId", Type: "uint32", Value: TypeUint32(0x70F7579F)},
+				{Name: "nodeType", Type: "uint32", Value: TypeUint32(0)},
+				{Name: "nodeName", Type: "ascii", Value: TypeAscii("mower")},
+			}},
+		},
+		"string taking the rest of the payload": {
+			def:     linkManager,
+			method:  "LinkManager.GetNodeName",
+			payload: []byte{0x60, 0x00, 0x0A, 0x06, 0x00, 0x00, 'm', 'o', 'w', 'e', 'r'},
+			expected: Response{Values: []OutValue{
+				{Name: "nodeName", Type: "ascii", Value: TypeAscii("mower")},
+			}},
+		},
+		"enum range and padding": {
+			def:    battery,
+			method: "Battery.GetStatus",
+			payload: []byte{
+				0x34, 0x12, 0x01, 0x11, 0x00,
+				0x00,
+				0x50,
+				0x01,
+				0xFE, 0xFF,
+				'A', 'B', 0x00, 0x00,
+				'h', 0x00, 0xE9, 0x00, 0x00, 0x00,
+				0xBE, 0xEF,
+			},
+			expected: Response{Values: []OutValue{
+				{Name: "charge", Type: "tPercent", Value: TypeUint8(80), Postfix: "%"},
+				{Name: "state", Type: "tChargeState", Value: TypeUint8(1), Enum: "Charging"},
+				{Name: "voltage", Type: "sint16", Value: TypeInt16(-2)},
+				{Name: "serial", Type: "ascii", Value: TypeAscii("AB")},
+				{Name: "owner", Type: "tUCS2", Value: TypeUCS2("hé")},
+				{Name: "raw", Type: "byteArray", Value: TypeByteArray{0xBE, 0xEF}},
+			}},
+		},
+		"error status without out parameters": {
+			def:      battery,
+			method:   "Battery.GetStatus",
+			payload:  []byte{0x34, 0x12, 0x01, 0x01, 0x00, 0x05},
+			expected: Response{Status: 0x05},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			method := findMethod(t, test.def, test.method)
+			response, err := NewDecoder(test.def).DecodeResponse(method, test.payload)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if diff := cmp.Diff(test.expected, response); diff != "" {
+				t.Errorf("DecodeResponse(%X) mismatch (-expected +got):\n%s", test.payload, diff)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseErrors(t *testing.T) {
+	t.Parallel()
+
+	def := loadTestDefinition(t, "testdata/linkmanager-def.json")
+	decoder := NewDecoder(def)
+
+	tests := map[string]struct {
+		method           string
+		payload          []byte
+		expectedTooShort *ErrResponseTooShort
+		expectedTooLong  *ErrResponseTooLong
+	}{
+		"too short": {
+			method:           "LinkManager.DeleteLink",
+			payload:          []byte{0x03, 0x00, 0x44, 0x33},
+			expectedTooShort: &ErrResponseTooShort{Method: "LinkManager.DeleteLink", Param: "deleteLinkId", Needed: 4, Remaining: 2},
+		},
+		"missing null terminator": {
+			method:           "LinkManager.Discover",
+			payload:          []byte{0x13, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'm'},
+			expectedTooShort: &ErrResponseTooShort{Method: "LinkManager.Discover", Param: "nodeName", Needed: 2, Remaining: 1},
+		},
+		"too long": {
+			method:          "LinkManager.SetProtocol",
+			payload:         []byte{0x09, 0x00, 0x01, 0xFF, 0xFF},
+			expectedTooLong: &ErrResponseTooLong{Method: "LinkManager.SetProtocol", Extra: 2},
+		},
+		"wrong response id": {
+			method:  "LinkManager.SetProtocol",
+			payload: []byte{0x03, 0x00, 0x01},
+		},
+		"wrong method": {
+			method:  "LinkManager.GetNodeName",
+			payload: []byte{0x60, 0x00, 0x00, 0x01, 0x00, 0x00},
+		},
+		"missing status": {
+			method:  "LinkManager.GetNodeName",
+			payload: []byte{0x60, 0x00, 0x0A, 0x00, 0x00},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			_, err := decoder.DecodeResponse(findMethod(t, def, test.method), test.payload)
+			if err == nil {
+				t.Fatal("expected an error but got none")
+			}
+
+			var tooShort *ErrResponseTooShort
+			if test.expectedTooShort != nil && (!errors.As(err, &tooShort) || *tooShort != *test.expectedTooShort) {
+				t.Errorf("expected %v but got %v", test.expectedTooShort, err)
+			}
+			var tooLong *ErrResponseTooLong
+			if test.expectedTooLong != nil && (!errors.As(err, &tooLong) || *tooLong != *test.expectedTooLong) {
+				t.Errorf("expected %v but got %v", test.expectedTooLong, err)
+			}
+		})
+	}
+}
+
+func TestOutValueString(t *testing.T) {
+	t.Parallel()
+
+	tests := map[OutValue]string{
+		{Value: TypeUint8(80), Postfix: "%"}:    "80 %",
+		{Value: TypeUint8(1), Enum: "Charging"}: "Charging",
+		{Value: TypeInt16(-2)}:                  "-2",
+		{Value: TypeAscii("mower")}:             "mower",
+	}
+	for value, expected := range tests {
+		if value.String() != expected {
+			t.Errorf("OutValue(%+v).String() returned %q; expected %q", value, value.String(), expected)
+		}
+	}
+
+	raw := OutValue{Value: TypeByteArray{0xBE, 0xEF}}
+	if raw.String() != "BEEF" {
+		t.Errorf("OutValue(%+v).String() returned %q; expected %q", raw, raw.String(), "BEEF")
+	}
+}
+
+func TestParamsTakingRestOfPayloadMustBeLast(t *testing.T) {
+	t.Parallel()
+
+	def := &TifDefinition{}
+	tests := map[string]struct {
+		typ    string
+		length FlexString
+	}{
+		"byte array without length": {typ: "byteArray"},
+		"byte array of length 0":    {typ: "byteArray", length: "0"},
+		"remaining byte array":      {typ: "byteArray", length: "-1"},
+		"remaining string":          {typ: "ascii", length: "-1"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			_, err := NewDecoder(def).DecodeParams("Test.Get", []OutputParameter{
+				{Name: "raw", Type: test.typ, Length: test.length},
+				{Name: "crc", Type: "uint8"},
+			}, []byte{0xBE, 0xEF, 0x01})
+			if err == nil {
+				t.Error("expected DecodeParams to refuse the parameter but got no error")
+			}
+
+			_, err = NewEncoder(def).EncodeParams([]InputParameter{
+				{Name: "raw", Type: test.typ, Length: test.length},
+				{Name: "crc", Type: "uint8"},
+			}, []any{TypeByteArray{0xBE, 0xEF}, TypeUint8(1)})
+			if err == nil {
+				t.Error("expected EncodeParams to refuse the parameter but got no error")
+			}
+		})
+	}
+}
+
+func TestDecodeRemainingString(t *testing.T) {
+	t.Parallel()
+
+	params := []OutputParameter{{Name: "name", Type: "tUCS2", Length: "-1"}}
+	decoder := NewDecoder(&TifDefinition{})
+
+	values, err := decoder.DecodeParams("Test.Get", params, []byte{'h', 0x00, 'i', 0x00})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := []OutValue{{Name: "name", Type: "tUCS2", Value: TypeUCS2("hi")}}
+	if diff := cmp.Diff(expected, values); diff != "" {
+		t.Errorf("DecodeParams mismatch (-expected +got):\n%s", diff)
+	}
+
+	_, err = decoder.DecodeParams("Test.Get", params, []byte{'h', 0x00, 'i', 0x00, '!'})
+	var tooLong *ErrResponseTooLong
+	if !errors.As(err, &tooLong) || *tooLong != (ErrResponseTooLong{Method: "Test.Get", Extra: 1}) {
+		t.Errorf("expected the odd trailing byte to make the response too long but got %v", err)
+	}
+}
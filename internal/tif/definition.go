@@ -1,8 +1,12 @@
 package tif
 
 import (
+	"encoding/json"
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type TifDefinition struct {
@@ -22,24 +26,27 @@ type TypeV2Definition struct {
 	Type        string `json:"type"`
 	Postfix     string `json:"postfix"`
 	Range       struct {
-		Type  string `json:"type"`
-		Start int    `json:"start"`
-		Stop  int    `json:"stop"`
-		Enums []struct {
-			Key         string `json:"key"`
-			Value       int    `json:"value"`
-			Description string `json:"description"`
-		} `json:"enum"`
+		Type  string           `json:"type"`
+		Start int              `json:"start"`
+		Stop  int              `json:"stop"`
+		Enums []EnumDefinition `json:"enum"`
 	} `json:"range"`
 }
 
+type EnumDefinition struct {
+	Key         string `json:"key"`
+	Value       int    `json:"value"`
+	Description string `json:"description"`
+}
+
 type AttributeV2Definition struct {
 	Family      string `json:"family"`
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
 	Params      []struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
+		Name   string     `json:"name"`
+		Type   string     `json:"type"`
+		Length FlexString `json:"length"`
 	} `json:"params"`
 	Operations []string `json:"operations"`
 	Read       struct {
@@ -66,6 +73,10 @@ type AttributeV2Definition struct {
 	} `json:"list,omitempty"`
 }
 
+func (attr AttributeV2Definition) FullName() string {
+	return fmt.Sprintf("%s.%s", attr.Family, attr.Name)
+}
+
 func (attr AttributeV2Definition) ReadCommand() (string, bool) {
 	if !slices.Contains(attr.Operations, "read") {
 		return "", false
@@ -82,32 +93,124 @@ func (attr AttributeV2Definition) WriteCommand() (string, bool) {
 	return fmt.Sprintf("%s.%s", attr.Write.Command.Family, attr.Write.Command.Name), true
 }
 
+// AllowsReadLoginLevel reports whether the attribute may be read at login level, ignoring case.
+// Attributes without read login levels may be read at any level.
+func (attr AttributeV2Definition) AllowsReadLoginLevel(level string) bool {
+	return allowsLoginLevel(attr.Protocol.Read.LoginLevels, level)
+}
+
+// FlexString is a string in the definition that some definitions write as a JSON number or bool.
+type FlexString string
+
+func (s *FlexString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		err := json.Unmarshal(data, &str)
+		*s = FlexString(str)
+		return err
+	}
+	if string(data) == "null" {
+		*s = ""
+		return nil
+	}
+	*s = FlexString(data)
+	return nil
+}
+
 type InputParameter struct {
-	Name   string `json:"name"`
-	Type   string `json:"type"`
-	Length string `json:"length"`
+	Name   string     `json:"name"`
+	Type   string     `json:"type"`
+	Length FlexString `json:"length"`
+}
+
+type OutputParameter struct {
+	Name   string     `json:"name"`
+	Type   string     `json:"type"`
+	Length FlexString `json:"length"`
+	Tags   []string   `json:"tags,omitempty"`
+}
+
+type ProtocolEntry struct {
+	Key   string     `json:"key"`
+	Value FlexString `json:"value"`
 }
 
 type MethodDefinition struct {
-	Family      string           `json:"family"`
-	Command     string           `json:"command"`
-	Description string           `json:"description,omitempty"`
-	ElementType string           `json:"elementType"`
-	InParams    []InputParameter `json:"inParams"`
-	OutParams   []struct {
-		Name string   `json:"name"`
-		Type string   `json:"type"`
-		Tags []string `json:"tags,omitempty"`
-	} `json:"outParams"`
-	Protocol []struct {
-		Key   string `json:"key"`
-		Value string `json:"value"`
-	} `json:"protocol"`
-	LoginLevels     []string `json:"loginLevels,omitempty"`
-	Tags            []string `json:"tags,omitempty"`
-	MaxResponseTime string   `json:"maxResponseTime,omitempty"`
+	Family          string            `json:"family"`
+	Command         string            `json:"command"`
+	Description     string            `json:"description,omitempty"`
+	ElementType     string            `json:"elementType"`
+	InParams        []InputParameter  `json:"inParams"`
+	OutParams       []OutputParameter `json:"outParams"`
+	Protocol        []ProtocolEntry   `json:"protocol"`
+	LoginLevels     []string          `json:"loginLevels,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	MaxResponseTime string            `json:"maxResponseTime,omitempty"`
 }
 
 func (m MethodDefinition) Name() string {
 	return fmt.Sprintf("%s.%s", m.Family, m.Command)
 }
+
+// Signature returns how to call the method, such as Family.Command(param: type) -> (out: type).
+func (m MethodDefinition) Signature() string {
+	in := make([]string, len(m.InParams))
+	for i, param := range m.InParams {
+		in[i] = fmt.Sprintf("%s: %s", param.Name, param.Type)
+	}
+	signature := fmt.Sprintf("%s(%s)", m.Name(), strings.Join(in, ", "))
+	if len(m.OutParams) == 0 {
+		return signature
+	}
+
+	out := make([]string, len(m.OutParams))
+	for i, param := range m.OutParams {
+		out[i] = fmt.Sprintf("%s: %s", param.Name, param.Type)
+	}
+	return fmt.Sprintf("%s -> (%s)", signature, strings.Join(out, ", "))
+}
+
+// ResponseTimeout returns how long the method may take to respond, if the definition says.
+// The maximum response time is given in milliseconds, or as a duration such as "1.5s".
+func (m MethodDefinition) ResponseTimeout() (time.Duration, bool) {
+	if m.MaxResponseTime == "" {
+		return 0, false
+	}
+	if ms, err := strconv.Atoi(m.MaxResponseTime); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if d, err := time.ParseDuration(m.MaxResponseTime); err == nil && d > 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// AllowsLoginLevel reports whether the method may be called at login level, ignoring case.
+// Methods without login levels may be called at any level.
+func (m MethodDefinition) AllowsLoginLevel(level string) bool {
+	return allowsLoginLevel(m.LoginLevels, level)
+}
+
+func allowsLoginLevel(levels []string, level string) bool {
+	return len(levels) == 0 || slices.ContainsFunc(levels, func(l string) bool { return strings.EqualFold(l, level) })
+}
+
+// LoginLevels returns the login levels the methods and attributes of the definition
+// mention, in the order they are first mentioned. Levels differing only in case are the same.
+func (def *TifDefinition) LoginLevels() []string {
+	var levels []string
+	add := func(mentioned []string) {
+		for _, level := range mentioned {
+			if level != "" && !containsFold(levels, level) {
+				levels = append(levels, level)
+			}
+		}
+	}
+	for _, method := range def.Methods {
+		add(method.LoginLevels)
+	}
+	for _, attr := range def.AttributesV2 {
+		add(attr.Protocol.Read.LoginLevels)
+	}
+	return levels
+}
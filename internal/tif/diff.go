@@ -0,0 +1,317 @@
+package tif
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Kinds of definition elements a Change can be about.
+const (
+	ElementMethod    = "method"
+	ElementAttribute = "attribute"
+	ElementType      = "type"
+)
+
+// ChangeDetail is one difference within a changed element.
+type ChangeDetail struct {
+	Description string `json:"description"`
+	// Whether clients written for the old definition may fail with the new
+	Breaking bool `json:"breaking"`
+}
+
+// Change is a method, attribute or type that was added, removed or changed between two definitions.
+type Change struct {
+	Kind    ChangeKind     `json:"kind"`
+	Element string         `json:"element"`
+	Name    string         `json:"name"`
+	Details []ChangeDetail `json:"details,omitempty"`
+	// Whether clients written for the old definition may fail with the new
+	Breaking bool `json:"breaking"`
+}
+
+// DefinitionDiff is the difference between two definitions, such as those of two firmware versions.
+type DefinitionDiff struct {
+	OldVersions []string `json:"oldVersions"`
+	NewVersions []string `json:"newVersions"`
+	// Methods, then attributes, then types, each in order of name
+	Changes []Change `json:"changes"`
+}
+
+// HasBreaking reports whether any change is breaking.
+func (d DefinitionDiff) HasBreaking() bool {
+	return slices.ContainsFunc(d.Changes, func(c Change) bool { return c.Breaking })
+}
+
+// DiffDefinitions compares the methods, attributes and types of old and new.
+// Elements are matched by name, ignoring case, the same way they are looked up.
+func DiffDefinitions(old, new *TifDefinition) DefinitionDiff {
+	diff := DefinitionDiff{
+		OldVersions: old.Header.TifVersions,
+		NewVersions: new.Header.TifVersions,
+		Changes:     []Change{},
+	}
+	diff.Changes = append(diff.Changes, diffElements(ElementMethod, old.Methods, new.Methods, MethodDefinition.Name, diffMethod)...)
+	diff.Changes = append(diff.Changes, diffElements(ElementAttribute, old.AttributesV2, new.AttributesV2, AttributeV2Definition.FullName, diffAttribute)...)
+	diff.Changes = append(diff.Changes, diffElements(ElementType, old.TypesV2, new.TypesV2, func(t TypeV2Definition) string { return t.Name }, diffType)...)
+	return diff
+}
+
+// diffElements matches old and new elements by name. Removed elements are breaking, added ones are not,
+// and elements in both are changed if diffElement finds any difference.
+func diffElements[T any](element string, old, new []T, name func(T) string, diffElement func(old, new T) []ChangeDetail) []Change {
+	oldByName := make(map[string]T, len(old))
+	for _, e := range old {
+		key := strings.ToLower(name(e))
+		if _, ok := oldByName[key]; !ok {
+			oldByName[key] = e
+		}
+	}
+	newByName := make(map[string]T, len(new))
+	for _, e := range new {
+		key := strings.ToLower(name(e))
+		if _, ok := newByName[key]; !ok {
+			newByName[key] = e
+		}
+	}
+
+	var changes []Change
+	for key, oldElement := range oldByName {
+		newElement, ok := newByName[key]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeRemoved, Element: element, Name: name(oldElement), Breaking: true})
+			continue
+		}
+		details := diffElement(oldElement, newElement)
+		if len(details) == 0 {
+			continue
+		}
+		changes = append(changes, Change{
+			Kind:     ChangeChanged,
+			Element:  element,
+			Name:     name(newElement),
+			Details:  details,
+			Breaking: slices.ContainsFunc(details, func(d ChangeDetail) bool { return d.Breaking }),
+		})
+	}
+	for key, newElement := range newByName {
+		if _, ok := oldByName[key]; !ok {
+			changes = append(changes, Change{Kind: ChangeAdded, Element: element, Name: name(newElement)})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return changes
+}
+
+func diffMethod(old, new MethodDefinition) []ChangeDetail {
+	var details []ChangeDetail
+
+	if oldProtocol, newProtocol := describeProtocol(old), describeProtocol(new); oldProtocol != newProtocol {
+		details = append(details, ChangeDetail{
+			Description: fmt.Sprintf("protocol changed from %s to %s", oldProtocol, newProtocol),
+			Breaking:    true,
+		})
+	}
+
+	details = append(details, diffParams("in parameter", paramsOf(old.InParams), paramsOf(new.InParams), true)...)
+	details = append(details, diffParams("out parameter", outParamsOf(old.OutParams), outParamsOf(new.OutParams), false)...)
+	details = append(details, diffLoginLevels(old.LoginLevels, new.LoginLevels)...)
+
+	if old.MaxResponseTime != new.MaxResponseTime {
+		details = append(details, ChangeDetail{Description: fmt.Sprintf("max response time changed from %s to %s", orNone(old.MaxResponseTime), orNone(new.MaxResponseTime))})
+	}
+	if oldTags, newTags := tagSet(old.Tags), tagSet(new.Tags); !slices.Equal(oldTags, newTags) {
+		details = append(details, ChangeDetail{Description: fmt.Sprintf("tags changed from %v to %v", oldTags, newTags)})
+	}
+	return details
+}
+
+// tagSet returns tags sorted and without repeats, as their order does not matter.
+func tagSet(tags []string) []string {
+	set := slices.Clone(tags)
+	slices.Sort(set)
+	return slices.Compact(set)
+}
+
+func describeProtocol(method MethodDefinition) string {
+	if method.IsLinked() {
+		request, _, err := method.RequestId()
+		if err != nil {
+			return "link manager"
+		}
+		return fmt.Sprintf("link manager request %d", request)
+	}
+	id, err := method.MethodId()
+	if err != nil {
+		return "payload"
+	}
+	return id.String()
+}
+
+// param is what a diff compares of input, output and attribute parameters.
+type param struct {
+	Name   string
+	Type   string
+	Length FlexString
+}
+
+func paramsOf(params []InputParameter) []param {
+	compared := make([]param, len(params))
+	for i, p := range params {
+		compared[i] = param{Name: p.Name, Type: p.Type, Length: p.Length}
+	}
+	return compared
+}
+
+func outParamsOf(params []OutputParameter) []param {
+	compared := make([]param, len(params))
+	for i, p := range params {
+		compared[i] = param{Name: p.Name, Type: p.Type, Length: p.Length}
+	}
+	return compared
+}
+
+// diffParams compares parameters by position, as that is how they are laid out in payloads.
+// Changes to the layout are breaking, and so are renames of parameters given by the caller,
+// as calls name their arguments. Out parameters may be renamed without breaking anything.
+func diffParams(kind string, old, new []param, given bool) []ChangeDetail {
+	var details []ChangeDetail
+	for i := range max(len(old), len(new)) {
+		switch {
+		case i >= len(old):
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("%s %s: %s added", kind, new[i].Name, new[i].Type), Breaking: true})
+		case i >= len(new):
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("%s %s removed", kind, old[i].Name), Breaking: true})
+		default:
+			if old[i].Name != new[i].Name {
+				details = append(details, ChangeDetail{Description: fmt.Sprintf("%s %s renamed to %s", kind, old[i].Name, new[i].Name), Breaking: given})
+			}
+			if old[i].Type != new[i].Type {
+				details = append(details, ChangeDetail{Description: fmt.Sprintf("%s %s type changed from %s to %s", kind, new[i].Name, old[i].Type, new[i].Type), Breaking: true})
+			}
+			if old[i].Length != new[i].Length {
+				details = append(details, ChangeDetail{Description: fmt.Sprintf("%s %s length changed from %s to %s", kind, new[i].Name, orNone(string(old[i].Length)), orNone(string(new[i].Length))), Breaking: true})
+			}
+		}
+	}
+	return details
+}
+
+// diffLoginLevels reports levels that may no longer call a method as breaking. Methods
+// without login levels may be called at any level, so restricting them is breaking too.
+func diffLoginLevels(old, new []string) []ChangeDetail {
+	switch {
+	case len(old) == 0 && len(new) == 0:
+		return nil
+	case len(old) == 0:
+		return []ChangeDetail{{Description: fmt.Sprintf("restricted to login level %s", strings.Join(new, ", ")), Breaking: true}}
+	case len(new) == 0:
+		return []ChangeDetail{{Description: fmt.Sprintf("no longer restricted to login level %s", strings.Join(old, ", "))}}
+	}
+
+	var details []ChangeDetail
+	for _, level := range old {
+		if !containsFold(new, level) {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("login level %s removed", level), Breaking: true})
+		}
+	}
+	for _, level := range new {
+		if !containsFold(old, level) {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("login level %s added", level)})
+		}
+	}
+	return details
+}
+
+func diffAttribute(old, new AttributeV2Definition) []ChangeDetail {
+	var details []ChangeDetail
+
+	oldParams := make([]param, len(old.Params))
+	for i, p := range old.Params {
+		oldParams[i] = param{Name: p.Name, Type: p.Type, Length: p.Length}
+	}
+	newParams := make([]param, len(new.Params))
+	for i, p := range new.Params {
+		newParams[i] = param{Name: p.Name, Type: p.Type, Length: p.Length}
+	}
+	details = append(details, diffParams("parameter", oldParams, newParams, true)...)
+
+	for _, operation := range old.Operations {
+		if !slices.Contains(new.Operations, operation) {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("operation %s removed", operation), Breaking: true})
+		}
+	}
+	for _, operation := range new.Operations {
+		if !slices.Contains(old.Operations, operation) {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("operation %s added", operation)})
+		}
+	}
+
+	oldRead, _ := old.ReadCommand()
+	newRead, hasRead := new.ReadCommand()
+	if hasRead && oldRead != "" && oldRead != newRead {
+		details = append(details, ChangeDetail{Description: fmt.Sprintf("read command changed from %s to %s", oldRead, newRead)})
+	}
+	oldWrite, _ := old.WriteCommand()
+	newWrite, hasWrite := new.WriteCommand()
+	if hasWrite && oldWrite != "" && oldWrite != newWrite {
+		details = append(details, ChangeDetail{Description: fmt.Sprintf("write command changed from %s to %s", oldWrite, newWrite)})
+	}
+
+	details = append(details, diffLoginLevels(old.Protocol.Read.LoginLevels, new.Protocol.Read.LoginLevels)...)
+	return details
+}
+
+func diffType(old, new TypeV2Definition) []ChangeDetail {
+	var details []ChangeDetail
+	if old.Type != new.Type {
+		details = append(details, ChangeDetail{Description: fmt.Sprintf("type changed from %s to %s", old.Type, new.Type), Breaking: true})
+	}
+	if old.Postfix != new.Postfix {
+		details = append(details, ChangeDetail{Description: fmt.Sprintf("postfix changed from %s to %s", orNone(old.Postfix), orNone(new.Postfix))})
+	}
+
+	if old.Range.Start != new.Range.Start || old.Range.Stop != new.Range.Stop {
+		narrowed := new.Range.Start > old.Range.Start || new.Range.Stop < old.Range.Stop
+		details = append(details, ChangeDetail{
+			Description: fmt.Sprintf("range changed from %d..%d to %d..%d", old.Range.Start, old.Range.Stop, new.Range.Start, new.Range.Stop),
+			Breaking:    narrowed,
+		})
+	}
+
+	for _, oldEnum := range old.Range.Enums {
+		i := slices.IndexFunc(new.Range.Enums, func(e EnumDefinition) bool { return strings.EqualFold(e.Key, oldEnum.Key) })
+		if i < 0 {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("enum value %s (%d) removed", oldEnum.Key, oldEnum.Value), Breaking: true})
+			continue
+		}
+		if newEnum := new.Range.Enums[i]; newEnum.Value != oldEnum.Value {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("enum value %s changed from %d to %d", newEnum.Key, oldEnum.Value, newEnum.Value), Breaking: true})
+		}
+	}
+	for _, newEnum := range new.Range.Enums {
+		if !slices.ContainsFunc(old.Range.Enums, func(e EnumDefinition) bool { return strings.EqualFold(e.Key, newEnum.Key) }) {
+			details = append(details, ChangeDetail{Description: fmt.Sprintf("enum value %s (%d) added", newEnum.Key, newEnum.Value)})
+		}
+	}
+	return details
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,220 @@
+package tif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const diffOldDefinitionJson = `{
+  "header": { "tifVersions": ["1.0"] },
+  "methods": [
+    {
+      "family": "Mower",
+      "command": "GetMode",
+      "outParams": [{ "name": "mode", "type": "tMode" }],
+      "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "1" }],
+      "loginLevels": ["User", "Service"],
+      "tags": ["status", "mode"]
+    },
+    {
+      "family": "Mower",
+      "command": "SetName",
+      "inParams": [{ "name": "name", "type": "ascii", "length": 16 }],
+      "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "2" }],
+      "tags": ["config"]
+    },
+    { "family": "Mower", "command": "Reset", "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "3" }] }
+  ],
+  "types-v2": [
+    {
+      "name": "tMode",
+      "type": "uint8",
+      "range": { "type": "enum", "enum": [{ "key": "Auto", "value": 0 }, { "key": "Manual", "value": 1 }] }
+    },
+    { "name": "tHeight", "type": "uint8", "postfix": "mm", "range": { "type": "range", "start": 20, "stop": 60 } }
+  ],
+  "attributes-v2": [
+    {
+      "family": "Mower",
+      "name": "Mode",
+      "params": [{ "name": "mode", "type": "tMode" }],
+      "operations": ["read", "write"]
+    },
+    {
+      "family": "Mower",
+      "name": "Name",
+      "params": [{ "name": "name", "type": "ascii", "length": 16 }],
+      "operations": ["read"]
+    }
+  ]
+}`
+
+const diffNewDefinitionJson = `{
+  "header": { "tifVersions": ["1.1"] },
+  "methods": [
+    {
+      "family": "Mower",
+      "command": "GetMode",
+      "outParams": [{ "name": "currentMode", "type": "tMode" }],
+      "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "1" }],
+      "loginLevels": ["Service", "Factory"],
+      "tags": ["mode", "status"]
+    },
+    {
+      "family": "Mower",
+      "command": "SetName",
+      "inParams": [{ "name": "name", "type": "tUCS2", "length": 16 }],
+      "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "2" }],
+      "tags": ["name", "config"]
+    },
+    { "family": "Mower", "command": "GetName", "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "4" }] }
+  ],
+  "types-v2": [
+    {
+      "name": "tMode",
+      "type": "uint8",
+      "range": { "type": "enum", "enum": [{ "key": "Auto", "value": 0 }, { "key": "Manual", "value": 1 }, { "key": "Home", "value": 2 }] }
+    },
+    { "name": "tHeight", "type": "uint8", "postfix": "mm", "range": { "type": "range", "start": 10, "stop": 60 } }
+  ],
+  "attributes-v2": [
+    {
+      "family": "Mower",
+      "name": "Mode",
+      "params": [{ "name": "mode", "type": "tMode" }],
+      "operations": ["read"]
+    },
+    {
+      "family": "Mower",
+      "name": "Name",
+      "params": [{ "name": "name", "type": "ascii", "length": 8 }],
+      "operations": ["read"]
+    }
+  ]
+}`
+
+func TestDiffDefinitions(t *testing.T) {
+	t.Parallel()
+
+	var old, new TifDefinition
+	if err := json.Unmarshal([]byte(diffOldDefinitionJson), &old); err != nil {
+		t.Fatalf("failed to parse old definition: %v", err)
+	}
+	if err := json.Unmarshal([]byte(diffNewDefinitionJson), &new); err != nil {
+		t.Fatalf("failed to parse new definition: %v", err)
+	}
+
+	expected := DefinitionDiff{
+		OldVersions: []string{"1.0"},
+		NewVersions: []string{"1.1"},
+		Changes: []Change{
+			{
+				Kind:    ChangeChanged,
+				Element: ElementMethod,
+				Name:    "Mower.GetMode",
+				Details: []ChangeDetail{
+					{Description: "out parameter mode renamed to currentMode"},
+					{Description: "login level User removed", Breaking: true},
+					{Description: "login level Factory added"},
+				},
+				Breaking: true,
+			},
+			{Kind: ChangeAdded, Element: ElementMethod, Name: "Mower.GetName"},
+			{Kind: ChangeRemoved, Element: ElementMethod, Name: "Mower.Reset", Breaking: true},
+			{
+				Kind:    ChangeChanged,
+				Element: ElementMethod,
+				Name:    "Mower.SetName",
+				Details: []ChangeDetail{
+					{Description: "in parameter name type changed from ascii to tUCS2", Breaking: true},
+					{Description: "tags changed from [config] to [config name]"},
+				},
+				Breaking: true,
+			},
+			{
+				Kind:     ChangeChanged,
+				Element:  ElementAttribute,
+				Name:     "Mower.Mode",
+				Details:  []ChangeDetail{{Description: "operation write removed", Breaking: true}},
+				Breaking: true,
+			},
+			{
+				Kind:     ChangeChanged,
+				Element:  ElementAttribute,
+				Name:     "Mower.Name",
+				Details:  []ChangeDetail{{Description: "parameter name length changed from 16 to 8", Breaking: true}},
+				Breaking: true,
+			},
+			{
+				Kind:    ChangeChanged,
+				Element: ElementType,
+				Name:    "tHeight",
+				Details: []ChangeDetail{{Description: "range changed from 20..60 to 10..60"}},
+			},
+			{
+				Kind:    ChangeChanged,
+				Element: ElementType,
+				Name:    "tMode",
+				Details: []ChangeDetail{{Description: "enum value Home (2) added"}},
+			},
+		},
+	}
+
+	diff := DiffDefinitions(&old, &new)
+	if d := cmp.Diff(expected, diff); d != "" {
+		t.Errorf("DiffDefinitions mismatch (-expected +got):\n%s", d)
+	}
+	if !diff.HasBreaking() {
+		t.Error("expected the diff to have breaking changes")
+	}
+
+	same := DiffDefinitions(&new, &new)
+	if len(same.Changes) != 0 || same.HasBreaking() {
+		t.Errorf("expected no changes between a definition and itself but got %v", same.Changes)
+	}
+}
+
+func TestDiffLoginLevels(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		old      []string
+		new      []string
+		expected []ChangeDetail
+	}{
+		"unrestricted": {},
+		"same ignoring case": {
+			old: []string{"Service"},
+			new: []string{"service"},
+		},
+		"restricted": {
+			new:      []string{"Service"},
+			expected: []ChangeDetail{{Description: "restricted to login level Service", Breaking: true}},
+		},
+		"no longer restricted": {
+			old:      []string{"Operator", "Service"},
+			expected: []ChangeDetail{{Description: "no longer restricted to login level Operator, Service"}},
+		},
+		"level removed and added": {
+			old: []string{"Operator"},
+			new: []string{"Service"},
+			expected: []ChangeDetail{
+				{Description: "login level Operator removed", Breaking: true},
+				{Description: "login level Service added"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			if diff := cmp.Diff(test.expected, diffLoginLevels(test.old, test.new)); diff != "" {
+				t.Errorf("diffLoginLevels(%v, %v) mismatch (-expected +got):\n%s", test.old, test.new, diff)
+			}
+		})
+	}
+}
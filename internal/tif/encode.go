@@ -0,0 +1,197 @@
+package tif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Special values of the length of a string or array parameter.
+const (
+	// The value is followed by a null terminator.
+	LengthNullTerminated = 0
+	// The value takes up the rest of the payload, it must be the last parameter.
+	LengthRemaining = -1
+)
+
+type ErrArgumentLength struct {
+	Param  string
+	Length int
+	Actual int
+}
+
+func (e *ErrArgumentLength) Error() string {
+	return fmt.Sprintf("argument for %s does not fit in its length; length: %d, argument length: %d", e.Param, e.Length, e.Actual)
+}
+
+// Encoder serializes method calls from a definition to their RoboticsProtocol2 payloads.
+type Encoder struct {
+	types *TypeRegistry
+}
+
+func NewEncoder(def *TifDefinition) *Encoder {
+	return &Encoder{types: NewTypeRegistry(def)}
+}
+
+// Types returns the resolved types of the definition.
+func (e *Encoder) Types() *TypeRegistry {
+	return e.types
+}
+
+// Primitive resolves a parameter type, which may be a type from types-v2, to its primitive type.
+func (e *Encoder) Primitive(typeName string) (string, error) {
+	return e.types.Primitive(typeName)
+}
+
+// Validate checks value against the range or enumerators of typeName, if it is a types-v2 type.
+func (e *Encoder) Validate(typeName string, value any) error {
+	return e.types.Validate(typeName, value)
+}
+
+// ParseArgument parses the string value of an argument for param, checking it against
+// the range or enumerators of its type. Arguments of enum types may also be given as
+// the key of an enumerator.
+func (e *Encoder) ParseArgument(param InputParameter, value string) (any, error) {
+	return e.types.ParseString(param.Type, value)
+}
+
+// EncodeCall returns the payload calling method with args, given in the order of its InParams.
+//
+// Methods that are not linked are sent as payload commands, starting with a method header.
+// Linked methods are link manager commands, starting with their request id.
+func (e *Encoder) EncodeCall(method MethodDefinition, args []any) ([]byte, error) {
+	params, err := e.EncodeParams(method.InParams, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode call to %s: %w", method.Name(), err)
+	}
+
+	if method.IsLinked() {
+		requestId, _, err := method.RequestId()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{requestId}, params...), nil
+	}
+
+	id, err := method.MethodId()
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0xFFFF {
+		return nil, fmt.Errorf("parameters of %s are %d bytes, more than fit in a method payload", method.Name(), len(params))
+	}
+	payload := AppendMethodHeader(make([]byte, 0, MethodHeaderSize+len(params)), id, len(params))
+	return append(payload, params...), nil
+}
+
+// EncodeParams encodes args, one for each of params, in little endian.
+func (e *Encoder) EncodeParams(params []InputParameter, args []any) ([]byte, error) {
+	if len(args) != len(params) {
+		return nil, fmt.Errorf("expected %d arguments but got %d", len(params), len(args))
+	}
+
+	var buf []byte
+	for i, param := range params {
+		primitive, err := e.Primitive(param.Type)
+		if err != nil {
+			return nil, err
+		}
+		length, hasLength, err := parseLength(param.Name, param.Length)
+		if err != nil {
+			return nil, err
+		}
+		if takesRemaining(primitive, length, hasLength) && i != len(params)-1 {
+			return nil, fmt.Errorf("parameter %s takes up the rest of the payload but is not the last parameter", param.Name)
+		}
+
+		buf, err = appendValue(buf, param.Name, primitive, length, hasLength, args[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func parseLength(param string, value FlexString) (length int, ok bool, err error) {
+	if value == "" {
+		return 0, false, nil
+	}
+	length, err = strconv.Atoi(string(value))
+	if err != nil {
+		return 0, false, fmt.Errorf("parameter %s has an invalid length %q: %w", param, value, err)
+	}
+	if length < LengthRemaining {
+		return 0, false, fmt.Errorf("parameter %s has an invalid length %d", param, length)
+	}
+	return length, true, nil
+}
+
+// takesRemaining reports whether a parameter takes up the rest of the payload, which
+// it can only do as the last parameter. Byte arrays without a length have no terminator.
+func takesRemaining(primitive string, length int, hasLength bool) bool {
+	if primitive == "byteArray" {
+		return !hasLength || length <= 0
+	}
+	return hasLength && length == LengthRemaining
+}
+
+func appendValue(buf []byte, param, primitive string, length int, hasLength bool, value any) ([]byte, error) {
+	valuePrimitive, ok := primitiveOf(value)
+	if !ok || valuePrimitive != primitive {
+		return nil, fmt.Errorf("argument for %s must be of type %s, got %T", param, primitive, value)
+	}
+	if hasLength && length > 0 && !isArrayPrimitive(primitive) {
+		return nil, fmt.Errorf("parameter %s is an array of %s, which is not supported", param, primitive)
+	}
+
+	if p := primitives[primitive]; p.append != nil {
+		buf, err := p.append(buf, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument for %s: %w", param, err)
+		}
+		return buf, nil
+	}
+
+	switch v := value.(type) {
+	case TypeAscii:
+		return appendString(buf, param, []byte(v), 1, length, hasLength)
+	case TypeUCS2:
+		var encoded []byte
+		for _, unit := range utf16.Encode([]rune(string(v))) {
+			encoded = binary.LittleEndian.AppendUint16(encoded, unit)
+		}
+		return appendString(buf, param, encoded, 2, length, hasLength)
+	case TypeByteArray:
+		if hasLength && length > 0 && len(v) != length {
+			return nil, &ErrArgumentLength{Param: param, Length: length, Actual: len(v)}
+		}
+		return append(buf, v...), nil
+	default:
+		return nil, &ErrUnkownType{Type: primitive}
+	}
+}
+
+// isArrayPrimitive reports whether the length of a primitive sets its number of elements.
+func isArrayPrimitive(primitive string) bool {
+	p, ok := primitives[primitive]
+	return ok && p.width == 0
+}
+
+// appendString appends an encoded string of characters that are charSize bytes wide.
+// Strings without a length are null terminated, strings with a fixed length are padded with nulls.
+func appendString(buf []byte, param string, encoded []byte, charSize int, length int, hasLength bool) ([]byte, error) {
+	if !hasLength || length == LengthNullTerminated {
+		buf = append(buf, encoded...)
+		return append(buf, make([]byte, charSize)...), nil
+	}
+	if length == LengthRemaining {
+		return append(buf, encoded...), nil
+	}
+
+	if len(encoded) > length*charSize {
+		return nil, &ErrArgumentLength{Param: param, Length: length, Actual: len(encoded) / charSize}
+	}
+	buf = append(buf, encoded...)
+	return append(buf, make([]byte, length*charSize-len(encoded))...), nil
+}
@@ -0,0 +1,208 @@
+package tif
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func loadTestDefinition(t *testing.T, path string) *TifDefinition {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read definition: %v", err)
+	}
+	var def TifDefinition
+	err = json.Unmarshal(data, &def)
+	if err != nil {
+		t.Fatalf("failed to parse definition: %v", err)
+	}
+	return &def
+}
+
+func findMethod(t *testing.T, def *TifDefinition, name string) MethodDefinition {
+	t.Helper()
+
+	for _, method := range def.Methods {
+		if method.Name() == name {
+			return method
+		}
+	}
+	t.Fatalf("definition has no method %s", name)
+	return MethodDefinition{}
+}
+
+var encodeTestDefinition = &TifDefinition{
+	TypesV2: []TypeV2Definition{
+		{Name: "tCuttingHeight", Type: "uint8"},
+		{Name: "tMowerName", Type: "ascii"},
+	},
+	Methods: []MethodDefinition{
+		{
+			Family:  "Mower",
+			Command: "SetSettings",
+			InParams: []InputParameter{
+				{Name: "height", Type: "tCuttingHeight"},
+				{Name: "enabled", Type: "bool"},
+				{Name: "offset", Type: "sint16"},
+				{Name: "since", Type: "tUnixTime"},
+				{Name: "version", Type: "tSimpleVersion"},
+				{Name: "name", Type: "tMowerName", Length: "4"},
+				{Name: "owner", Type: "tUCS2"},
+				{Name: "key", Type: "byteArray", Length: "2"},
+				{Name: "data", Type: "byteArray", Length: "-1"},
+			},
+			Protocol: []ProtocolEntry{{Key: "msgType", Value: "0x1234"}, {Key: "subCmd", Value: "7"}},
+		},
+	},
+}
+
+func TestEncodeCall(t *testing.T) {
+	t.Parallel()
+
+	linkManager := loadTestDefinition(t, "testdata/linkmanager-def.json")
+
+	tests := map[string]struct {
+		def      *TifDefinition
+		method   string
+		args     []string
+		expected []byte
+	}{
+		"linked with null terminated string": {
+			def:    linkManager,
+			method: "LinkManager.Connect",
+			args:   []string{"0x11223344", "0", "tools-cli"},
+			expected: []byte{
+				0x14,
+				0x44, 0x33, 0x22, 0x11,
+				0x00, 0x00, 0x00, 0x00,
+				't', 'o', 'o', 'l', 's', '-', 'c', 'l', 'i', 0x00,
+			},
+		},
+		// Payload of a captured SetProtocol frame
+		"captured set protocol": {
+			def:      linkManager,
+			method:   "LinkManager.SetProtocol",
+			args:     []string{"1"},
+			expected: []byte{0x08, 0x01},
+		},
+		// Payload of a captured Discover frame
+		"captured discover": {
+			def:      linkManager,
+			method:   "LinkManager.Discover",
+			args:     []string{"0x70F7579F"},
+			expected: []byte{0x12, 0x9F, 0x57, 0xF7, 0x70},
+		},
+		"not linked without parameters": {
+			def:      linkManager,
+			method:   "LinkManager.GetNodeName",
+			expected: []byte{0x60, 0x00, 0x0A, 0x00, 0x00},
+		},
+		"not linked with every parameter kind": {
+			def:    encodeTestDefinition,
+			method: "Mower.SetSettings",
+			args:   []string{"50", "true", "-2", "0x65000000", "0x0102", "abc", "hé", "BEEF", "0102"},
+			expected: []byte{
+				0x34, 0x12, 0x07, 0x18, 0x00,
+				0x32,
+				0x01,
+				0xFE, 0xFF,
+				0x00, 0x00, 0x00, 0x65,
+				0x02, 0x01,
+				'a', 'b', 'c', 0x00,
+				'h', 0x00, 0xE9, 0x00, 0x00, 0x00,
+				0xBE, 0xEF,
+				0x01, 0x02,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			encoder := NewEncoder(test.def)
+			method := findMethod(t, test.def, test.method)
+			args := make([]any, len(test.args))
+			for i, arg := range test.args {
+				value, err := encoder.ParseArgument(method.InParams[i], arg)
+				if err != nil {
+					t.Fatalf("ParseArgument(%v) returned %v; expected no error", arg, err)
+				}
+				args[i] = value
+			}
+
+			payload, err := encoder.EncodeCall(method, args)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if !bytes.Equal(test.expected, payload) {
+				t.Errorf("expected payload %X but got %X", test.expected, payload)
+			}
+		})
+	}
+}
+
+func TestEncodeCallErrors(t *testing.T) {
+	t.Parallel()
+
+	encoder := NewEncoder(encodeTestDefinition)
+	method := encodeTestDefinition.Methods[0]
+	validArgs := func() []any {
+		return []any{
+			TypeUint8(50), TypeBool(true), TypeInt16(-2), TypeUnixTime(0), TypeRoboticsVersion(1),
+			TypeAscii("abc"), TypeUCS2("hé"), TypeByteArray{0xBE, 0xEF}, TypeByteArray{},
+		}
+	}
+
+	_, err := encoder.EncodeCall(method, validArgs())
+	if err != nil {
+		t.Fatalf("expected no error for valid arguments but got %v", err)
+	}
+
+	tooLongName := validArgs()
+	tooLongName[5] = TypeAscii("abcde")
+	shortKey := validArgs()
+	shortKey[7] = TypeByteArray{0xBE}
+	wrongType := validArgs()
+	wrongType[0] = TypeUint16(50)
+
+	tests := map[string]struct {
+		args           []any
+		expectedLength *ErrArgumentLength
+	}{
+		"too few arguments": {args: validArgs()[:3]},
+		"string too long": {
+			args:           tooLongName,
+			expectedLength: &ErrArgumentLength{Param: "name", Length: 4, Actual: 5},
+		},
+		"byte array length": {
+			args:           shortKey,
+			expectedLength: &ErrArgumentLength{Param: "key", Length: 2, Actual: 1},
+		},
+		"wrong type": {args: wrongType},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			_, err := encoder.EncodeCall(method, test.args)
+			if err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if test.expectedLength == nil {
+				return
+			}
+			var lengthErr *ErrArgumentLength
+			if !errors.As(err, &lengthErr) || *lengthErr != *test.expectedLength {
+				t.Errorf("expected %v but got %v", test.expectedLength, err)
+			}
+		})
+	}
+}
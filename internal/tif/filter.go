@@ -0,0 +1,158 @@
+package tif
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pattern matches names, ignoring case, against a glob such as Mower.* or a regular expression.
+// A nil pattern matches every name.
+type Pattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// NewGlobPattern returns a pattern matching whole names against glob, where * matches any
+// run of characters, ? any one character and [...] any character of a class.
+func NewGlobPattern(glob string) (*Pattern, error) {
+	glob = strings.ToLower(glob)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	return &Pattern{glob: glob}, nil
+}
+
+// NewRegexPattern returns a pattern matching names containing a match of expr.
+func NewRegexPattern(expr string) (*Pattern, error) {
+	regex, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", expr, err)
+	}
+	return &Pattern{regex: regex}, nil
+}
+
+func (p *Pattern) Match(name string) bool {
+	if p == nil {
+		return true
+	}
+	if p.regex != nil {
+		return p.regex.MatchString(name)
+	}
+	matched, _ := path.Match(p.glob, strings.ToLower(name))
+	return matched
+}
+
+// Filter selects the methods, attributes and types of an indexed definition. Empty fields select everything,
+// and tags, operations and login levels are matched ignoring case. Methods and attributes without login
+// levels are selected at any login level.
+type Filter struct {
+	Family *Pattern
+	// Matches the command of methods and the name of attributes, with or without the family, and the name of types
+	Name       *Pattern
+	Tag        string
+	Operation  string
+	LoginLevel string
+	// Words that must all be in the name or description, ignoring case
+	Search string
+}
+
+// Methods returns the methods selected by the filter, in the order they are defined.
+// Methods have no operations, so no method is selected if an operation is given.
+func (f Filter) Methods(idx *Index) []MethodDefinition {
+	if f.Operation != "" {
+		return nil
+	}
+
+	var methods []MethodDefinition
+	for _, i := range idx.methodsAt(f.Tag, f.LoginLevel) {
+		method := idx.data.Definition.Methods[i]
+		if !f.Family.Match(method.Family) || !f.matchName(method.Command, method.Name()) {
+			continue
+		}
+		text := []string{method.Name(), method.Description}
+		for _, param := range method.InParams {
+			text = append(text, param.Name)
+		}
+		for _, param := range method.OutParams {
+			text = append(text, param.Name)
+		}
+		if !containsWords(text, f.Search) {
+			continue
+		}
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// Attributes returns the attributes selected by the filter, in the order they are defined.
+// The login level of an attribute is the one it can be read at.
+func (f Filter) Attributes(idx *Index) []AttributeV2Definition {
+	var attributes []AttributeV2Definition
+	for _, i := range idx.attributesAt(f.Tag, f.LoginLevel) {
+		attr := idx.data.Definition.AttributesV2[i]
+		if !f.Family.Match(attr.Family) || !f.matchName(attr.Name, attr.FullName()) || !hasOperation(attr, f.Operation) {
+			continue
+		}
+		if !containsWords([]string{attr.FullName(), attr.Description}, f.Search) {
+			continue
+		}
+		attributes = append(attributes, attr)
+	}
+	return attributes
+}
+
+// Types returns the types-v2 types selected by the filter, in the order they are defined.
+// Types have no family, tags, operations or login levels, so no type is selected if any of them is given.
+func (f Filter) Types(idx *Index) []TypeV2Definition {
+	if f.Family != nil || f.Tag != "" || f.Operation != "" || f.LoginLevel != "" {
+		return nil
+	}
+
+	var types []TypeV2Definition
+	for _, t := range idx.data.Definition.TypesV2 {
+		if !f.Name.Match(t.Name) {
+			continue
+		}
+		text := []string{t.Name, t.Description}
+		for _, enum := range t.Range.Enums {
+			text = append(text, enum.Key, enum.Description)
+		}
+		if !containsWords(text, f.Search) {
+			continue
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+func (f Filter) matchName(name, fullName string) bool {
+	return f.Name.Match(name) || f.Name.Match(fullName)
+}
+
+// containsFold reports whether values contains value, ignoring case. Any values contain the empty value.
+func containsFold(values []string, value string) bool {
+	return value == "" || slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, value) })
+}
+
+// hasOperation reports whether attr supports operation, ignoring case. Attributes
+// with a list command can be listed even if list is not one of their operations.
+func hasOperation(attr AttributeV2Definition, operation string) bool {
+	if strings.EqualFold(operation, "list") && (attr.List.Family != "" || attr.List.Name != "") {
+		return true
+	}
+	return containsFold(attr.Operations, operation)
+}
+
+// containsWords reports whether every word of search is in one of texts, ignoring case.
+func containsWords(texts []string, search string) bool {
+	text := strings.ToLower(strings.Join(texts, "\n"))
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package tif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const filterTestDefinitionJson = `{
+  "methods": [
+    { "family": "Mower", "command": "GetMode", "description": "Current operating mode", "tags": ["status"], "loginLevels": ["Operator"] },
+    { "family": "Mower", "command": "SetMode", "inParams": [{ "name": "mode", "type": "tMode" }], "loginLevels": ["Service"] },
+    { "family": "Blade", "command": "GetSpeed", "outParams": [{ "name": "rpm", "type": "uint16" }], "tags": ["Status"] }
+  ],
+  "attributes-v2": [
+    { "family": "Mower", "name": "Mode", "operations": ["read", "write"], "tags": ["status"], "protocol": { "read": { "loginLevels": ["Operator"] } } },
+    { "family": "Mower", "name": "Events", "description": "Log of events", "operations": ["read"], "list": { "family": "Mower", "name": "ListEvents" } },
+    { "family": "Blade", "name": "Speed", "operations": ["read"] }
+  ],
+  "types-v2": [
+    { "name": "tMode", "type": "uint8", "range": { "type": "enum", "enum": [{ "key": "Auto", "value": 0 }, { "key": "Park", "value": 1 }] } },
+    { "name": "tSpeed", "type": "uint16", "description": "Rotations per minute" }
+  ]
+}`
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	var def TifDefinition
+	err := json.Unmarshal([]byte(filterTestDefinitionJson), &def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex(&def)
+	glob := func(pattern string) *Pattern {
+		p, err := NewGlobPattern(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	regex := func(pattern string) *Pattern {
+		p, err := NewRegexPattern(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := map[string]struct {
+		filter     Filter
+		methods    []string
+		attributes []string
+		types      []string
+	}{
+		"everything": {
+			methods:    []string{"Mower.GetMode", "Mower.SetMode", "Blade.GetSpeed"},
+			attributes: []string{"Mower.Mode", "Mower.Events", "Blade.Speed"},
+			types:      []string{"tMode", "tSpeed"},
+		},
+		"family glob": {
+			filter:     Filter{Family: glob("mow*")},
+			methods:    []string{"Mower.GetMode", "Mower.SetMode"},
+			attributes: []string{"Mower.Mode", "Mower.Events"},
+		},
+		"name glob": {
+			filter:     Filter{Name: glob("*Speed")},
+			methods:    []string{"Blade.GetSpeed"},
+			attributes: []string{"Blade.Speed"},
+			types:      []string{"tSpeed"},
+		},
+		"full name glob": {
+			filter:  Filter{Name: glob("Mower.?et*")},
+			methods: []string{"Mower.GetMode", "Mower.SetMode"},
+		},
+		"name regex": {
+			filter:  Filter{Name: regex("^(get|set)mode$")},
+			methods: []string{"Mower.GetMode", "Mower.SetMode"},
+		},
+		"tag": {
+			filter:     Filter{Tag: "status"},
+			methods:    []string{"Mower.GetMode", "Blade.GetSpeed"},
+			attributes: []string{"Mower.Mode"},
+		},
+		"write operation": {
+			filter:     Filter{Operation: "write"},
+			attributes: []string{"Mower.Mode"},
+		},
+		"list operation": {
+			filter:     Filter{Operation: "list"},
+			attributes: []string{"Mower.Events"},
+		},
+		"login level": {
+			filter:     Filter{LoginLevel: "operator"},
+			methods:    []string{"Mower.GetMode", "Blade.GetSpeed"},
+			attributes: []string{"Mower.Mode", "Mower.Events", "Blade.Speed"},
+		},
+		"tag and login level": {
+			filter:  Filter{Tag: "STATUS", LoginLevel: "Service"},
+			methods: []string{"Blade.GetSpeed"},
+		},
+		"search descriptions": {
+			filter:  Filter{Search: "OPERATING mode"},
+			methods: []string{"Mower.GetMode"},
+		},
+		"search parameters and enumerators": {
+			filter: Filter{Search: "park"},
+			types:  []string{"tMode"},
+		},
+		"search rpm": {
+			filter:  Filter{Search: "rpm"},
+			methods: []string{"Blade.GetSpeed"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var methods, attributes, types []string
+			for _, method := range test.filter.Methods(idx) {
+				methods = append(methods, method.Name())
+			}
+			for _, attr := range test.filter.Attributes(idx) {
+				attributes = append(attributes, attr.FullName())
+			}
+			for _, typ := range test.filter.Types(idx) {
+				types = append(types, typ.Name)
+			}
+			if diff := cmp.Diff(test.methods, methods); diff != "" {
+				t.Errorf("methods mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.attributes, attributes); diff != "" {
+				t.Errorf("attributes mismatch (-expected +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.types, types); diff != "" {
+				t.Errorf("types mismatch (-expected +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := NewGlobPattern("[a-"); err == nil {
+		t.Error("expected an error for an invalid glob")
+	}
+	if _, err := NewRegexPattern("(a"); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
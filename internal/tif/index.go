@@ -0,0 +1,323 @@
+package tif
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version of the index cache format, bumped whenever TifDefinition or indexData changes
+// so that caches written by older versions are rebuilt instead of decoded wrongly.
+const indexCacheVersion = 3
+
+// Number of index caches kept, one per version of a definition that was loaded.
+const maxIndexCaches = 16
+
+// Index is a definition with its methods, attributes and types looked up by name, and its
+// methods and attributes by tag and login level. Names, tags and login levels are matched ignoring case.
+// Methods and attributes without login levels may be used at any login level.
+type Index struct {
+	data indexData
+}
+
+// indexData is what an index cache holds. Elements are referred to by their position in the definition.
+type indexData struct {
+	Definition      *TifDefinition
+	Methods         map[string]int
+	Attributes      map[string]int
+	Types           map[string]int
+	MethodTags      map[string][]int
+	AttributeTags   map[string][]int
+	MethodLevels    map[string][]int
+	AttributeLevels map[string][]int
+	// Methods and attributes without login levels
+	UnrestrictedMethods    []int
+	UnrestrictedAttributes []int
+}
+
+// NewIndex indexes def. Like lookups in the definition, the first of elements with the same name is used.
+func NewIndex(def *TifDefinition) *Index {
+	data := indexData{
+		Definition:      def,
+		Methods:         make(map[string]int, len(def.Methods)),
+		Attributes:      make(map[string]int, len(def.AttributesV2)),
+		Types:           make(map[string]int, len(def.TypesV2)),
+		MethodTags:      make(map[string][]int),
+		AttributeTags:   make(map[string][]int),
+		MethodLevels:    make(map[string][]int),
+		AttributeLevels: make(map[string][]int),
+	}
+
+	for i, method := range def.Methods {
+		key := strings.ToLower(method.Name())
+		if _, ok := data.Methods[key]; ok {
+			continue
+		}
+		data.Methods[key] = i
+		addKeys(data.MethodTags, method.Tags, i)
+		addKeys(data.MethodLevels, method.LoginLevels, i)
+		if len(method.LoginLevels) == 0 {
+			data.UnrestrictedMethods = append(data.UnrestrictedMethods, i)
+		}
+	}
+	for i, attr := range def.AttributesV2 {
+		key := strings.ToLower(attr.FullName())
+		if _, ok := data.Attributes[key]; ok {
+			continue
+		}
+		data.Attributes[key] = i
+		addKeys(data.AttributeTags, attr.Tags, i)
+		addKeys(data.AttributeLevels, attr.Protocol.Read.LoginLevels, i)
+		if len(attr.Protocol.Read.LoginLevels) == 0 {
+			data.UnrestrictedAttributes = append(data.UnrestrictedAttributes, i)
+		}
+	}
+	for i, t := range def.TypesV2 {
+		// Types are looked up by their exact name, as parameters refer to them
+		if _, ok := data.Types[t.Name]; !ok {
+			data.Types[t.Name] = i
+		}
+	}
+	return &Index{data: data}
+}
+
+func addKeys(index map[string][]int, keys []string, i int) {
+	for _, key := range keys {
+		key = strings.ToLower(key)
+		positions := index[key]
+		// Definitions may repeat a tag or login level of an element
+		if len(positions) > 0 && positions[len(positions)-1] == i {
+			continue
+		}
+		index[key] = append(positions, i)
+	}
+}
+
+// Definition returns the indexed definition.
+func (idx *Index) Definition() *TifDefinition {
+	return idx.data.Definition
+}
+
+// Method returns the method with the given Family.Command name.
+func (idx *Index) Method(name string) (MethodDefinition, bool) {
+	i, ok := idx.data.Methods[strings.ToLower(name)]
+	if !ok {
+		return MethodDefinition{}, false
+	}
+	return idx.data.Definition.Methods[i], true
+}
+
+// Attribute returns the attribute with the given Family.Name name.
+func (idx *Index) Attribute(name string) (AttributeV2Definition, bool) {
+	i, ok := idx.data.Attributes[strings.ToLower(name)]
+	if !ok {
+		return AttributeV2Definition{}, false
+	}
+	return idx.data.Definition.AttributesV2[i], true
+}
+
+// Type returns the types-v2 type with the given name.
+func (idx *Index) Type(name string) (TypeV2Definition, bool) {
+	i, ok := idx.data.Types[name]
+	if !ok {
+		return TypeV2Definition{}, false
+	}
+	return idx.data.Definition.TypesV2[i], true
+}
+
+// MethodsWithTag returns the methods tagged with tag, in the order they are defined.
+func (idx *Index) MethodsWithTag(tag string) []MethodDefinition {
+	return elementsAt(idx.data.Definition.Methods, idx.data.MethodTags[strings.ToLower(tag)])
+}
+
+// AttributesWithTag returns the attributes tagged with tag, in the order they are defined.
+func (idx *Index) AttributesWithTag(tag string) []AttributeV2Definition {
+	return elementsAt(idx.data.Definition.AttributesV2, idx.data.AttributeTags[strings.ToLower(tag)])
+}
+
+// MethodsWithLoginLevel returns the methods that may be called at the given login level, in the order they are defined.
+func (idx *Index) MethodsWithLoginLevel(level string) []MethodDefinition {
+	return elementsAt(idx.data.Definition.Methods, idx.methodsAt("", level))
+}
+
+// AttributesWithLoginLevel returns the attributes that may be read at the given login level, in the order they are defined.
+func (idx *Index) AttributesWithLoginLevel(level string) []AttributeV2Definition {
+	return elementsAt(idx.data.Definition.AttributesV2, idx.attributesAt("", level))
+}
+
+// methodsAt returns the positions of the methods with tag that may be called at level.
+// An empty tag or level selects methods regardless of their tags or login levels.
+func (idx *Index) methodsAt(tag, level string) []int {
+	return selectPositions(len(idx.data.Definition.Methods), idx.data.MethodTags, tag, idx.data.MethodLevels, idx.data.UnrestrictedMethods, level)
+}
+
+// attributesAt returns the positions of the attributes with tag that may be read at level, like methodsAt.
+func (idx *Index) attributesAt(tag, level string) []int {
+	return selectPositions(len(idx.data.Definition.AttributesV2), idx.data.AttributeTags, tag, idx.data.AttributeLevels, idx.data.UnrestrictedAttributes, level)
+}
+
+// selectPositions returns the positions, out of count elements, of the elements with tag that may be
+// used at level: those restricted to level and the unrestricted ones. Positions are in ascending order.
+func selectPositions(count int, tags map[string][]int, tag string, levels map[string][]int, unrestricted []int, level string) []int {
+	var positions []int
+	if tag == "" && level == "" {
+		positions = make([]int, count)
+		for i := range positions {
+			positions[i] = i
+		}
+		return positions
+	}
+
+	if tag != "" {
+		positions = tags[strings.ToLower(tag)]
+	}
+	if level != "" {
+		allowed := append(slices.Clone(levels[strings.ToLower(level)]), unrestricted...)
+		slices.Sort(allowed)
+		if tag == "" {
+			return allowed
+		}
+		positions = slices.DeleteFunc(slices.Clone(positions), func(i int) bool {
+			_, found := slices.BinarySearch(allowed, i)
+			return !found
+		})
+	}
+	return positions
+}
+
+func elementsAt[T any](elements []T, positions []int) []T {
+	found := make([]T, len(positions))
+	for i, position := range positions {
+		found[i] = elements[position]
+	}
+	return found
+}
+
+// LoadIndex reads and indexes the definition at path. When cacheDir is not empty, the index is
+// cached there in a binary file named after the hash of the definition, and read from there as long
+// as the definition is unchanged. The returned bool reports whether the index came from the cache.
+//
+// Failing to read or write the cache is not an error, the definition is then decoded as usual.
+func LoadIndex(path, cacheDir string) (*Index, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var cachePath string
+	if cacheDir != "" {
+		hash := sha256.Sum256(data)
+		cachePath = filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".tifidx")
+		if idx, err := readIndexCache(cachePath); err == nil {
+			return idx, true, nil
+		}
+	}
+
+	var def TifDefinition
+	err = json.Unmarshal(data, &def)
+	if err != nil {
+		return nil, false, err
+	}
+	idx := NewIndex(&def)
+
+	if cachePath != "" {
+		// The cache only makes later loads faster, so failing to write it does not fail this one
+		if writeIndexCache(cachePath, idx) == nil {
+			pruneIndexCaches(cacheDir)
+		}
+	}
+	return idx, false, nil
+}
+
+func readIndexCache(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+	var version int
+	err = decoder.Decode(&version)
+	if err != nil {
+		return nil, err
+	}
+	if version != indexCacheVersion {
+		return nil, fmt.Errorf("index cache %s has version %d; expected %d", path, version, indexCacheVersion)
+	}
+
+	var data indexData
+	err = decoder.Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Definition == nil {
+		return nil, errors.New("index cache has no definition")
+	}
+	return &Index{data: data}, nil
+}
+
+// writeIndexCache writes the cache to a temporary file first, so that a concurrent
+// load never reads a partially written cache.
+func writeIndexCache(path string, idx *Index) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err = encoder.Encode(indexCacheVersion)
+	if err != nil {
+		return err
+	}
+	err = encoder.Encode(idx.data)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(buf.Bytes())
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// pruneIndexCaches removes the least recently written caches beyond maxIndexCaches.
+func pruneIndexCaches(cacheDir string) {
+	paths, err := filepath.Glob(filepath.Join(cacheDir, "*.tifidx"))
+	if err != nil || len(paths) <= maxIndexCaches {
+		return
+	}
+
+	written := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			written[path] = info.ModTime()
+		}
+	}
+	slices.SortFunc(paths, func(a, b string) int { return written[b].Compare(written[a]) })
+	for _, path := range paths[maxIndexCaches:] {
+		os.Remove(path)
+	}
+}
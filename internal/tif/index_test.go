@@ -0,0 +1,147 @@
+package tif
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const indexTestDefinitionJson = `{
+  "header": { "tifVersions": ["1.0"], "protocolName": "RoboticsProtocol2" },
+  "methods": [
+    { "family": "Mower", "command": "GetMode", "tags": ["status"], "loginLevels": ["Operator", "Service"] },
+    { "family": "Mower", "command": "SetMode", "tags": ["control", "Control"], "loginLevels": ["Service"] },
+    { "family": "mower", "command": "getMode", "tags": ["shadowed"] },
+    { "family": "Blade", "command": "GetSpeed" }
+  ],
+  "attributes-v2": [
+    {
+      "family": "Mower",
+      "name": "Mode",
+      "tags": ["status"],
+      "protocol": { "read": { "loginLevels": ["Operator"] } }
+    }
+  ],
+  "types-v2": [
+    { "name": "tMode", "type": "uint8", "description": "first" },
+    { "name": "tMode", "type": "uint16", "description": "second" }
+  ]
+}`
+
+func TestIndexLookups(t *testing.T) {
+	t.Parallel()
+
+	var def TifDefinition
+	err := json.Unmarshal([]byte(indexTestDefinitionJson), &def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex(&def)
+
+	method, ok := idx.Method("mower.getmode")
+	if !ok || method.Command != "GetMode" {
+		t.Errorf("expected mower.getmode to find the first GetMode but got %v, %t", method.Name(), ok)
+	}
+	if _, ok := idx.Method("Mower.Missing"); ok {
+		t.Error("expected Mower.Missing not to be found")
+	}
+	if attr, ok := idx.Attribute("MOWER.MODE"); !ok || attr.Name != "Mode" {
+		t.Errorf("expected MOWER.MODE to find Mower.Mode but got %v, %t", attr.FullName(), ok)
+	}
+	if typ, ok := idx.Type("tMode"); !ok || typ.Description != "first" {
+		t.Errorf("expected the first tMode but got %+v, %t", typ, ok)
+	}
+
+	names := func(methods []MethodDefinition) []string {
+		found := []string{}
+		for _, method := range methods {
+			found = append(found, method.Name())
+		}
+		return found
+	}
+	tests := map[string]struct {
+		got      []string
+		expected []string
+	}{
+		"tag":                    {got: names(idx.MethodsWithTag("STATUS")), expected: []string{"Mower.GetMode"}},
+		"repeated tag":           {got: names(idx.MethodsWithTag("control")), expected: []string{"Mower.SetMode"}},
+		"tag of shadowed method": {got: names(idx.MethodsWithTag("shadowed")), expected: []string{}},
+		"login level":            {got: names(idx.MethodsWithLoginLevel("service")), expected: []string{"Mower.GetMode", "Mower.SetMode", "Blade.GetSpeed"}},
+		"unknown login level":    {got: names(idx.MethodsWithLoginLevel("Admin")), expected: []string{"Blade.GetSpeed"}},
+	}
+	for name, test := range tests {
+		if diff := cmp.Diff(test.expected, test.got); diff != "" {
+			t.Errorf("%s mismatch (-expected +got):\n%s", name, diff)
+		}
+	}
+
+	if attrs := idx.AttributesWithTag("status"); len(attrs) != 1 {
+		t.Errorf("expected 1 attribute tagged status but got %d", len(attrs))
+	}
+	if attrs := idx.AttributesWithLoginLevel("operator"); len(attrs) != 1 {
+		t.Errorf("expected 1 attribute readable by operator but got %d", len(attrs))
+	}
+}
+
+func TestLoadIndexCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	path := filepath.Join(dir, "def.json")
+	err := os.WriteFile(path, []byte(indexTestDefinitionJson), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, cached, err := LoadIndex(path, cacheDir)
+	if err != nil || cached {
+		t.Fatalf("expected the first load to decode the definition but got cached %t, %v", cached, err)
+	}
+	fromCache, cached, err := LoadIndex(path, cacheDir)
+	if err != nil || !cached {
+		t.Fatalf("expected the second load to read the cache but got cached %t, %v", cached, err)
+	}
+	if diff := cmp.Diff(decoded.data, fromCache.data); diff != "" {
+		t.Errorf("cached index mismatch (-decoded +cached):\n%s", diff)
+	}
+	if method, ok := fromCache.Method("Mower.SetMode"); !ok || method.LoginLevels[0] != "Service" {
+		t.Errorf("expected cached index to find Mower.SetMode but got %+v, %t", method, ok)
+	}
+
+	// A changed definition has a different hash, so it must not be read from the cache
+	err = os.WriteFile(path, []byte(`{"methods": [{ "family": "Blade", "command": "Start" }]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, cached, err := LoadIndex(path, cacheDir)
+	if err != nil || cached {
+		t.Fatalf("expected the changed definition to be decoded but got cached %t, %v", cached, err)
+	}
+	if _, ok := changed.Method("Blade.Start"); !ok {
+		t.Error("expected the changed definition to have Blade.Start")
+	}
+
+	// Corrupt caches are rebuilt
+	caches, err := filepath.Glob(filepath.Join(cacheDir, "*.tifidx"))
+	if err != nil || len(caches) != 2 {
+		t.Fatalf("expected 2 caches but got %v, %v", caches, err)
+	}
+	for _, cache := range caches {
+		err = os.WriteFile(cache, []byte("not a cache"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, cached, err = LoadIndex(path, cacheDir)
+	if err != nil || cached {
+		t.Fatalf("expected a corrupt cache to be rebuilt but got cached %t, %v", cached, err)
+	}
+	_, cached, err = LoadIndex(path, cacheDir)
+	if err != nil || !cached {
+		t.Fatalf("expected the rebuilt cache to be read but got cached %t, %v", cached, err)
+	}
+}
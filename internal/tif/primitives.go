@@ -1,9 +1,14 @@
 package tif
 
 import (
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Todo: use time.Time instead
@@ -11,7 +16,7 @@ type TypeUnixTime int32
 type TUnixTime struct{}
 
 func (t *TUnixTime) ParseString(v string) (any, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseInt(v, base, 32)
 	if err != nil {
 		return nil, err
@@ -109,24 +114,47 @@ type TypeFloat float32
 type TFloat struct{}
 
 func (t *TFloat) ParseString(v string) (any, error) {
-	val, err := strconv.ParseFloat(v, 32)
-	return TypeFloat(val), err
+	return parseFloat(v)
 }
 
+// TypeBit is a byte of bits, written in binary with the most significant bit first.
 type TypeBit byte
 type TBit struct{}
 
 func (t *TBit) ParseString(v string) (any, error) {
-	val, err := strconv.ParseUint(v, 2, 8)
-	return TypeBit(val), err
+	return parseBit(v)
+}
+
+func (v TypeBit) String() string {
+	return fmt.Sprintf("%08b", byte(v))
 }
 
+// TypeRoboticsVersion is a version with the major version in its high byte and the minor in its low byte.
 type TypeRoboticsVersion uint16
 type TRoboticsVersion struct{}
 
 func (t *TRoboticsVersion) ParseString(v string) (any, error) {
-	val, err := parseInt16(v)
-	return TypeRoboticsVersion(val), err
+	return parseRoboticsVersion(v)
+}
+
+func (v TypeRoboticsVersion) String() string {
+	return fmt.Sprintf("%d.%d", v>>8, v&0xFF)
+}
+
+// Layout dateTime values are written in. They have no time zone, the device decides what they are relative to.
+const DateTimeLayout = "2006-01-02T15:04:05"
+
+// TypeDateTime is a date and time to the second, sent as a little endian
+// uint16 year followed by a byte each for month, day, hour, minute and second.
+type TypeDateTime time.Time
+type TDateTime struct{}
+
+func (t *TDateTime) ParseString(v string) (any, error) {
+	return parseDateTime(v)
+}
+
+func (v TypeDateTime) String() string {
+	return time.Time(v).Format(DateTimeLayout)
 }
 
 type ErrUnkownType struct {
@@ -137,61 +165,180 @@ func (e *ErrUnkownType) Error() string {
 	return "unkown type passed to [ParseType]; passed type: " + e.Type
 }
 
-func ParseType(tifType, data string) (any, error) {
-	switch tifType {
-	case "tUnixTime":
-		base := getValueBase(data)
-		integer, err := strconv.ParseInt(data, base, 32)
-		if err != nil {
-			return nil, err
+// primitive describes how values of a primitive type are parsed, encoded and decoded.
+type primitive struct {
+	parser TPrimitiveStringer
+	// Zero value of the type of parsed values
+	value any
+	// Size of encoded values in bytes. Strings and arrays have no fixed size,
+	// their size depends on their length and they are not read or appended by the table.
+	width  int
+	read   func(data []byte) any
+	append func(buf []byte, value any) ([]byte, error)
+	// Converts an integer, such as the value of an enumerator, to the primitive.
+	// Nil for primitives that are not integers, false if the integer does not fit.
+	fromInt func(v int64) (any, bool)
+}
+
+// primitives are the primitive types by name, which types-v2 types are ultimately encoded as.
+var primitives = map[string]primitive{
+	"uint8": {parser: &TUint8{}, value: TypeUint8(0), width: 1,
+		read:    func(data []byte) any { return TypeUint8(data[0]) },
+		append:  func(buf []byte, v any) ([]byte, error) { return append(buf, byte(v.(TypeUint8))), nil },
+		fromInt: fromInt[TypeUint8](0, math.MaxUint8)},
+	"uint16": {parser: &TUint16{}, value: TypeUint16(0), width: 2,
+		read: func(data []byte) any { return TypeUint16(binary.LittleEndian.Uint16(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint16(buf, uint16(v.(TypeUint16))), nil
+		},
+		fromInt: fromInt[TypeUint16](0, math.MaxUint16)},
+	"uint32": {parser: &TUint32{}, value: TypeUint32(0), width: 4,
+		read: func(data []byte) any { return TypeUint32(binary.LittleEndian.Uint32(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint32(buf, uint32(v.(TypeUint32))), nil
+		},
+		fromInt: fromInt[TypeUint32](0, math.MaxUint32)},
+	"uint64": {parser: &TUint64{}, value: TypeUint64(0), width: 8,
+		read: func(data []byte) any { return TypeUint64(binary.LittleEndian.Uint64(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint64(buf, uint64(v.(TypeUint64))), nil
+		},
+		fromInt: fromInt[TypeUint64](0, math.MaxInt64)},
+	"sint8": {parser: &TInt8{}, value: TypeInt8(0), width: 1,
+		read:    func(data []byte) any { return TypeInt8(data[0]) },
+		append:  func(buf []byte, v any) ([]byte, error) { return append(buf, byte(v.(TypeInt8))), nil },
+		fromInt: fromInt[TypeInt8](math.MinInt8, math.MaxInt8)},
+	"sint16": {parser: &TInt16{}, value: TypeInt16(0), width: 2,
+		read: func(data []byte) any { return TypeInt16(binary.LittleEndian.Uint16(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint16(buf, uint16(v.(TypeInt16))), nil
+		},
+		fromInt: fromInt[TypeInt16](math.MinInt16, math.MaxInt16)},
+	"sint32": {parser: &TInt32{}, value: TypeInt32(0), width: 4,
+		read: func(data []byte) any { return TypeInt32(binary.LittleEndian.Uint32(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint32(buf, uint32(v.(TypeInt32))), nil
+		},
+		fromInt: fromInt[TypeInt32](math.MinInt32, math.MaxInt32)},
+	"sint64": {parser: &TInt64{}, value: TypeInt64(0), width: 8,
+		read: func(data []byte) any { return TypeInt64(binary.LittleEndian.Uint64(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint64(buf, uint64(v.(TypeInt64))), nil
+		},
+		fromInt: fromInt[TypeInt64](math.MinInt64, math.MaxInt64)},
+	"bool": {parser: &TBool{}, value: TypeBool(false), width: 1,
+		read: func(data []byte) any { return TypeBool(data[0] != 0) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			if v.(TypeBool) {
+				return append(buf, 0x01), nil
+			}
+			return append(buf, 0x00), nil
+		}},
+	"bit": {parser: &TBit{}, value: TypeBit(0), width: 1,
+		read:    func(data []byte) any { return TypeBit(data[0]) },
+		append:  func(buf []byte, v any) ([]byte, error) { return append(buf, byte(v.(TypeBit))), nil },
+		fromInt: fromInt[TypeBit](0, math.MaxUint8)},
+	"float": {parser: &TFloat{}, value: TypeFloat(0), width: 4,
+		read: func(data []byte) any { return TypeFloat(math.Float32frombits(binary.LittleEndian.Uint32(data))) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v.(TypeFloat)))), nil
+		}},
+	"tUnixTime": {parser: &TUnixTime{}, value: TypeUnixTime(0), width: 4,
+		read: func(data []byte) any { return TypeUnixTime(binary.LittleEndian.Uint32(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint32(buf, uint32(v.(TypeUnixTime))), nil
+		},
+		fromInt: fromInt[TypeUnixTime](math.MinInt32, math.MaxInt32)},
+	"tSimpleVersion": {parser: &TRoboticsVersion{}, value: TypeRoboticsVersion(0), width: 2,
+		read: func(data []byte) any { return TypeRoboticsVersion(binary.LittleEndian.Uint16(data)) },
+		append: func(buf []byte, v any) ([]byte, error) {
+			return binary.LittleEndian.AppendUint16(buf, uint16(v.(TypeRoboticsVersion))), nil
+		},
+		fromInt: fromInt[TypeRoboticsVersion](0, math.MaxUint16)},
+	"dateTime": {parser: &TDateTime{}, value: TypeDateTime{}, width: 7,
+		read: func(data []byte) any {
+			year := int(binary.LittleEndian.Uint16(data))
+			return TypeDateTime(time.Date(year, time.Month(data[2]), int(data[3]), int(data[4]), int(data[5]), int(data[6]), 0, time.UTC))
+		},
+		append: func(buf []byte, v any) ([]byte, error) {
+			t := time.Time(v.(TypeDateTime))
+			if t.Year() < 0 || t.Year() > math.MaxUint16 {
+				return nil, fmt.Errorf("year %d does not fit in a dateTime", t.Year())
+			}
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(t.Year()))
+			return append(buf, byte(t.Month()), byte(t.Day()), byte(t.Hour()), byte(t.Minute()), byte(t.Second())), nil
+		}},
+	"ascii":     {parser: &TAscii{}, value: TypeAscii("")},
+	"tUCS2":     {parser: &TUCS2{}, value: TypeUCS2("")},
+	"byteArray": {parser: &TByteArray{}, value: TypeByteArray(nil)},
+}
+
+// fromInt returns a conversion of integers from min to max to T.
+func fromInt[T ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~int8 | ~int16 | ~int32 | ~int64](min, max int64) func(int64) (any, bool) {
+	return func(v int64) (any, bool) {
+		if v < min || v > max {
+			return nil, false
 		}
-		return TypeUnixTime(integer), nil
-	case "ascii":
-		return TypeAscii(data), nil
-	case "byteArray":
-		val, err := hex.DecodeString(data)
-		return TypeByteArray(val), err
-	case "bool":
-		val, err := strconv.ParseBool(data)
-		return TypeBool(val), err
-	case "uint8":
-		return parseUint8(data)
-	case "uint16":
-		return parseUint16(data)
-	case "uint32":
-		return parseUint32(data)
-	case "uint64":
-		return parseUint64(data)
-	case "sint8":
-		return parseInt8(data)
-	case "sint16":
-		return parseInt16(data)
-	case "sint32":
-		return parseInt32(data)
-	case "sint64":
-		return parseInt64(data)
-	case "tUCS2":
-		return TypeUCS2(data), nil
-	case "tSimpleVersion":
-		val, err := parseInt16(data)
-		return TypeRoboticsVersion(val), err
-	default:
+		return T(v), true
+	}
+}
+
+// newPrimitive returns the parser of a primitive type.
+func newPrimitive(name string) (TPrimitiveStringer, bool) {
+	p, ok := primitives[name]
+	return p.parser, ok
+}
+
+// primitiveName returns the name of the primitive type parsed by parser.
+func primitiveName(parser TPrimitiveStringer) string {
+	for name, p := range primitives {
+		if reflect.TypeOf(p.parser) == reflect.TypeOf(parser) {
+			return name
+		}
+	}
+	return ""
+}
+
+// primitiveOf returns the primitive type name of a value returned by ParseType.
+func primitiveOf(value any) (string, bool) {
+	for name, p := range primitives {
+		if reflect.TypeOf(p.value) == reflect.TypeOf(value) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func ParseType(tifType, data string) (any, error) {
+	primitive, ok := newPrimitive(tifType)
+	if !ok {
 		return nil, &ErrUnkownType{Type: tifType}
-		// case "bit":
-		// case "dateTime":
-		// case "float":
+	}
+	return primitive.ParseString(data)
+}
+
+// FormatType writes a value returned by ParseType the way ParseType parses it.
+func FormatType(value any) string {
+	switch v := value.(type) {
+	case TypeByteArray:
+		return strings.ToUpper(hex.EncodeToString(v))
+	case TypeFloat:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return fmt.Sprint(v)
 	}
 }
 
-func getValueBase(v string) int {
-	if strings.HasPrefix(v, "0x") {
-		return 16
+// getValueBase returns the digits of v without its base prefix, and the base they are in.
+func getValueBase(v string) (string, int) {
+	if digits, ok := strings.CutPrefix(v, "0x"); ok {
+		return digits, 16
 	}
-	return 10
+	return v, 10
 }
 
 func parseUint8(v string) (TypeUint8, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseUint(v, base, 8)
 	if err != nil {
 		return 0, err
@@ -200,7 +347,7 @@ func parseUint8(v string) (TypeUint8, error) {
 }
 
 func parseUint16(v string) (TypeUint16, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseUint(v, base, 16)
 	if err != nil {
 		return 0, err
@@ -209,7 +356,7 @@ func parseUint16(v string) (TypeUint16, error) {
 }
 
 func parseUint32(v string) (TypeUint32, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseUint(v, base, 32)
 	if err != nil {
 		return 0, err
@@ -218,7 +365,7 @@ func parseUint32(v string) (TypeUint32, error) {
 }
 
 func parseUint64(v string) (TypeUint64, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseUint(v, base, 64)
 	if err != nil {
 		return 0, err
@@ -227,7 +374,7 @@ func parseUint64(v string) (TypeUint64, error) {
 }
 
 func parseInt8(v string) (TypeInt8, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseInt(v, base, 8)
 	if err != nil {
 		return 0, err
@@ -236,7 +383,7 @@ func parseInt8(v string) (TypeInt8, error) {
 }
 
 func parseInt16(v string) (TypeInt16, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseInt(v, base, 16)
 	if err != nil {
 		return 0, err
@@ -245,7 +392,7 @@ func parseInt16(v string) (TypeInt16, error) {
 }
 
 func parseInt32(v string) (TypeInt32, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseInt(v, base, 32)
 	if err != nil {
 		return 0, err
@@ -254,10 +401,54 @@ func parseInt32(v string) (TypeInt32, error) {
 }
 
 func parseInt64(v string) (TypeInt64, error) {
-	base := getValueBase(v)
+	v, base := getValueBase(v)
 	integer, err := strconv.ParseInt(v, base, 64)
 	if err != nil {
 		return 0, err
 	}
 	return TypeInt64(integer), nil
 }
+
+func parseFloat(v string) (TypeFloat, error) {
+	val, err := strconv.ParseFloat(v, 32)
+	if err != nil {
+		return 0, err
+	}
+	return TypeFloat(val), nil
+}
+
+// parseBit parses up to eight binary digits, with or without a 0b prefix.
+func parseBit(v string) (TypeBit, error) {
+	val, err := strconv.ParseUint(strings.TrimPrefix(v, "0b"), 2, 8)
+	if err != nil {
+		return 0, err
+	}
+	return TypeBit(val), nil
+}
+
+// parseRoboticsVersion parses a version written as major.minor, or as its uint16 value.
+func parseRoboticsVersion(v string) (TypeRoboticsVersion, error) {
+	majorStr, minorStr, isDotted := strings.Cut(v, ".")
+	if !isDotted {
+		val, err := parseUint16(v)
+		return TypeRoboticsVersion(val), err
+	}
+
+	major, err := strconv.ParseUint(majorStr, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid major version: %w", err)
+	}
+	minor, err := strconv.ParseUint(minorStr, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid minor version: %w", err)
+	}
+	return TypeRoboticsVersion(major<<8 | minor), nil
+}
+
+func parseDateTime(v string) (TypeDateTime, error) {
+	val, err := time.Parse(DateTimeLayout, v)
+	if err != nil {
+		return TypeDateTime{}, err
+	}
+	return TypeDateTime(val), nil
+}
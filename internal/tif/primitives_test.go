@@ -0,0 +1,179 @@
+package tif
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+	"unicode/utf16"
+)
+
+// roundTrip checks that value survives being formatted and parsed, and being encoded and decoded.
+func roundTrip(t *testing.T, primitive string, length int, hasLength bool, value any) bool {
+	t.Helper()
+
+	formatted := FormatType(value)
+	parsed, err := ParseType(primitive, formatted)
+	if err != nil {
+		t.Errorf("ParseType(%s, %q) returned %v; expected no error", primitive, formatted, err)
+		return false
+	}
+	if !equalValues(value, parsed) {
+		t.Errorf("%s value %#v was parsed back as %#v from %q", primitive, value, parsed, formatted)
+		return false
+	}
+
+	encoded, err := appendValue(nil, "value", primitive, length, hasLength, value)
+	if err != nil {
+		t.Errorf("failed to encode %s value %#v: %v", primitive, value, err)
+		return false
+	}
+	decoded, n, err := readValue(encoded, primitive, length, hasLength)
+	if err != nil {
+		t.Errorf("failed to decode %s value % X: %v", primitive, encoded, err)
+		return false
+	}
+	if n != len(encoded) || !equalValues(value, decoded) {
+		t.Errorf("%s value %#v was decoded back as %#v using %d of %d bytes", primitive, value, decoded, n, len(encoded))
+		return false
+	}
+	return true
+}
+
+func equalValues(a, b any) bool {
+	if aTime, ok := a.(TypeDateTime); ok {
+		bTime, ok := b.(TypeDateTime)
+		return ok && time.Time(aTime).Equal(time.Time(bTime))
+	}
+	if aBytes, ok := a.(TypeByteArray); ok {
+		bBytes, ok := b.(TypeByteArray)
+		return ok && bytes.Equal(aBytes, bBytes)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// withoutNulls replaces null characters, which end strings without a length.
+func withoutNulls(s string) string {
+	return strings.ReplaceAll(s, "\x00", " ")
+}
+
+// roundTripProperties returns a property for testing/quick of each primitive, reporting failures to t.
+func roundTripProperties(t *testing.T) map[string]any {
+	return map[string]any{
+		"uint8":          func(v uint8) bool { return roundTrip(t, "uint8", 0, false, TypeUint8(v)) },
+		"uint16":         func(v uint16) bool { return roundTrip(t, "uint16", 0, false, TypeUint16(v)) },
+		"uint32":         func(v uint32) bool { return roundTrip(t, "uint32", 0, false, TypeUint32(v)) },
+		"uint64":         func(v uint64) bool { return roundTrip(t, "uint64", 0, false, TypeUint64(v)) },
+		"sint8":          func(v int8) bool { return roundTrip(t, "sint8", 0, false, TypeInt8(v)) },
+		"sint16":         func(v int16) bool { return roundTrip(t, "sint16", 0, false, TypeInt16(v)) },
+		"sint32":         func(v int32) bool { return roundTrip(t, "sint32", 0, false, TypeInt32(v)) },
+		"sint64":         func(v int64) bool { return roundTrip(t, "sint64", 0, false, TypeInt64(v)) },
+		"bool":           func(v bool) bool { return roundTrip(t, "bool", 0, false, TypeBool(v)) },
+		"tUnixTime":      func(v int32) bool { return roundTrip(t, "tUnixTime", 0, false, TypeUnixTime(v)) },
+		"tSimpleVersion": func(v uint16) bool { return roundTrip(t, "tSimpleVersion", 0, false, TypeRoboticsVersion(v)) },
+		"float":          func(v float32) bool { return roundTrip(t, "float", 0, false, TypeFloat(v)) },
+		"bit":            func(v uint8) bool { return roundTrip(t, "bit", 0, false, TypeBit(v)) },
+		"dateTime": func(v uint32) bool {
+			return roundTrip(t, "dateTime", 0, false, TypeDateTime(time.Unix(int64(v), 0).UTC()))
+		},
+		"ascii": func(v []byte) bool {
+			for i := range v {
+				v[i] = ' ' + v[i]%('~'-' ')
+			}
+			return roundTrip(t, "ascii", 0, false, TypeAscii(v))
+		},
+		"ascii array": func(v []byte) bool {
+			v = v[:min(len(v), 16)]
+			for i := range v {
+				v[i] = ' ' + v[i]%('~'-' ')
+			}
+			return roundTrip(t, "ascii", 16, true, TypeAscii(v))
+		},
+		"tUCS2": func(v string) bool {
+			return roundTrip(t, "tUCS2", 0, false, TypeUCS2(withoutNulls(v)))
+		},
+		"tUCS2 array": func(v string) bool {
+			runes := []rune(withoutNulls(v))
+			for len(utf16.Encode(runes)) > 16 {
+				runes = runes[:len(runes)-1]
+			}
+			return roundTrip(t, "tUCS2", 16, true, TypeUCS2(runes))
+		},
+		"tUCS2 rest of payload": func(v string) bool {
+			return roundTrip(t, "tUCS2", LengthRemaining, true, TypeUCS2(withoutNulls(v)))
+		},
+		"byteArray": func(v []byte) bool {
+			return roundTrip(t, "byteArray", 0, false, TypeByteArray(v))
+		},
+	}
+}
+
+func TestPrimitiveRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for name := range roundTripProperties(t) {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := quick.Check(roundTripProperties(t)[name], nil)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestParseTypeFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		primitive string
+		value     string
+		expected  any
+	}{
+		"version as major.minor": {primitive: "tSimpleVersion", value: "1.2", expected: TypeRoboticsVersion(0x0102)},
+		"version above int16":    {primitive: "tSimpleVersion", value: "65535", expected: TypeRoboticsVersion(0xFFFF)},
+		"version in hex":         {primitive: "tSimpleVersion", value: "0x0A03", expected: TypeRoboticsVersion(0x0A03)},
+		"bit with prefix":        {primitive: "bit", value: "0b101", expected: TypeBit(5)},
+		"float":                  {primitive: "float", value: "-1.5", expected: TypeFloat(-1.5)},
+		"dateTime":               {primitive: "dateTime", value: "2024-05-17T13:45:00", expected: TypeDateTime(time.Date(2024, 5, 17, 13, 45, 0, 0, time.UTC))},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := ParseType(test.primitive, test.value)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if !equalValues(test.expected, value) {
+				t.Errorf("ParseType(%s, %s) returned %#v; expected %#v", test.primitive, test.value, value, test.expected)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"256.0", "1.256", "1.x"} {
+		_, err := ParseType("tSimpleVersion", invalid)
+		if err == nil {
+			t.Errorf("ParseType(tSimpleVersion, %s) returned no error", invalid)
+		}
+	}
+}
+
+func TestEncodeDateTime(t *testing.T) {
+	t.Parallel()
+
+	value := TypeDateTime(time.Date(2024, 5, 17, 13, 45, 30, 0, time.UTC))
+	encoded, err := appendValue(nil, "when", "dateTime", 0, false, value)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := []byte{0xE8, 0x07, 5, 17, 13, 45, 30}
+	if !reflect.DeepEqual(expected, encoded) {
+		t.Errorf("encoded % X; expected % X", encoded, expected)
+	}
+}
@@ -17,17 +17,20 @@ type TEnum struct {
 	Name        string
 	Description string
 	Primitive   TPrimitiveStringer
+	Postfix     string
 	Enumerators []TEnumEnumerator
 }
 
 type TEnumEnumerator struct {
 	Key         string
 	Description string
-	Value       any
+	// Value parsed as the primitive of the enum
+	Value any
 }
 
 type TSimple struct {
 	Name        string
 	Description string
 	Primitive   TPrimitiveStringer
+	Postfix     string
 }
@@ -0,0 +1,361 @@
+package tif
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Maximum depth of types-v2 types defined in terms of each other.
+const maxTypeDepth = 8
+
+type ErrOutOfRange struct {
+	Type string
+	// Value of the primitive of the type
+	Value any
+	Start int
+	Stop  int
+}
+
+func (e *ErrOutOfRange) Error() string {
+	return fmt.Sprintf("value %d is out of range for %s; expected %d to %d", e.Value, e.Type, e.Start, e.Stop)
+}
+
+type ErrNotEnumerator struct {
+	Type  string
+	Value int64
+}
+
+func (e *ErrNotEnumerator) Error() string {
+	return fmt.Sprintf("value %d is not an enumerator of %s", e.Value, e.Type)
+}
+
+// ErrEnumValue is returned when an enumerator of an enum type has a value its primitive can not hold.
+type ErrEnumValue struct {
+	Type      string
+	Key       string
+	Value     int
+	Primitive string
+}
+
+func (e *ErrEnumValue) Error() string {
+	return fmt.Sprintf("enumerator %s of %s has value %d, which is not a valid %s", e.Key, e.Type, e.Value, e.Primitive)
+}
+
+// ErrTypeDepth is returned when a types-v2 type is nested too deep to resolve, usually because it refers to itself.
+type ErrTypeDepth struct {
+	Type  string
+	Depth int
+}
+
+func (e *ErrTypeDepth) Error() string {
+	return fmt.Sprintf("type %s is nested more than %d levels deep", e.Type, e.Depth)
+}
+
+// ErrUnknownEnumerator is returned when a value of an enum type is neither a key nor a number.
+type ErrUnknownEnumerator struct {
+	Type string
+	Key  string
+	// Closest key of the enum, empty if no key is close
+	Suggestion string
+}
+
+func (e *ErrUnknownEnumerator) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("%s is not an enumerator of %s; did you mean %s?", e.Key, e.Type, e.Suggestion)
+	}
+	return fmt.Sprintf("%s is not an enumerator of %s", e.Key, e.Type)
+}
+
+// TypeRegistry holds the types-v2 types of a definition, resolved to a *TRange,
+// *TEnum or *TSimple linked to the primitive the type is encoded as.
+type TypeRegistry struct {
+	types map[string]TPrimitiveStringer
+	// Types that could not be resolved, such as types of an unknown primitive
+	broken map[string]error
+}
+
+// NewTypeRegistry resolves the types-v2 types of def. Types that can not be resolved
+// do not stop the others from resolving, their error is returned when they are looked up.
+func NewTypeRegistry(def *TifDefinition) *TypeRegistry {
+	definitions := make(map[string]TypeV2Definition, len(def.TypesV2))
+	for _, t := range def.TypesV2 {
+		// Like methods, the first of types with the same name is used
+		if _, ok := definitions[t.Name]; !ok {
+			definitions[t.Name] = t
+		}
+	}
+
+	registry := &TypeRegistry{
+		types:  make(map[string]TPrimitiveStringer, len(definitions)),
+		broken: make(map[string]error),
+	}
+	for name, t := range definitions {
+		primitive, err := resolvePrimitive(definitions, name)
+		if err != nil {
+			registry.broken[name] = err
+			continue
+		}
+		named, err := newNamedType(t, primitive)
+		if err != nil {
+			registry.broken[name] = err
+			continue
+		}
+		registry.types[name] = named
+	}
+	return registry
+}
+
+// resolvePrimitive follows a type, which may be defined in terms of other types-v2 types, to its primitive.
+func resolvePrimitive(definitions map[string]TypeV2Definition, typeName string) (TPrimitiveStringer, error) {
+	name := typeName
+	for range maxTypeDepth {
+		t, ok := definitions[name]
+		if !ok {
+			primitive, ok := newPrimitive(name)
+			if !ok {
+				return nil, &ErrUnkownType{Type: name}
+			}
+			return primitive, nil
+		}
+		name = t.Type
+	}
+	return nil, &ErrTypeDepth{Type: typeName, Depth: maxTypeDepth}
+}
+
+// newNamedType links a types-v2 type to its primitive. The values of enumerators are converted
+// to the primitive, so that they compare equal to parsed and decoded values.
+func newNamedType(t TypeV2Definition, primitive TPrimitiveStringer) (TPrimitiveStringer, error) {
+	if t.Range.Type == "enum" || len(t.Range.Enums) > 0 {
+		name := primitiveName(primitive)
+		fromInt := primitives[name].fromInt
+		enumerators := make([]TEnumEnumerator, len(t.Range.Enums))
+		for i, enum := range t.Range.Enums {
+			var value any
+			ok := fromInt != nil
+			if ok {
+				value, ok = fromInt(int64(enum.Value))
+			}
+			if !ok {
+				return nil, &ErrEnumValue{Type: t.Name, Key: enum.Key, Value: enum.Value, Primitive: name}
+			}
+			enumerators[i] = TEnumEnumerator{Key: enum.Key, Description: enum.Description, Value: value}
+		}
+		return &TEnum{
+			Name:        t.Name,
+			Description: t.Description,
+			Primitive:   primitive,
+			Postfix:     t.Postfix,
+			Enumerators: enumerators,
+		}, nil
+	}
+
+	if t.Range.Type == "range" {
+		return &TRange{
+			Name:        t.Name,
+			Description: t.Description,
+			Primitive:   primitive,
+			Postfix:     t.Postfix,
+			Min:         t.Range.Start,
+			Max:         t.Range.Stop,
+		}, nil
+	}
+
+	return &TSimple{
+		Name:        t.Name,
+		Description: t.Description,
+		Primitive:   primitive,
+		Postfix:     t.Postfix,
+	}, nil
+}
+
+// Lookup returns the resolved type of a types-v2 type or primitive.
+func (r *TypeRegistry) Lookup(typeName string) (TPrimitiveStringer, error) {
+	if t, ok := r.types[typeName]; ok {
+		return t, nil
+	}
+	if err, ok := r.broken[typeName]; ok {
+		return nil, err
+	}
+	if primitive, ok := newPrimitive(typeName); ok {
+		return primitive, nil
+	}
+	return nil, &ErrUnkownType{Type: typeName}
+}
+
+// Has reports whether typeName is a types-v2 type, resolved or not, or a primitive.
+func (r *TypeRegistry) Has(typeName string) bool {
+	_, named := r.types[typeName]
+	_, broken := r.broken[typeName]
+	_, primitive := newPrimitive(typeName)
+	return named || broken || primitive
+}
+
+// Primitive returns the name of the primitive type a types-v2 type or primitive is encoded as.
+func (r *TypeRegistry) Primitive(typeName string) (string, error) {
+	t, err := r.Lookup(typeName)
+	if err != nil {
+		return "", err
+	}
+	switch named := t.(type) {
+	case *TRange:
+		return primitiveName(named.Primitive), nil
+	case *TEnum:
+		return primitiveName(named.Primitive), nil
+	case *TSimple:
+		return primitiveName(named.Primitive), nil
+	default:
+		return primitiveName(t), nil
+	}
+}
+
+// Postfix returns the unit of a types-v2 type, empty for primitives and types without one.
+func (r *TypeRegistry) Postfix(typeName string) string {
+	switch t := r.types[typeName].(type) {
+	case *TRange:
+		return t.Postfix
+	case *TEnum:
+		return t.Postfix
+	case *TSimple:
+		return t.Postfix
+	default:
+		return ""
+	}
+}
+
+// Enum returns the enum type with the given name, if it is one.
+func (r *TypeRegistry) Enum(typeName string) (*TEnum, bool) {
+	enum, ok := r.types[typeName].(*TEnum)
+	return enum, ok
+}
+
+// ParseString parses value as typeName, enforcing the bounds of ranges and the enumerators of enums.
+func (r *TypeRegistry) ParseString(typeName, value string) (any, error) {
+	t, err := r.Lookup(typeName)
+	if err != nil {
+		return nil, err
+	}
+	return t.ParseString(value)
+}
+
+// Validate checks an integer value against the range or enumerators of its types-v2 type.
+// Values of other types, and types without a range, are always valid.
+func (r *TypeRegistry) Validate(typeName string, value any) error {
+	switch t := r.types[typeName].(type) {
+	case *TRange:
+		return t.validate(value)
+	case *TEnum:
+		return t.validate(value)
+	default:
+		return nil
+	}
+}
+
+func (t *TRange) ParseString(v string) (any, error) {
+	value, err := t.Primitive.ParseString(v)
+	if err != nil {
+		return nil, err
+	}
+	return value, t.validate(value)
+}
+
+func (t *TRange) validate(value any) error {
+	var inRange bool
+	if unsigned, ok := value.(TypeUint64); ok {
+		// Compared as unsigned, values above math.MaxInt64 would wrap around as an int64
+		inRange = (t.Min <= 0 || uint64(unsigned) >= uint64(t.Min)) && t.Max >= 0 && uint64(unsigned) <= uint64(t.Max)
+	} else {
+		integer, ok := integerOf(value)
+		if !ok {
+			return nil
+		}
+		inRange = integer >= int64(t.Min) && integer <= int64(t.Max)
+	}
+	if !inRange {
+		return &ErrOutOfRange{Type: t.Name, Value: value, Start: t.Min, Stop: t.Max}
+	}
+	return nil
+}
+
+// ParseString parses the key of an enumerator, ignoring case, or the value of one.
+func (t *TEnum) ParseString(v string) (any, error) {
+	if enum, ok := t.Enumerator(v); ok {
+		return enum.Value, nil
+	}
+
+	value, err := t.Primitive.ParseString(v)
+	if err != nil {
+		return nil, &ErrUnknownEnumerator{Type: t.Name, Key: v, Suggestion: t.suggest(v)}
+	}
+	return value, t.validate(value)
+}
+
+// Enumerator returns the enumerator with the given key, ignoring case.
+func (t *TEnum) Enumerator(key string) (TEnumEnumerator, bool) {
+	for _, enum := range t.Enumerators {
+		if strings.EqualFold(enum.Key, key) {
+			return enum, true
+		}
+	}
+	return TEnumEnumerator{}, false
+}
+
+// Key returns the key of the enumerator with the given value.
+func (t *TEnum) Key(value any) (string, bool) {
+	for _, enum := range t.Enumerators {
+		if enum.Value == value {
+			return enum.Key, true
+		}
+	}
+	return "", false
+}
+
+func (t *TEnum) validate(value any) error {
+	integer, ok := integerOf(value)
+	if !ok {
+		return nil
+	}
+	if _, ok := t.Key(value); ok {
+		return nil
+	}
+	return &ErrNotEnumerator{Type: t.Name, Value: integer}
+}
+
+// suggest returns the key closest to a mistyped key, if any is close enough to be what was meant.
+func (t *TEnum) suggest(key string) string {
+	suggestion := ""
+	best := len(key)/2 + 1
+	for _, enum := range t.Enumerators {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(enum.Key))
+		if distance < best {
+			suggestion = enum.Key
+			best = distance
+		}
+	}
+	return suggestion
+}
+
+func (t *TSimple) ParseString(v string) (any, error) {
+	return t.Primitive.ParseString(v)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
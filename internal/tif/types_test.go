@@ -0,0 +1,248 @@
+package tif
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	def := decodeTestDefinition()
+	addRange := func(name, primitive, rangeType string, start, stop int) {
+		t := TypeV2Definition{Name: name, Type: primitive}
+		t.Range.Type = rangeType
+		t.Range.Start = start
+		t.Range.Stop = stop
+		def.TypesV2 = append(def.TypesV2, t)
+	}
+	addRange("tHeight", "uint8", "range", 20, 60)
+	addRange("tOff", "uint8", "range", 0, 0)
+	addRange("tBounds", "uint8", "", 20, 60)
+	addRange("tCounter", "uint64", "range", 10, math.MaxInt64)
+	encoder := NewEncoder(def)
+
+	tests := map[string]struct {
+		typeName      string
+		value         any
+		expectedRange *ErrOutOfRange
+		expectedEnum  *ErrNotEnumerator
+	}{
+		"primitive":          {typeName: "uint8", value: TypeUint8(255)},
+		"without range":      {typeName: "tPercent", value: TypeUint8(255)},
+		"range start":        {typeName: "tHeight", value: TypeUint8(20)},
+		"range stop":         {typeName: "tHeight", value: TypeUint8(60)},
+		"enumerator":         {typeName: "tChargeState", value: TypeUint8(1)},
+		"below range":        {typeName: "tHeight", value: TypeUint8(19), expectedRange: &ErrOutOfRange{Type: "tHeight", Value: TypeUint8(19), Start: 20, Stop: 60}},
+		"above range":        {typeName: "tHeight", value: TypeUint8(61), expectedRange: &ErrOutOfRange{Type: "tHeight", Value: TypeUint8(61), Start: 20, Stop: 60}},
+		"empty range":        {typeName: "tOff", value: TypeUint8(0)},
+		"above empty range":  {typeName: "tOff", value: TypeUint8(1), expectedRange: &ErrOutOfRange{Type: "tOff", Value: TypeUint8(1), Start: 0, Stop: 0}},
+		"bounds of no range": {typeName: "tBounds", value: TypeUint8(61)},
+		"uint64 range stop":  {typeName: "tCounter", value: TypeUint64(math.MaxInt64)},
+		"uint64 below range": {typeName: "tCounter", value: TypeUint64(9), expectedRange: &ErrOutOfRange{Type: "tCounter", Value: TypeUint64(9), Start: 10, Stop: math.MaxInt64}},
+		"uint64 above int64": {
+			typeName:      "tCounter",
+			value:         TypeUint64(math.MaxInt64 + 1),
+			expectedRange: &ErrOutOfRange{Type: "tCounter", Value: TypeUint64(math.MaxInt64 + 1), Start: 10, Stop: math.MaxInt64},
+		},
+		"not an enumerator": {typeName: "tChargeState", value: TypeUint8(2), expectedEnum: &ErrNotEnumerator{Type: "tChargeState", Value: 2}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			err := encoder.Validate(test.typeName, test.value)
+			var rangeErr *ErrOutOfRange
+			var enumErr *ErrNotEnumerator
+			switch {
+			case test.expectedRange != nil:
+				if !errors.As(err, &rangeErr) || *rangeErr != *test.expectedRange {
+					t.Errorf("expected %v but got %v", test.expectedRange, err)
+				}
+			case test.expectedEnum != nil:
+				if !errors.As(err, &enumErr) || *enumErr != *test.expectedEnum {
+					t.Errorf("expected %v but got %v", test.expectedEnum, err)
+				}
+			case err != nil:
+				t.Errorf("expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func registryTestDefinition() *TifDefinition {
+	def := &TifDefinition{
+		TypesV2: []TypeV2Definition{
+			{Name: "tMode", Type: "uint8", Description: "Operating mode"},
+			{Name: "tHeight", Type: "uint8", Postfix: "mm"},
+			{Name: "tLength", Type: "tDistance"},
+			{Name: "tDistance", Type: "sint32", Postfix: "cm"},
+			{Name: "tBroken", Type: "uint128"},
+		},
+	}
+	def.TypesV2[0].Range.Type = "enum"
+	def.TypesV2[0].Range.Enums = []EnumDefinition{
+		{Key: "Auto", Value: 0},
+		{Key: "Manual", Value: 1},
+		{Key: "Home", Value: 2},
+	}
+	def.TypesV2[1].Range.Type = "range"
+	def.TypesV2[1].Range.Start = 20
+	def.TypesV2[1].Range.Stop = 60
+	return def
+}
+
+func TestTypeRegistryLookup(t *testing.T) {
+	t.Parallel()
+
+	registry := NewTypeRegistry(registryTestDefinition())
+
+	mode, err := registry.Lookup("tMode")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expectedMode := &TEnum{
+		Name:        "tMode",
+		Description: "Operating mode",
+		Primitive:   &TUint8{},
+		Enumerators: []TEnumEnumerator{
+			{Key: "Auto", Value: TypeUint8(0)},
+			{Key: "Manual", Value: TypeUint8(1)},
+			{Key: "Home", Value: TypeUint8(2)},
+		},
+	}
+	if diff := cmp.Diff(expectedMode, mode); diff != "" {
+		t.Errorf("Lookup(tMode) mismatch (-expected +got):\n%s", diff)
+	}
+
+	height, err := registry.Lookup("tHeight")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expectedHeight := &TRange{Name: "tHeight", Primitive: &TUint8{}, Postfix: "mm", Min: 20, Max: 60}
+	if diff := cmp.Diff(expectedHeight, height); diff != "" {
+		t.Errorf("Lookup(tHeight) mismatch (-expected +got):\n%s", diff)
+	}
+
+	tests := map[string]struct {
+		typeName  string
+		primitive string
+		isError   bool
+	}{
+		"primitive":            {typeName: "uint16", primitive: "uint16"},
+		"enum":                 {typeName: "tMode", primitive: "uint8"},
+		"type of a named type": {typeName: "tLength", primitive: "sint32"},
+		"unknown primitive":    {typeName: "tBroken", isError: true},
+		"unknown type":         {typeName: "tMissing", isError: true},
+	}
+	for name, test := range tests {
+		primitive, err := registry.Primitive(test.typeName)
+		if test.isError {
+			var unknown *ErrUnkownType
+			if !errors.As(err, &unknown) {
+				t.Errorf("%s: expected unknown type error but got %v", name, err)
+			}
+			continue
+		}
+		if err != nil || primitive != test.primitive {
+			t.Errorf("%s: Primitive(%s) returned %s, %v; expected %s", name, test.typeName, primitive, err, test.primitive)
+		}
+	}
+}
+
+func TestTypeRegistryParseString(t *testing.T) {
+	t.Parallel()
+
+	registry := NewTypeRegistry(registryTestDefinition())
+
+	tests := map[string]struct {
+		typeName string
+		value    string
+		expected any
+		err      error
+	}{
+		"enum key":         {typeName: "tMode", value: "manual", expected: TypeUint8(1)},
+		"enum value":       {typeName: "tMode", value: "2", expected: TypeUint8(2)},
+		"enum typo":        {typeName: "tMode", value: "Autu", err: &ErrUnknownEnumerator{Type: "tMode", Key: "Autu", Suggestion: "Auto"}},
+		"enum unknown key": {typeName: "tMode", value: "Sideways", err: &ErrUnknownEnumerator{Type: "tMode", Key: "Sideways"}},
+		"enum bad value":   {typeName: "tMode", value: "3", err: &ErrNotEnumerator{Type: "tMode", Value: 3}},
+		"range":            {typeName: "tHeight", value: "40", expected: TypeUint8(40)},
+		"below range":      {typeName: "tHeight", value: "19", err: &ErrOutOfRange{Type: "tHeight", Value: TypeUint8(19), Start: 20, Stop: 60}},
+		"above range":      {typeName: "tHeight", value: "0x3D", err: &ErrOutOfRange{Type: "tHeight", Value: TypeUint8(61), Start: 20, Stop: 60}},
+		"simple":           {typeName: "tLength", value: "-5", expected: TypeInt32(-5)},
+		"primitive":        {typeName: "bool", value: "true", expected: TypeBool(true)},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := registry.ParseString(test.typeName, test.value)
+			if test.err != nil {
+				if diff := cmp.Diff(test.err, err); diff != "" {
+					t.Errorf("ParseString(%s) error mismatch (-expected +got):\n%s", test.value, diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if value != test.expected {
+				t.Errorf("ParseString(%s) returned %v; expected %v", test.value, value, test.expected)
+			}
+		})
+	}
+}
+
+func TestTypeRegistryEnumValues(t *testing.T) {
+	t.Parallel()
+
+	enumType := func(name, primitive string, values ...int) TypeV2Definition {
+		t := TypeV2Definition{Name: name, Type: primitive}
+		t.Range.Type = "enum"
+		for i, value := range values {
+			t.Range.Enums = append(t.Range.Enums, EnumDefinition{Key: fmt.Sprintf("Key%d", i), Value: value})
+		}
+		return t
+	}
+	registry := NewTypeRegistry(&TifDefinition{
+		TypesV2: []TypeV2Definition{
+			enumType("tFlags", "bit", 2, 10),
+			enumType("tNegative", "sint8", -1),
+			enumType("tTooLarge", "uint8", 1, 300),
+			enumType("tNotInteger", "float", 1),
+		},
+	})
+
+	flags, ok := registry.Enum("tFlags")
+	if !ok {
+		t.Fatal("expected tFlags to be an enum")
+	}
+	for value, key := range map[TypeBit]string{2: "Key0", 10: "Key1"} {
+		if got, ok := flags.Key(value); !ok || got != key {
+			t.Errorf("expected bit value %d to be %s but got %q, %t", value, key, got, ok)
+		}
+	}
+	if negative, ok := registry.Enum("tNegative"); !ok || negative.Enumerators[0].Value != TypeInt8(-1) {
+		t.Errorf("expected tNegative to have value -1 as sint8 but got %+v", negative)
+	}
+
+	tests := map[string]ErrEnumValue{
+		"tTooLarge":   {Type: "tTooLarge", Key: "Key1", Value: 300, Primitive: "uint8"},
+		"tNotInteger": {Type: "tNotInteger", Key: "Key0", Value: 1, Primitive: "float"},
+	}
+	for typeName, expected := range tests {
+		_, err := registry.Lookup(typeName)
+		var valueErr *ErrEnumValue
+		if !errors.As(err, &valueErr) || *valueErr != expected {
+			t.Errorf("expected %v looking up %s but got %v", &expected, typeName, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package tif
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IssueCode identifies the kind of problem an Issue is.
+type IssueCode string
+
+const (
+	IssueDuplicateMethod    IssueCode = "duplicate-method"
+	IssueDuplicateType      IssueCode = "duplicate-type"
+	IssueDuplicateEnumValue IssueCode = "duplicate-enum-value"
+	IssueDuplicateEnumKey   IssueCode = "duplicate-enum-key"
+	IssueUnknownMethod      IssueCode = "unknown-method"
+	IssueUnknownType        IssueCode = "unknown-type"
+	IssueInvalidEnumValue   IssueCode = "invalid-enum-value"
+	IssueTypeTooDeep        IssueCode = "type-too-deep"
+	IssueMaxResponseTime    IssueCode = "invalid-max-response-time"
+)
+
+// Issue is a broken reference or inconsistency in a definition.
+type Issue struct {
+	Code IssueCode `json:"code"`
+	// Method, attribute or type the issue is in
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s [%s]", i.Subject, i.Message, i.Code)
+}
+
+// ValidateDefinition checks that everything the indexed definition refers to exists and is unambiguous,
+// returning the issues found in the order they appear in the definition.
+func ValidateDefinition(idx *Index) []Issue {
+	def := idx.Definition()
+	types := NewTypeRegistry(def)
+	var issues []Issue
+	issues = append(issues, validateTypes(def, types)...)
+	issues = append(issues, validateMethods(def, types)...)
+	issues = append(issues, validateAttributes(idx, types)...)
+	return issues
+}
+
+func validateTypes(def *TifDefinition, types *TypeRegistry) []Issue {
+	var issues []Issue
+	seen := make(map[string]bool, len(def.TypesV2))
+	for _, t := range def.TypesV2 {
+		if seen[t.Name] {
+			issues = append(issues, Issue{Code: IssueDuplicateType, Subject: t.Name, Message: "type is defined more than once"})
+			continue
+		}
+		seen[t.Name] = true
+
+		if _, err := types.Lookup(t.Name); err != nil {
+			issues = append(issues, typeIssue(t.Name, err))
+		}
+
+		values := make(map[int]string, len(t.Range.Enums))
+		keys := make(map[string]bool, len(t.Range.Enums))
+		for _, enum := range t.Range.Enums {
+			if key, ok := values[enum.Value]; ok {
+				issues = append(issues, Issue{
+					Code:    IssueDuplicateEnumValue,
+					Subject: t.Name,
+					Message: fmt.Sprintf("enumerators %s and %s have the same value %d", key, enum.Key, enum.Value),
+				})
+			} else {
+				values[enum.Value] = enum.Key
+			}
+
+			// Keys are matched ignoring case when parsing arguments
+			key := strings.ToLower(enum.Key)
+			if keys[key] {
+				issues = append(issues, Issue{
+					Code:    IssueDuplicateEnumKey,
+					Subject: t.Name,
+					Message: fmt.Sprintf("enumerator key %s is used more than once", enum.Key),
+				})
+			}
+			keys[key] = true
+		}
+	}
+	return issues
+}
+
+// typeIssue is the issue of a type that could not be resolved because of err.
+func typeIssue(typeName string, err error) Issue {
+	var (
+		unknown   *ErrUnkownType
+		enumValue *ErrEnumValue
+		depth     *ErrTypeDepth
+	)
+	switch {
+	case errors.As(err, &unknown):
+		message := fmt.Sprintf("type is based on %s, which is neither in types-v2 nor a primitive", unknown.Type)
+		return Issue{Code: IssueUnknownType, Subject: typeName, Message: message}
+	case errors.As(err, &enumValue):
+		return Issue{Code: IssueInvalidEnumValue, Subject: typeName, Message: err.Error()}
+	case errors.As(err, &depth):
+		message := fmt.Sprintf("type is nested more than %d levels deep, it may refer to itself", depth.Depth)
+		return Issue{Code: IssueTypeTooDeep, Subject: typeName, Message: message}
+	default:
+		return Issue{Code: IssueUnknownType, Subject: typeName, Message: err.Error()}
+	}
+}
+
+func validateMethods(def *TifDefinition, types *TypeRegistry) []Issue {
+	var issues []Issue
+	seen := make(map[string]bool, len(def.Methods))
+	for _, method := range def.Methods {
+		// Methods are looked up ignoring case
+		key := strings.ToLower(method.Name())
+		if seen[key] {
+			issues = append(issues, Issue{Code: IssueDuplicateMethod, Subject: method.Name(), Message: "method is defined more than once"})
+		}
+		seen[key] = true
+
+		for _, param := range method.InParams {
+			if !types.Has(param.Type) {
+				issues = append(issues, unknownParamType(method.Name(), "in parameter", param.Name, param.Type))
+			}
+		}
+		for _, param := range method.OutParams {
+			if !types.Has(param.Type) {
+				issues = append(issues, unknownParamType(method.Name(), "out parameter", param.Name, param.Type))
+			}
+		}
+
+		if _, ok := method.ResponseTimeout(); method.MaxResponseTime != "" && !ok {
+			issues = append(issues, Issue{
+				Code:    IssueMaxResponseTime,
+				Subject: method.Name(),
+				Message: fmt.Sprintf("max response time %q is not a positive number of milliseconds or duration", method.MaxResponseTime),
+			})
+		}
+	}
+	return issues
+}
+
+func validateAttributes(idx *Index, types *TypeRegistry) []Issue {
+	var issues []Issue
+	for _, attr := range idx.Definition().AttributesV2 {
+		for _, param := range attr.Params {
+			if !types.Has(param.Type) {
+				issues = append(issues, unknownParamType(attr.FullName(), "parameter", param.Name, param.Type))
+			}
+		}
+
+		if command, ok := attr.ReadCommand(); ok {
+			issues = append(issues, checkCommand(idx, attr, "read", command)...)
+		}
+		if command, ok := attr.WriteCommand(); ok {
+			issues = append(issues, checkCommand(idx, attr, "write", command)...)
+		}
+		if attr.List.Family != "" || attr.List.Name != "" {
+			issues = append(issues, checkCommand(idx, attr, "list", fmt.Sprintf("%s.%s", attr.List.Family, attr.List.Name))...)
+		}
+	}
+	return issues
+}
+
+func checkCommand(idx *Index, attr AttributeV2Definition, operation, command string) []Issue {
+	if _, ok := idx.Method(command); ok {
+		return nil
+	}
+	return []Issue{{
+		Code:    IssueUnknownMethod,
+		Subject: attr.FullName(),
+		Message: fmt.Sprintf("%s command %s does not exist", operation, command),
+	}}
+}
+
+func unknownParamType(subject, kind, name, typeName string) Issue {
+	return Issue{
+		Code:    IssueUnknownType,
+		Subject: subject,
+		Message: fmt.Sprintf("%s %s has type %s, which is neither in types-v2 nor a primitive", kind, name, typeName),
+	}
+}
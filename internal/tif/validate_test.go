@@ -0,0 +1,108 @@
+package tif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const validateTestDefinitionJson = `{
+  "methods": [
+    {
+      "family": "Mower",
+      "command": "GetMode",
+      "outParams": [{ "name": "mode", "type": "tMode" }],
+      "maxResponseTime": "500"
+    },
+    {
+      "family": "Mower",
+      "command": "SetMode",
+      "inParams": [{ "name": "mode", "type": "tModes" }],
+      "maxResponseTime": "soon"
+    },
+    { "family": "mower", "command": "getMode" },
+    {
+      "family": "Mower",
+      "command": "GetHeight",
+      "outParams": [{ "name": "height", "type": "tHeight" }]
+    }
+  ],
+  "types-v2": [
+    {
+      "name": "tMode",
+      "type": "uint8",
+      "range": {
+        "type": "enum",
+        "enum": [
+          { "key": "Auto", "value": 0 },
+          { "key": "Manual", "value": 1 },
+          { "key": "Home", "value": 1 },
+          { "key": "auto", "value": 2 }
+        ]
+      }
+    },
+    { "name": "tHeight", "type": "uint24" },
+    { "name": "tHeight", "type": "uint8" },
+    {
+      "name": "tLevel",
+      "type": "uint8",
+      "range": { "type": "enum", "enum": [{ "key": "Low", "value": 0 }, { "key": "High", "value": 300 }] }
+    },
+    { "name": "tLoop", "type": "tLoopBack" },
+    { "name": "tLoopBack", "type": "tLoop" }
+  ],
+  "attributes-v2": [
+    {
+      "family": "Mower",
+      "name": "Mode",
+      "params": [{ "name": "mode", "type": "tMode" }],
+      "operations": ["read", "write"],
+      "read": { "command": { "family": "Mower", "name": "GetMode" } },
+      "write": { "command": { "family": "Mower", "name": "PutMode" } }
+    },
+    {
+      "family": "Mower",
+      "name": "Zones",
+      "params": [{ "name": "zone", "type": "tZone" }],
+      "operations": ["read"],
+      "read": { "command": { "family": "Mower", "name": "GetZone" } },
+      "list": { "family": "Mower", "name": "ListZones" }
+    }
+  ]
+}`
+
+func TestValidateDefinition(t *testing.T) {
+	t.Parallel()
+
+	var def TifDefinition
+	err := json.Unmarshal([]byte(validateTestDefinitionJson), &def)
+	if err != nil {
+		t.Fatalf("failed to parse definition: %v", err)
+	}
+
+	expected := []Issue{
+		{Code: IssueDuplicateEnumValue, Subject: "tMode", Message: "enumerators Manual and Home have the same value 1"},
+		{Code: IssueDuplicateEnumKey, Subject: "tMode", Message: "enumerator key auto is used more than once"},
+		{Code: IssueUnknownType, Subject: "tHeight", Message: "type is based on uint24, which is neither in types-v2 nor a primitive"},
+		{Code: IssueDuplicateType, Subject: "tHeight", Message: "type is defined more than once"},
+		{Code: IssueInvalidEnumValue, Subject: "tLevel", Message: "enumerator High of tLevel has value 300, which is not a valid uint8"},
+		{Code: IssueTypeTooDeep, Subject: "tLoop", Message: "type is nested more than 8 levels deep, it may refer to itself"},
+		{Code: IssueTypeTooDeep, Subject: "tLoopBack", Message: "type is nested more than 8 levels deep, it may refer to itself"},
+		{Code: IssueUnknownType, Subject: "Mower.SetMode", Message: "in parameter mode has type tModes, which is neither in types-v2 nor a primitive"},
+		{Code: IssueMaxResponseTime, Subject: "Mower.SetMode", Message: `max response time "soon" is not a positive number of milliseconds or duration`},
+		{Code: IssueDuplicateMethod, Subject: "mower.getMode", Message: "method is defined more than once"},
+		{Code: IssueUnknownMethod, Subject: "Mower.Mode", Message: "write command Mower.PutMode does not exist"},
+		{Code: IssueUnknownType, Subject: "Mower.Zones", Message: "parameter zone has type tZone, which is neither in types-v2 nor a primitive"},
+		{Code: IssueUnknownMethod, Subject: "Mower.Zones", Message: "read command Mower.GetZone does not exist"},
+		{Code: IssueUnknownMethod, Subject: "Mower.Zones", Message: "list command Mower.ListZones does not exist"},
+	}
+	if diff := cmp.Diff(expected, ValidateDefinition(NewIndex(&def))); diff != "" {
+		t.Errorf("ValidateDefinition mismatch (-expected +got):\n%s", diff)
+	}
+
+	linkManager := loadTestDefinition(t, "testdata/linkmanager-def.json")
+	if issues := ValidateDefinition(NewIndex(linkManager)); len(issues) > 0 {
+		t.Errorf("expected no issues in the link manager definition but got %v", issues)
+	}
+}
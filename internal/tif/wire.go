@@ -0,0 +1,102 @@
+package tif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// Size of the header that starts every RoboticsProtocol2 method payload:
+// message type (uint16), sub command (uint8) and parameter length (uint16).
+const MethodHeaderSize = 5
+
+// MethodId identifies a method that is not sent through the link manager.
+type MethodId struct {
+	MsgType uint16
+	SubCmd  uint8
+}
+
+func (id MethodId) String() string {
+	return fmt.Sprintf("%d.%d", id.MsgType, id.SubCmd)
+}
+
+// ProtocolValue returns the value of the protocol entry with the given key.
+func (m MethodDefinition) ProtocolValue(key string) (string, bool) {
+	for _, entry := range m.Protocol {
+		if entry.Key == key {
+			return string(entry.Value), true
+		}
+	}
+	return "", false
+}
+
+// IsLinked reports whether the method is a link manager command,
+// identified by a request id instead of a message type and sub command.
+func (m MethodDefinition) IsLinked() bool {
+	linked, _ := m.ProtocolValue("linked")
+	return linked == "true"
+}
+
+// MethodId returns the message type and sub command of a method that is not linked.
+func (m MethodDefinition) MethodId() (MethodId, error) {
+	msgType, err := m.protocolUint("msgType", 16)
+	if err != nil {
+		return MethodId{}, err
+	}
+	subCmd, err := m.protocolUint("subCmd", 8)
+	if err != nil {
+		return MethodId{}, err
+	}
+	return MethodId{MsgType: uint16(msgType), SubCmd: uint8(subCmd)}, nil
+}
+
+// RequestId returns the request and response ids of a linked method.
+func (m MethodDefinition) RequestId() (request, response byte, err error) {
+	requestId, err := m.protocolUint("requestId", 8)
+	if err != nil {
+		return 0, 0, err
+	}
+	responseId, err := m.protocolUint("responseId", 8)
+	if err != nil {
+		return 0, 0, err
+	}
+	return byte(requestId), byte(responseId), nil
+}
+
+func (m MethodDefinition) protocolUint(key string, bitSize int) (uint64, error) {
+	value, ok := m.ProtocolValue(key)
+	if !ok {
+		return 0, fmt.Errorf("method %s has no %s in its protocol", m.Name(), key)
+	}
+	value, base := getValueBase(value)
+	integer, err := strconv.ParseUint(value, base, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("method %s has an invalid %s: %w", m.Name(), key, err)
+	}
+	return integer, nil
+}
+
+// AppendMethodHeader appends the header of a method payload with paramsLength bytes of parameters.
+func AppendMethodHeader(buf []byte, id MethodId, paramsLength int) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, id.MsgType)
+	buf = append(buf, id.SubCmd)
+	return binary.LittleEndian.AppendUint16(buf, uint16(paramsLength))
+}
+
+// ParseMethodHeader splits a method payload into its method id and parameters.
+func ParseMethodHeader(payload []byte) (MethodId, []byte, error) {
+	if len(payload) < MethodHeaderSize {
+		return MethodId{}, nil, fmt.Errorf("method payload of %d bytes is shorter than its header", len(payload))
+	}
+
+	id := MethodId{
+		MsgType: binary.LittleEndian.Uint16(payload[0:2]),
+		SubCmd:  payload[2],
+	}
+	length := int(binary.LittleEndian.Uint16(payload[3:5]))
+	params := payload[MethodHeaderSize:]
+	if len(params) != length {
+		return id, nil, fmt.Errorf("method %s header declares %d bytes of parameters but has %d", id, length, len(params))
+	}
+	return id, params, nil
+}
@@ -0,0 +1,92 @@
+package tifclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Tifufu/tools-cli/internal/tif"
+)
+
+// ReadAttribute reads the attribute with the given Family.Name name through its read command.
+// args are the arguments of the read command, in the order of its parameters. Like methods,
+// attributes are checked against the login level of the session before they are read, by the
+// login levels of the attribute or, if it has none, those of its read command.
+func (c *Client) ReadAttribute(ctx context.Context, name string, args []string) (tif.MethodDefinition, tif.Response, error) {
+	attr, ok := c.idx.Attribute(name)
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("could not find attribute %s", name)
+	}
+	command, ok := attr.ReadCommand()
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("attribute %s can not be read", attr.FullName())
+	}
+	method, ok := c.idx.Method(command)
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("could not find read command %s of attribute %s", command, attr.FullName())
+	}
+
+	checked, allowed := attr.FullName(), attr.Protocol.Read.LoginLevels
+	if len(allowed) == 0 {
+		checked, allowed = method.Name(), method.LoginLevels
+	}
+	err := c.checkLoginLevel(checked, allowed)
+	if err != nil {
+		return method, tif.Response{}, err
+	}
+
+	values, err := c.parsePositional(method, method.InParams, args)
+	if err != nil {
+		return method, tif.Response{}, err
+	}
+	response, err := c.call(ctx, method, values)
+	return method, response, err
+}
+
+// WriteAttribute writes the attribute with the given Family.Name name through its write command.
+// args are the values of the attribute parameters, in order. Each value is checked against
+// the type of its attribute parameter, including its range, before anything is sent.
+func (c *Client) WriteAttribute(ctx context.Context, name string, args []string) (tif.MethodDefinition, tif.Response, error) {
+	attr, ok := c.idx.Attribute(name)
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("could not find attribute %s", name)
+	}
+	command, ok := attr.WriteCommand()
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("attribute %s can not be written", attr.FullName())
+	}
+	method, ok := c.idx.Method(command)
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("could not find write command %s of attribute %s", command, attr.FullName())
+	}
+	if len(method.InParams) != len(attr.Params) {
+		return method, tif.Response{}, fmt.Errorf("write command %s has %d parameters but attribute %s has %d", command, len(method.InParams), attr.FullName(), len(attr.Params))
+	}
+
+	params := make([]tif.InputParameter, len(attr.Params))
+	for i, param := range attr.Params {
+		params[i] = tif.InputParameter{Name: param.Name, Type: param.Type, Length: method.InParams[i].Length}
+	}
+	values, err := c.parsePositional(method, params, args)
+	if err != nil {
+		return method, tif.Response{}, err
+	}
+	response, err := c.Call(ctx, method, values)
+	return method, response, err
+}
+
+// parsePositional parses and validates args, one for each of params in order.
+func (c *Client) parsePositional(method tif.MethodDefinition, params []tif.InputParameter, args []string) ([]any, error) {
+	if len(args) != len(params) {
+		return nil, fmt.Errorf("%s expects %d values but got %d", method.Name(), len(params), len(args))
+	}
+
+	values := make([]any, len(params))
+	for i, param := range params {
+		value, err := c.encoder.ParseArgument(param, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", param.Name, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,107 @@
+package tifclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Tifufu/tools-cli/internal/emulator"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/google/go-cmp/cmp"
+)
+
+const attributeDefinitionJson = `{
+  "attributes-v2": [
+    {
+      "family": "Mower",
+      "name": "CuttingHeight",
+      "params": [{ "name": "height", "type": "tCuttingHeight" }],
+      "operations": ["read", "write"],
+      "read": { "command": { "family": "Mower", "name": "GetCuttingHeight" } },
+      "write": { "command": { "family": "Mower", "name": "SetCuttingHeight" } }
+    },
+    {
+      "family": "Mower",
+      "name": "Serial",
+      "params": [{ "name": "serial", "type": "uint32" }],
+      "operations": ["read"],
+      "read": { "command": { "family": "Mower", "name": "GetSerial" } }
+    }
+  ],
+  "methods": [
+    {
+      "family": "Mower",
+      "command": "GetCuttingHeight",
+      "inParams": [],
+      "outParams": [{ "name": "height", "type": "tCuttingHeight" }],
+      "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "1" }]
+    },
+    {
+      "family": "Mower",
+      "command": "SetCuttingHeight",
+      "inParams": [{ "name": "height", "type": "tCuttingHeight" }],
+      "outParams": [],
+      "protocol": [{ "key": "msgType", "value": "0x2000" }, { "key": "subCmd", "value": "2" }]
+    }
+  ],
+  "types-v2": [
+    { "name": "tCuttingHeight", "type": "uint8", "postfix": "mm", "range": { "type": "range", "start": 20, "stop": 60 } }
+  ]
+}`
+
+func TestClientAttributes(t *testing.T) {
+	t.Parallel()
+
+	var def tif.TifDefinition
+	err := json.Unmarshal([]byte(attributeDefinitionJson), &def)
+	if err != nil {
+		t.Fatalf("failed to parse definition: %v", err)
+	}
+	client := openTestClientFor(t, &def,
+		emulator.WithResponses("Mower.GetCuttingHeight", emulator.Response{Params: []byte{0x28}}),
+	)
+	ctx := context.Background()
+
+	method, response, err := client.ReadAttribute(ctx, "mower.cuttingheight", nil)
+	if err != nil {
+		t.Fatalf("expected no error reading attribute but got %v", err)
+	}
+	if method.Name() != "Mower.GetCuttingHeight" {
+		t.Errorf("expected attribute to be read with Mower.GetCuttingHeight but got %s", method.Name())
+	}
+	expected := tif.Response{Values: []tif.OutValue{{Name: "height", Type: "tCuttingHeight", Value: tif.TypeUint8(40), Postfix: "mm"}}}
+	if diff := cmp.Diff(expected, response); diff != "" {
+		t.Errorf("ReadAttribute mismatch (-expected +got):\n%s", diff)
+	}
+
+	method, _, err = client.WriteAttribute(ctx, "Mower.CuttingHeight", []string{"60"})
+	if err != nil {
+		t.Fatalf("expected no error writing attribute but got %v", err)
+	}
+	if method.Name() != "Mower.SetCuttingHeight" {
+		t.Errorf("expected attribute to be written with Mower.SetCuttingHeight but got %s", method.Name())
+	}
+
+	_, _, err = client.WriteAttribute(ctx, "Mower.CuttingHeight", []string{"61"})
+	var rangeErr *tif.ErrOutOfRange
+	if !errors.As(err, &rangeErr) {
+		t.Errorf("expected out of range error but got %v", err)
+	}
+
+	invalid := map[string]struct {
+		name string
+		args []string
+	}{
+		"read only":         {name: "Mower.Serial", args: []string{"1"}},
+		"unknown attribute": {name: "Mower.Speed", args: []string{"1"}},
+		"too many values":   {name: "Mower.CuttingHeight", args: []string{"30", "40"}},
+		"malformed value":   {name: "Mower.CuttingHeight", args: []string{"high"}},
+	}
+	for name, test := range invalid {
+		_, _, err := client.WriteAttribute(ctx, test.name, test.args)
+		if err == nil {
+			t.Errorf("%s: expected an error but got none", name)
+		}
+	}
+}
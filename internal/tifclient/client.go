@@ -0,0 +1,190 @@
+// Package tifclient calls tif methods on a device over a link of a linking.LinkMux.
+package tifclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+)
+
+// Time to wait for a response to methods without a maximum response time in the definition.
+const DefaultResponseTimeout = 5 * time.Second
+
+// ErrStatus is returned when the device responds to a call with a status other than tif.StatusOk.
+type ErrStatus struct {
+	Method string
+	Status byte
+	// Levels the method may be called at, if the session may not be logged in at one of them
+	LoginLevels []string
+}
+
+func (e *ErrStatus) Error() string {
+	if len(e.LoginLevels) > 0 {
+		return fmt.Sprintf("%s responded with error status %d, it requires login level %s", e.Method, e.Status, joinLevels(e.LoginLevels))
+	}
+	return fmt.Sprintf("%s responded with error status %d", e.Method, e.Status)
+}
+
+// ErrTimeout is returned when the device does not respond to a call in time.
+type ErrTimeout struct {
+	Method  string
+	Timeout time.Duration
+}
+
+func (e *ErrTimeout) Error() string {
+	return fmt.Sprintf("%s did not respond within %s", e.Method, e.Timeout)
+}
+
+type Client struct {
+	idx     *tif.Index
+	link    *linking.Link
+	encoder *tif.Encoder
+	decoder *tif.Decoder
+
+	// Timeout of calls to methods without a maximum response time
+	ResponseTimeout time.Duration
+	// Login level of the session, empty if unknown. Set by Login and SetLoginLevel.
+	LoginLevel string
+	// What to do before calling methods the session is not logged in to call
+	LoginCheck LoginCheck
+	// Logs login level warnings, if not nil
+	Logger *log.Logger
+}
+
+// NewClient opens a new link on mux, using RoboticsProtocol2, to call the methods of the indexed definition.
+func NewClient(ctx context.Context, mux *linking.LinkMux, idx *tif.Index) (*Client, error) {
+	link, err := mux.OpenLink(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = link.SetProtocol(ctx, linking.RoboticsProtocol2)
+	if err != nil {
+		return nil, errors.Join(err, link.Close(ctx))
+	}
+
+	def := idx.Definition()
+	return &Client{
+		idx:             idx,
+		link:            link,
+		encoder:         tif.NewEncoder(def),
+		decoder:         tif.NewDecoder(def),
+		ResponseTimeout: DefaultResponseTimeout,
+		LoginCheck:      LoginCheckWarn,
+	}, nil
+}
+
+func (c *Client) Definition() *tif.TifDefinition {
+	return c.idx.Definition()
+}
+
+func (c *Client) Index() *tif.Index {
+	return c.idx
+}
+
+func (c *Client) Encoder() *tif.Encoder {
+	return c.encoder
+}
+
+// Close closes the link of the client.
+func (c *Client) Close(ctx context.Context) error {
+	return c.link.Close(ctx)
+}
+
+// CallString parses and performs a call written as Family.Command(param: value, ...).
+func (c *Client) CallString(ctx context.Context, call string) (tif.MethodDefinition, tif.Response, error) {
+	name, args, err := tif.ParseCall(call)
+	if err != nil {
+		return tif.MethodDefinition{}, tif.Response{}, err
+	}
+	method, ok := c.idx.Method(name)
+	if !ok {
+		return tif.MethodDefinition{}, tif.Response{}, fmt.Errorf("could not find method for %s", name)
+	}
+	values, err := c.encoder.ParseArguments(method, args)
+	if err != nil {
+		return method, tif.Response{}, err
+	}
+
+	response, err := c.Call(ctx, method, values)
+	return method, response, err
+}
+
+// Call calls method with args, given in the order of its InParams, and decodes the response.
+//
+// The call waits for the maximum response time of the method, or ResponseTimeout if it has none,
+// but never past the deadline of ctx. A response with an error status is returned along with an ErrStatus.
+// Methods are checked against the login level of the session first, according to LoginCheck.
+func (c *Client) Call(ctx context.Context, method tif.MethodDefinition, args []any) (tif.Response, error) {
+	err := c.checkLoginLevel(method.Name(), method.LoginLevels)
+	if err != nil {
+		return tif.Response{}, err
+	}
+	return c.call(ctx, method, args)
+}
+
+func (c *Client) call(ctx context.Context, method tif.MethodDefinition, args []any) (tif.Response, error) {
+	payload, err := c.encoder.EncodeCall(method, args)
+	if err != nil {
+		return tif.Response{}, err
+	}
+
+	timeout := c.ResponseTimeout
+	if methodTimeout, ok := method.ResponseTimeout(); ok {
+		timeout = methodTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ctrl, match, err := responseMatcher(method)
+	if err != nil {
+		return tif.Response{}, err
+	}
+	responsePayload, err := c.link.Request(ctx, ctrl, payload, match)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return tif.Response{}, &ErrTimeout{Method: method.Name(), Timeout: timeout}
+	}
+	if err != nil {
+		return tif.Response{}, fmt.Errorf("failed to call %s: %w", method.Name(), err)
+	}
+
+	response, err := c.decoder.DecodeResponse(method, responsePayload)
+	if err != nil {
+		return response, err
+	}
+	if response.Status != tif.StatusOk {
+		return response, &ErrStatus{Method: method.Name(), Status: response.Status, LoginLevels: c.requiredLoginLevels(method)}
+	}
+	return response, nil
+}
+
+// responseMatcher returns the control byte method is called with, and a function
+// telling the responses to method apart from other responses on the same link.
+func responseMatcher(method tif.MethodDefinition) (byte, func([]byte) bool, error) {
+	if method.IsLinked() {
+		_, responseId, err := method.RequestId()
+		if err != nil {
+			return 0, nil, err
+		}
+		return linking.ControlLinkManagerCommand, func(response []byte) bool {
+			return len(response) > 0 && response[0] == responseId
+		}, nil
+	}
+
+	id, err := method.MethodId()
+	if err != nil {
+		return 0, nil, err
+	}
+	return linking.ControlPayloadCommand, func(response []byte) bool {
+		responseId, _, err := tif.ParseMethodHeader(response)
+		return err == nil && responseId == id
+	}, nil
+}
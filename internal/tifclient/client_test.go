@@ -0,0 +1,159 @@
+package tifclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/emulator"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testDefinition = &tif.TifDefinition{
+	Methods: []tif.MethodDefinition{
+		{
+			Family:  "Battery",
+			Command: "GetCharge",
+			InParams: []tif.InputParameter{
+				{Name: "cell", Type: "uint8"},
+				{Name: "average", Type: "bool"},
+			},
+			OutParams: []tif.OutputParameter{{Name: "charge", Type: "uint16"}},
+			Protocol:  []tif.ProtocolEntry{{Key: "msgType", Value: "0x1234"}, {Key: "subCmd", Value: "1"}},
+		},
+		{
+			Family:          "System",
+			Command:         "Reset",
+			Protocol:        []tif.ProtocolEntry{{Key: "msgType", Value: "0x1000"}, {Key: "subCmd", Value: "2"}},
+			MaxResponseTime: "50",
+		},
+		{
+			Family:    "LinkManager",
+			Command:   "GetNodeInfo",
+			OutParams: []tif.OutputParameter{{Name: "responseId", Type: "uint8"}, {Name: "nodeType", Type: "uint8"}},
+			Protocol:  []tif.ProtocolEntry{{Key: "linked", Value: "true"}, {Key: "requestId", Value: "32"}, {Key: "responseId", Value: "33"}},
+		},
+	},
+}
+
+// openTestClient connects a client to an emulator of testDefinition.
+func openTestClient(t *testing.T, opts ...emulator.Option) *Client {
+	t.Helper()
+	return openTestClientFor(t, testDefinition, opts...)
+}
+
+// openTestClientFor connects a client to an emulator of def.
+func openTestClientFor(t *testing.T, def *tif.TifDefinition, opts ...emulator.Option) *Client {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	opts = append(opts, emulator.WithDefinition(def))
+	served := make(chan error, 1)
+	go func() {
+		served <- emulator.NewEmulator(log.New(io.Discard), opts...).Serve(ctx, listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to emulator: %v", err)
+	}
+	device := automower.NewDevice(conn, context.Background())
+	mux := linking.NewLinkMux(device, log.New(io.Discard))
+	go mux.Start()
+	t.Cleanup(func() {
+		mux.Stop()
+		cancel()
+		<-served
+	})
+
+	openCtx, openCancel := context.WithTimeout(context.Background(), time.Second)
+	defer openCancel()
+	client, err := NewClient(openCtx, mux, tif.NewIndex(def))
+	if err != nil {
+		t.Fatalf("expected no error opening client but got %v", err)
+	}
+	return client
+}
+
+func TestClientCall(t *testing.T) {
+	t.Parallel()
+	client := openTestClient(t,
+		emulator.WithResponses("Battery.GetCharge", emulator.Response{Params: []byte{0x10, 0x27}}),
+		emulator.WithResponses("LinkManager.GetNodeInfo", emulator.Response{Params: []byte{0x07}}),
+	)
+
+	tests := map[string]struct {
+		call     string
+		expected tif.Response
+	}{
+		"payload method": {
+			call:     "Battery.GetCharge(average: true, cell: 2)",
+			expected: tif.Response{Values: []tif.OutValue{{Name: "charge", Type: "uint16", Value: tif.TypeUint16(10000)}}},
+		},
+		"linked method": {
+			call:     "LinkManager.GetNodeInfo()",
+			expected: tif.Response{Values: []tif.OutValue{{Name: "nodeType", Type: "uint8", Value: tif.TypeUint8(7)}}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			_, response, err := client.CallString(context.Background(), test.call)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if diff := cmp.Diff(test.expected, response); diff != "" {
+				t.Errorf("CallString(%s) mismatch (-expected +got):\n%s", test.call, diff)
+			}
+		})
+	}
+}
+
+func TestClientCallErrors(t *testing.T) {
+	t.Parallel()
+	client := openTestClient(t,
+		emulator.WithResponses("Battery.GetCharge", emulator.Response{Status: 0x04}),
+		emulator.WithResponses("System.Reset", emulator.Response{Drop: true}),
+	)
+
+	_, response, err := client.CallString(context.Background(), "Battery.GetCharge(cell: 1, average: false)")
+	var statusErr *ErrStatus
+	if !errors.As(err, &statusErr) || statusErr.Status != 0x04 {
+		t.Errorf("expected error status 4 but got %v", err)
+	}
+	if response.Status != 0x04 {
+		t.Errorf("expected response with status 4 but got %d", response.Status)
+	}
+
+	// Reset has a maximum response time of 50ms, far shorter than the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	_, _, err = client.CallString(ctx, "System.Reset()")
+	var timeoutErr *ErrTimeout
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 50*time.Millisecond {
+		t.Errorf("expected timeout after 50ms but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected call to time out after the maximum response time but it took %s", elapsed)
+	}
+
+	_, _, err = client.CallString(context.Background(), "Battery.Unknown()")
+	if err == nil {
+		t.Error("expected error calling unknown method")
+	}
+}
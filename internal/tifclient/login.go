@@ -0,0 +1,126 @@
+package tifclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Tifufu/tools-cli/internal/tif"
+)
+
+// LoginCheck is what a client does before calling a method its session is not logged in to call.
+type LoginCheck int
+
+const (
+	// Call the method anyway, as if it had no login levels
+	LoginCheckOff LoginCheck = iota
+	// Call the method anyway, but log a warning first
+	LoginCheckWarn
+	// Refuse the call with an ErrLoginLevel without sending anything
+	LoginCheckEnforce
+)
+
+// ErrLoginLevel is returned when a method is called at a login level it may not be called at.
+type ErrLoginLevel struct {
+	Method string
+	Level  string
+	// Levels the method may be called at
+	Allowed []string
+}
+
+func (e *ErrLoginLevel) Error() string {
+	return fmt.Sprintf("%s requires login level %s but the session is logged in as %s", e.Method, joinLevels(e.Allowed), e.Level)
+}
+
+// Login performs the login handshake, a call written as Family.Command(param: value, ...),
+// and on success sets the login level of the session to level. Which method logs in, and
+// with which credentials, differs between devices, so it is given by the caller.
+//
+// The login call itself is never checked against the login level of the session.
+func (c *Client) Login(ctx context.Context, call string, level string) error {
+	err := c.checkKnownLevel(level)
+	if err != nil {
+		return err
+	}
+
+	name, args, err := tif.ParseCall(call)
+	if err != nil {
+		return err
+	}
+	method, ok := c.idx.Method(name)
+	if !ok {
+		return fmt.Errorf("could not find login method %s", name)
+	}
+	values, err := c.encoder.ParseArguments(method, args)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.call(ctx, method, values)
+	if err != nil {
+		return fmt.Errorf("failed to log in as %s: %w", level, err)
+	}
+	c.LoginLevel = level
+	return nil
+}
+
+// SetLoginLevel sets the login level the session is known to be at without logging in,
+// such as the level devices start sessions at. An empty level is unknown.
+func (c *Client) SetLoginLevel(level string) error {
+	if level != "" {
+		err := c.checkKnownLevel(level)
+		if err != nil {
+			return err
+		}
+	}
+	c.LoginLevel = level
+	return nil
+}
+
+// checkKnownLevel checks that the definition mentions level, unless it mentions no levels at all.
+func (c *Client) checkKnownLevel(level string) error {
+	levels := c.idx.Definition().LoginLevels()
+	if len(levels) > 0 && !containsLevel(levels, level) {
+		return fmt.Errorf("unknown login level %s. Must be one of [%s]", level, strings.Join(levels, " "))
+	}
+	return nil
+}
+
+// checkLoginLevel checks that the session may use method, which may be called at the allowed levels,
+// according to LoginCheck. Nothing is checked while the login level of the session is unknown.
+func (c *Client) checkLoginLevel(method string, allowed []string) error {
+	if c.LoginCheck == LoginCheckOff || c.LoginLevel == "" || len(allowed) == 0 || containsLevel(allowed, c.LoginLevel) {
+		return nil
+	}
+
+	err := &ErrLoginLevel{Method: method, Level: c.LoginLevel, Allowed: allowed}
+	if c.LoginCheck == LoginCheckEnforce {
+		return err
+	}
+	if c.Logger != nil {
+		c.Logger.Warn("Calling method above the login level of the session", "method", method, "level", c.LoginLevel, "requires", joinLevels(allowed))
+	}
+	return nil
+}
+
+// requiredLoginLevels returns the levels method may be called at if the session may not be at one of them,
+// to explain an error status the device likely responded with because of the login level.
+func (c *Client) requiredLoginLevels(method tif.MethodDefinition) []string {
+	if len(method.LoginLevels) == 0 || (c.LoginLevel != "" && method.AllowsLoginLevel(c.LoginLevel)) {
+		return nil
+	}
+	return method.LoginLevels
+}
+
+func containsLevel(levels []string, level string) bool {
+	for _, l := range levels {
+		if strings.EqualFold(l, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinLevels(levels []string) string {
+	return strings.Join(levels, " or ")
+}
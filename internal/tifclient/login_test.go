@@ -0,0 +1,224 @@
+package tifclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tifufu/tools-cli/internal/emulator"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-cmp/cmp"
+)
+
+const loginDefinitionJson = `{
+  "attributes-v2": [
+    {
+      "family": "Mower",
+      "name": "Serial",
+      "params": [{ "name": "serial", "type": "uint32" }],
+      "operations": ["read"],
+      "read": { "command": { "family": "Mower", "name": "GetSerial" } },
+      "protocol": { "read": { "loginLevels": ["Service"] } }
+    }
+  ],
+  "methods": [
+    {
+      "family": "Security",
+      "command": "Login",
+      "inParams": [{ "name": "code", "type": "uint16" }],
+      "protocol": [{ "key": "msgType", "value": "0x3000" }, { "key": "subCmd", "value": "1" }]
+    },
+    {
+      "family": "Mower",
+      "command": "GetMode",
+      "outParams": [{ "name": "mode", "type": "uint8" }],
+      "protocol": [{ "key": "msgType", "value": "0x3001" }, { "key": "subCmd", "value": "1" }],
+      "loginLevels": ["Operator", "Service"]
+    },
+    {
+      "family": "Mower",
+      "command": "SetMode",
+      "inParams": [{ "name": "mode", "type": "uint8" }],
+      "protocol": [{ "key": "msgType", "value": "0x3001" }, { "key": "subCmd", "value": "2" }],
+      "loginLevels": ["Service"]
+    },
+    {
+      "family": "Mower",
+      "command": "GetSerial",
+      "outParams": [{ "name": "serial", "type": "uint32" }],
+      "protocol": [{ "key": "msgType", "value": "0x3001" }, { "key": "subCmd", "value": "3" }],
+      "loginLevels": ["Service"]
+    }
+  ]
+}`
+
+// openLoginClient connects a client to an emulator of the login definition, whose connections
+// start at the Operator login level and log in at the Service level with Security.Login.
+func openLoginClient(t *testing.T) *Client {
+	t.Helper()
+
+	var def tif.TifDefinition
+	err := json.Unmarshal([]byte(loginDefinitionJson), &def)
+	if err != nil {
+		t.Fatalf("failed to parse definition: %v", err)
+	}
+	return openTestClientFor(t, &def,
+		emulator.WithLoginLevel("Operator"),
+		emulator.WithLogin("Security.Login", "Service"),
+		emulator.WithResponses("Mower.GetMode", emulator.Response{Params: []byte{0x02}}),
+		emulator.WithResponses("Mower.GetSerial", emulator.Response{Params: []byte{0x01, 0x00, 0x00, 0x00}}),
+	)
+}
+
+func TestClientLoginCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		check LoginCheck
+		level string
+		// Whether the call is refused without being sent
+		refused bool
+		// Levels in the ErrStatus of the status the emulator refuses the call with
+		statusLevels []string
+		warned       bool
+	}{
+		"enforced": {
+			check:   LoginCheckEnforce,
+			level:   "Operator",
+			refused: true,
+		},
+		// Let through, but the emulator has not logged the connection in as service
+		"enforced ignoring case": {
+			check:        LoginCheckEnforce,
+			level:        "service",
+			statusLevels: nil,
+		},
+		"warned": {
+			check:        LoginCheckWarn,
+			level:        "Operator",
+			statusLevels: []string{"Service"},
+			warned:       true,
+		},
+		"off": {
+			check:        LoginCheckOff,
+			level:        "Operator",
+			statusLevels: []string{"Service"},
+		},
+		"unknown level": {
+			check:        LoginCheckEnforce,
+			statusLevels: []string{"Service"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			client := openLoginClient(t)
+			var logs bytes.Buffer
+			client.Logger = log.New(&logs)
+			client.LoginCheck = test.check
+			client.LoginLevel = test.level
+
+			_, _, err := client.CallString(context.Background(), "Mower.SetMode(mode: 1)")
+
+			var levelErr *ErrLoginLevel
+			if refused := errors.As(err, &levelErr); refused != test.refused {
+				t.Fatalf("expected refused %t but got %v", test.refused, err)
+			}
+			if test.refused {
+				expected := &ErrLoginLevel{Method: "Mower.SetMode", Level: "Operator", Allowed: []string{"Service"}}
+				if diff := cmp.Diff(expected, levelErr); diff != "" {
+					t.Errorf("ErrLoginLevel mismatch (-expected +got):\n%s", diff)
+				}
+			}
+
+			var statusErr *ErrStatus
+			if !test.refused && !errors.As(err, &statusErr) {
+				t.Fatalf("expected the emulator to refuse the call but got %v", err)
+			}
+			if statusErr != nil {
+				if statusErr.Status != emulator.StatusLoginRequired {
+					t.Errorf("expected status %d but got %d", emulator.StatusLoginRequired, statusErr.Status)
+				}
+				if diff := cmp.Diff(test.statusLevels, statusErr.LoginLevels); diff != "" {
+					t.Errorf("ErrStatus login levels mismatch (-expected +got):\n%s", diff)
+				}
+			}
+
+			if warned := strings.Contains(logs.String(), "Mower.SetMode"); warned != test.warned {
+				t.Errorf("expected warned %t but got logs %q", test.warned, logs.String())
+			}
+		})
+	}
+}
+
+func TestClientLogin(t *testing.T) {
+	t.Parallel()
+	client := openLoginClient(t)
+	client.LoginCheck = LoginCheckEnforce
+	client.LoginLevel = "Operator"
+	ctx := context.Background()
+
+	_, _, err := client.CallString(ctx, "Mower.GetMode()")
+	if err != nil {
+		t.Errorf("expected operator to call Mower.GetMode but got %v", err)
+	}
+	_, _, err = client.ReadAttribute(ctx, "Mower.Serial", nil)
+	var levelErr *ErrLoginLevel
+	if !errors.As(err, &levelErr) || levelErr.Method != "Mower.Serial" {
+		t.Errorf("expected operator not to read Mower.Serial but got %v", err)
+	}
+
+	err = client.Login(ctx, "Security.Login(code: 1234)", "Admin")
+	if err == nil || !strings.Contains(err.Error(), "unknown login level") {
+		t.Errorf("expected unknown login level error but got %v", err)
+	}
+	if client.LoginLevel != "Operator" {
+		t.Errorf("expected failed login to keep level Operator but got %s", client.LoginLevel)
+	}
+
+	err = client.Login(ctx, "Security.Login(code: 1234)", "Service")
+	if err != nil {
+		t.Fatalf("expected no error logging in but got %v", err)
+	}
+	if client.LoginLevel != "Service" {
+		t.Errorf("expected level Service after login but got %s", client.LoginLevel)
+	}
+
+	_, _, err = client.CallString(ctx, "Mower.SetMode(mode: 1)")
+	if err != nil {
+		t.Errorf("expected service to call Mower.SetMode but got %v", err)
+	}
+	_, response, err := client.ReadAttribute(ctx, "Mower.Serial", nil)
+	if err != nil {
+		t.Fatalf("expected service to read Mower.Serial but got %v", err)
+	}
+	expected := tif.Response{Values: []tif.OutValue{{Name: "serial", Type: "uint32", Value: tif.TypeUint32(1)}}}
+	if diff := cmp.Diff(expected, response); diff != "" {
+		t.Errorf("ReadAttribute(Mower.Serial) mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestClientReadAttributeWarnsOnce(t *testing.T) {
+	t.Parallel()
+	client := openLoginClient(t)
+	var logs bytes.Buffer
+	client.Logger = log.New(&logs)
+	client.LoginCheck = LoginCheckWarn
+	client.LoginLevel = "Operator"
+
+	_, _, err := client.ReadAttribute(context.Background(), "Mower.Serial", nil)
+	var statusErr *ErrStatus
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected the emulator to refuse the read but got %v", err)
+	}
+	if warnings := strings.Count(logs.String(), "login level"); warnings != 1 {
+		t.Errorf("expected one warning but got %d in logs %q", warnings, logs.String())
+	}
+}
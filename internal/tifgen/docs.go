@@ -0,0 +1,513 @@
+package tifgen
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Tifufu/tools-cli/internal/tif"
+)
+
+//go:embed templates/docs.html.tmpl
+var htmlDocsTemplateText string
+
+//go:embed templates/docs.md.tmpl
+var markdownDocsTemplateText string
+
+//go:embed templates/docs.css
+var docsStyle []byte
+
+//go:embed templates/docs-search.js
+var docsSearchScript []byte
+
+var htmlDocsTemplate = htmltemplate.Must(htmltemplate.New("docs").Parse(htmlDocsTemplateText))
+
+var markdownDocsTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"cell": markdownCell,
+}).Parse(markdownDocsTemplateText))
+
+// DocsFormat is the format documentation is generated in.
+type DocsFormat string
+
+const (
+	DocsHTML     DocsFormat = "html"
+	DocsMarkdown DocsFormat = "md"
+)
+
+// DocsFile is a file of generated documentation.
+type DocsFile struct {
+	// Path relative to the root of the site, using forward slashes
+	Path    string
+	Content []byte
+}
+
+// SearchEntry is a method, attribute, type or enumerator in the search index of generated documentation.
+type SearchEntry struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+	// Link relative to the root of the site
+	Href string `json:"href"`
+}
+
+// Encodings of primitives, shown on the types page.
+var primitiveDocs = []struct {
+	name        string
+	description string
+}{
+	{"uint8", "Unsigned 8-bit integer."},
+	{"uint16", "Unsigned 16-bit integer, little endian."},
+	{"uint32", "Unsigned 32-bit integer, little endian."},
+	{"uint64", "Unsigned 64-bit integer, little endian."},
+	{"sint8", "Signed 8-bit integer."},
+	{"sint16", "Signed 16-bit integer, little endian."},
+	{"sint32", "Signed 32-bit integer, little endian."},
+	{"sint64", "Signed 64-bit integer, little endian."},
+	{"bool", "One byte, 0 for false and anything else for true."},
+	{"float", "IEEE 754 single precision float, little endian."},
+	{"bit", "Eight flags in one byte."},
+	{"tUnixTime", "Seconds since 1970-01-01 UTC as a signed 32-bit integer, little endian."},
+	{"tSimpleVersion", "Version as a 16-bit integer, little endian, with the major version in the high byte."},
+	{"dateTime", "Year as a 16-bit integer, little endian, followed by month, day, hour, minute and second bytes."},
+	{"ascii", "ASCII text, null-terminated unless it has a length."},
+	{"tUCS2", "UCS-2 text of 16-bit characters, little endian, null-terminated unless it has a length."},
+	{"byteArray", "Raw bytes, the rest of the payload unless it has a length."},
+}
+
+// Views of the definition, shared by the templates of every format.
+type (
+	docsSite struct {
+		Protocol string
+		Versions string
+		// Extension of pages, including the dot
+		Ext          string
+		Families     []*docsFamily
+		Types        []*docsType
+		Primitives   []*docsType
+		MethodCount  int
+		AttrCount    int
+		SearchScript bool
+	}
+
+	docsPage struct {
+		Site   *docsSite
+		Title  string
+		Family *docsFamily
+	}
+
+	docsLink struct {
+		Text string
+		// Empty if there is nothing to link to, such as an unknown type
+		Href string
+	}
+
+	docsFamily struct {
+		Name       string
+		File       string
+		Methods    []*docsMethod
+		Attributes []*docsAttribute
+	}
+
+	docsMethod struct {
+		Name            string
+		Command         string
+		Anchor          string
+		Href            string
+		Description     string
+		Signature       string
+		Protocol        string
+		InParams        []docsParam
+		OutParams       []docsParam
+		LoginLevels     []string
+		Tags            []string
+		MaxResponseTime string
+		UsedBy          []docsLink
+	}
+
+	docsAttribute struct {
+		Name        string
+		FullName    string
+		Anchor      string
+		Description string
+		Params      []docsParam
+		Operations  []string
+		Commands    []docsCommand
+		LoginLevels []string
+		Tags        []string
+	}
+
+	docsCommand struct {
+		Operation string
+		Method    docsLink
+	}
+
+	docsParam struct {
+		Name   string
+		Type   docsLink
+		Length string
+		Unit   string
+		Tags   []string
+	}
+
+	docsType struct {
+		Name        string
+		Anchor      string
+		Description string
+		Base        docsLink
+		Kind        string
+		Unit        string
+		Range       string
+		Enumerators []tif.EnumDefinition
+		UsedBy      []docsLink
+	}
+)
+
+type docsGenerator struct {
+	def    *tif.TifDefinition
+	idx    *tif.Index
+	types  *tif.TypeRegistry
+	ext    string
+	site   *docsSite
+	search []SearchEntry
+	// Types-v2 types and primitives by name
+	typeDocs map[string]*docsType
+	// Methods by lower case name, for links from attributes
+	methods map[string]*docsMethod
+	// Families by lower case name, as methods are looked up ignoring case
+	families map[string]*docsFamily
+}
+
+// GenerateDocs renders the families, methods, attributes and types of the indexed definition as a site of cross-linked pages:
+// an index, a page per family and a page of types. The site includes a search index of every element,
+// and HTML sites a script searching it.
+func GenerateDocs(idx *tif.Index, format DocsFormat) ([]DocsFile, error) {
+	def := idx.Definition()
+	g := &docsGenerator{
+		def:      def,
+		idx:      idx,
+		types:    tif.NewTypeRegistry(def),
+		typeDocs: make(map[string]*docsType),
+		methods:  make(map[string]*docsMethod),
+		families: make(map[string]*docsFamily),
+	}
+	switch format {
+	case DocsHTML:
+		g.ext = ".html"
+	case DocsMarkdown:
+		g.ext = ".md"
+	default:
+		return nil, fmt.Errorf("unknown documentation format %s", format)
+	}
+	g.site = &docsSite{
+		Protocol:     def.Header.ProtocolName,
+		Versions:     strings.Join(def.Header.TifVersions, ", "),
+		Ext:          g.ext,
+		SearchScript: format == DocsHTML,
+	}
+	if g.site.Protocol == "" {
+		g.site.Protocol = "TIF"
+	}
+
+	// Types come first so that parameters can link to them and be listed as their users
+	g.addTypes()
+	g.addMethods()
+	g.addAttributes()
+	g.sort()
+
+	var files []DocsFile
+	render := func(path, name string, page docsPage) error {
+		var buf bytes.Buffer
+		var err error
+		if format == DocsHTML {
+			err = htmlDocsTemplate.ExecuteTemplate(&buf, name, page)
+		} else {
+			err = markdownDocsTemplate.ExecuteTemplate(&buf, name, page)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", path, err)
+		}
+		files = append(files, DocsFile{Path: path, Content: buf.Bytes()})
+		return nil
+	}
+
+	err := render("index"+g.ext, "index", docsPage{Site: g.site, Title: g.site.Protocol})
+	if err != nil {
+		return nil, err
+	}
+	err = render("types"+g.ext, "types", docsPage{Site: g.site, Title: "Types"})
+	if err != nil {
+		return nil, err
+	}
+	for _, family := range g.site.Families {
+		err = render(family.File, "family", docsPage{Site: g.site, Title: family.Name, Family: family})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	index, err := json.Marshal(g.search)
+	if err != nil {
+		return nil, err
+	}
+	if format == DocsHTML {
+		// A script instead of JSON, so that the search also works when pages are opened from disk
+		script := append([]byte("var tifSearchIndex = "), index...)
+		script = append(script, ";\n"...)
+		files = append(files,
+			DocsFile{Path: "search-index.js", Content: script},
+			DocsFile{Path: "search.js", Content: docsSearchScript},
+			DocsFile{Path: "style.css", Content: docsStyle},
+		)
+	} else {
+		files = append(files, DocsFile{Path: "search-index.json", Content: append(index, '\n')})
+	}
+	return files, nil
+}
+
+func (g *docsGenerator) addTypes() {
+	for _, primitive := range primitiveDocs {
+		t := &docsType{
+			Name:        primitive.name,
+			Anchor:      "primitive-" + slug(primitive.name),
+			Description: primitive.description,
+			Kind:        "primitive",
+		}
+		g.typeDocs[t.Name] = t
+		g.site.Primitives = append(g.site.Primitives, t)
+	}
+
+	for _, definition := range g.def.TypesV2 {
+		// Like lookups, only the first of types with the same name is used
+		if _, ok := g.typeDocs[definition.Name]; ok {
+			continue
+		}
+		t := &docsType{
+			Name:        definition.Name,
+			Anchor:      slug(definition.Name),
+			Description: definition.Description,
+			Unit:        definition.Postfix,
+			Enumerators: definition.Range.Enums,
+		}
+		switch named, _ := g.types.Lookup(definition.Name); named.(type) {
+		case *tif.TEnum:
+			t.Kind = "enum"
+		case *tif.TRange:
+			t.Kind = "range"
+			t.Range = fmt.Sprintf("%d to %d", definition.Range.Start, definition.Range.Stop)
+		case *tif.TSimple:
+			t.Kind = "simple"
+		default:
+			t.Kind = "unresolved"
+		}
+		g.typeDocs[t.Name] = t
+		g.site.Types = append(g.site.Types, t)
+
+		href := "types" + g.ext + "#" + t.Anchor
+		g.search = append(g.search, SearchEntry{Name: t.Name, Kind: "type", Description: t.Description, Href: href})
+		for _, enum := range t.Enumerators {
+			g.search = append(g.search, SearchEntry{
+				Name:        fmt.Sprintf("%s.%s", t.Name, enum.Key),
+				Kind:        "enumerator",
+				Description: enum.Description,
+				Href:        href,
+			})
+		}
+	}
+
+	// Bases are linked once every type is known, as types may be based on types defined after them
+	for _, t := range g.site.Types {
+		definition, _ := g.idx.Type(t.Name)
+		t.Base = g.typeLink(definition.Type)
+	}
+}
+
+func (g *docsGenerator) addMethods() {
+	for _, method := range g.def.Methods {
+		key := strings.ToLower(method.Name())
+		if _, ok := g.methods[key]; ok {
+			continue
+		}
+		family := g.family(method.Family)
+
+		m := &docsMethod{
+			Name:            method.Name(),
+			Command:         method.Command,
+			Anchor:          slug(method.Command),
+			Description:     method.Description,
+			Signature:       method.Signature(),
+			Protocol:        protocolOf(method),
+			LoginLevels:     method.LoginLevels,
+			Tags:            method.Tags,
+			MaxResponseTime: method.MaxResponseTime,
+		}
+		if timeout, ok := method.ResponseTimeout(); ok {
+			m.MaxResponseTime = timeout.String()
+		}
+		m.Href = family.File + "#" + m.Anchor
+		self := docsLink{Text: m.Name, Href: m.Href}
+		for _, param := range method.InParams {
+			m.InParams = append(m.InParams, g.param(param.Name, param.Type, param.Length, nil, self))
+		}
+		for _, param := range method.OutParams {
+			m.OutParams = append(m.OutParams, g.param(param.Name, param.Type, param.Length, param.Tags, self))
+		}
+
+		g.methods[key] = m
+		family.Methods = append(family.Methods, m)
+		g.site.MethodCount++
+		g.search = append(g.search, SearchEntry{Name: m.Name, Kind: "method", Description: m.Description, Href: self.Href})
+	}
+}
+
+func (g *docsGenerator) addAttributes() {
+	seen := make(map[string]bool, len(g.def.AttributesV2))
+	for _, attr := range g.def.AttributesV2 {
+		key := strings.ToLower(attr.FullName())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		family := g.family(attr.Family)
+
+		a := &docsAttribute{
+			Name:        attr.Name,
+			FullName:    attr.FullName(),
+			Anchor:      "attribute-" + slug(attr.Name),
+			Description: attr.Description,
+			Operations:  attr.Operations,
+			LoginLevels: attr.Protocol.Read.LoginLevels,
+			Tags:        attr.Tags,
+		}
+		self := docsLink{Text: a.FullName, Href: family.File + "#" + a.Anchor}
+		for _, param := range attr.Params {
+			a.Params = append(a.Params, g.param(param.Name, param.Type, "", nil, self))
+		}
+
+		commands := []struct{ operation, name string }{}
+		if command, ok := attr.ReadCommand(); ok {
+			commands = append(commands, struct{ operation, name string }{"read", command})
+		}
+		if command, ok := attr.WriteCommand(); ok {
+			commands = append(commands, struct{ operation, name string }{"write", command})
+		}
+		if attr.List.Family != "" || attr.List.Name != "" {
+			commands = append(commands, struct{ operation, name string }{"list", fmt.Sprintf("%s.%s", attr.List.Family, attr.List.Name)})
+		}
+		for _, command := range commands {
+			link := docsLink{Text: command.name}
+			if method, ok := g.methods[strings.ToLower(command.name)]; ok {
+				link = docsLink{Text: method.Name, Href: method.Href}
+				method.UsedBy = append(method.UsedBy, docsLink{Text: fmt.Sprintf("%s (%s)", self.Text, command.operation), Href: self.Href})
+			}
+			a.Commands = append(a.Commands, docsCommand{Operation: command.operation, Method: link})
+		}
+
+		family.Attributes = append(family.Attributes, a)
+		g.site.AttrCount++
+		g.search = append(g.search, SearchEntry{Name: a.FullName, Kind: "attribute", Description: a.Description, Href: self.Href})
+	}
+}
+
+func (g *docsGenerator) family(name string) *docsFamily {
+	key := strings.ToLower(name)
+	if family, ok := g.families[key]; ok {
+		return family
+	}
+	family := &docsFamily{Name: name, File: "family-" + slug(name) + g.ext}
+	g.families[key] = family
+	g.site.Families = append(g.site.Families, family)
+	return family
+}
+
+// param describes a parameter of user, listing user as a user of the parameter's type.
+func (g *docsGenerator) param(name, typeName string, length tif.FlexString, tags []string, user docsLink) docsParam {
+	param := docsParam{
+		Name:   name,
+		Type:   g.typeLink(typeName),
+		Length: lengthOf(length),
+		Unit:   g.types.Postfix(typeName),
+		Tags:   tags,
+	}
+	if t, ok := g.typeDocs[typeName]; ok && t.Kind != "primitive" {
+		if !slices.Contains(t.UsedBy, user) {
+			t.UsedBy = append(t.UsedBy, user)
+		}
+	}
+	return param
+}
+
+func (g *docsGenerator) typeLink(typeName string) docsLink {
+	t, ok := g.typeDocs[typeName]
+	if !ok {
+		return docsLink{Text: typeName}
+	}
+	return docsLink{Text: typeName, Href: "types" + g.ext + "#" + t.Anchor}
+}
+
+// sort orders families, their methods and attributes, and types by name, ignoring case.
+func (g *docsGenerator) sort() {
+	byName := func(a, b string) int { return cmp.Compare(strings.ToLower(a), strings.ToLower(b)) }
+	slices.SortFunc(g.site.Families, func(a, b *docsFamily) int { return byName(a.Name, b.Name) })
+	for _, family := range g.site.Families {
+		slices.SortFunc(family.Methods, func(a, b *docsMethod) int { return byName(a.Command, b.Command) })
+		slices.SortFunc(family.Attributes, func(a, b *docsAttribute) int { return byName(a.Name, b.Name) })
+	}
+	slices.SortFunc(g.site.Types, func(a, b *docsType) int { return byName(a.Name, b.Name) })
+	slices.SortFunc(g.search, func(a, b SearchEntry) int { return byName(a.Name, b.Name) })
+}
+
+func protocolOf(method tif.MethodDefinition) string {
+	if method.IsLinked() {
+		request, response, err := method.RequestId()
+		if err != nil {
+			return "link manager"
+		}
+		return fmt.Sprintf("link manager, request id %d, response id %d", request, response)
+	}
+	id, err := method.MethodId()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("message type 0x%04X, sub command %d", id.MsgType, id.SubCmd)
+}
+
+func lengthOf(length tif.FlexString) string {
+	switch length {
+	case "", "0":
+		return ""
+	case "-1":
+		return "rest of payload"
+	default:
+		return string(length)
+	}
+}
+
+// slug turns a name into an anchor or file name, such as "Park until further notice" into "park-until-further-notice".
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// markdownCell escapes text to fit in a cell of a Markdown table.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
@@ -0,0 +1,131 @@
+package tifgen
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	hrefPattern   = regexp.MustCompile(`href="([^"]+)"|\]\(([^)]+)\)`)
+	anchorPattern = regexp.MustCompile(`id="([^"]+)"`)
+)
+
+func TestGenerateDocsLinks(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []DocsFormat{DocsHTML, DocsMarkdown} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			files, err := GenerateDocs(loadSampleIndex(t), format)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			pages := make(map[string]string, len(files))
+			for _, file := range files {
+				pages[file.Path] = string(file.Content)
+			}
+
+			// Every link must point to a generated file, and to an anchor in it
+			for path, content := range pages {
+				for _, match := range hrefPattern.FindAllStringSubmatch(content, -1) {
+					href := match[1] + match[2]
+					file, anchor, _ := strings.Cut(href, "#")
+					if file == "" {
+						file = path
+					}
+					target, ok := pages[file]
+					if !ok {
+						t.Errorf("%s links to %s, which was not generated", path, href)
+						continue
+					}
+					if anchor != "" && !hasAnchor(target, anchor) {
+						t.Errorf("%s links to %s, which has no anchor %s", path, file, anchor)
+					}
+				}
+			}
+
+			page := pages["family-mower."+string(format)]
+			for _, expected := range []string{"Operator, Service", "status", "500ms", "message type 0x2000, sub command 1", "Updated every second."} {
+				if !strings.Contains(page, expected) {
+					t.Errorf("expected Mower page to contain %q", expected)
+				}
+			}
+		})
+	}
+}
+
+func hasAnchor(content, anchor string) bool {
+	for _, match := range anchorPattern.FindAllStringSubmatch(content, -1) {
+		if match[1] == anchor {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGenerateDocsSearchIndex(t *testing.T) {
+	t.Parallel()
+
+	files, err := GenerateDocs(loadSampleIndex(t), DocsMarkdown)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	var index []SearchEntry
+	for _, file := range files {
+		if file.Path == "search-index.json" {
+			err = json.Unmarshal(file.Content, &index)
+			if err != nil {
+				t.Fatalf("failed to parse search index: %v", err)
+			}
+		}
+	}
+
+	var names []string
+	for _, entry := range index {
+		names = append(names, entry.Kind+" "+entry.Name)
+	}
+	expected := []string{
+		"method LinkManager.GetNodeInfo",
+		"method Mower.GetStatus",
+		"attribute Mower.Mode",
+		"method Mower.SetMode",
+		"method Mower.SetName",
+		"method System.GetInfo",
+		"type tCuttingHeight",
+		"type tMode",
+		"enumerator tMode.Auto",
+		"enumerator tMode.Manual",
+		"enumerator tMode.Park until further notice",
+		"type tPercent",
+	}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("search index mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestGenerateDocsMarkdownTables(t *testing.T) {
+	t.Parallel()
+
+	files, err := GenerateDocs(loadSampleIndex(t), DocsMarkdown)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	for _, file := range files {
+		if file.Path != "types.md" {
+			continue
+		}
+		expected := "| `Park until further notice` | 2 |  |"
+		if !strings.Contains(string(file.Content), expected) {
+			t.Errorf("expected types page to contain %q", expected)
+		}
+	}
+	if got := markdownCell("a | b\nc"); got != `a \| b<br>c` {
+		t.Errorf("markdownCell escaped to %q", got)
+	}
+}
@@ -0,0 +1,403 @@
+// Package tifgen generates code for calling the methods of a tif definition, and documentation of them.
+package tifgen
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/tif"
+)
+
+//go:embed templates/go.tmpl
+var goTemplateText string
+
+var goTemplate = template.Must(template.New("go").Parse(goTemplateText))
+
+// goPrimitive is how values of a primitive are represented and encoded in generated Go code.
+type goPrimitive struct {
+	// Type of the values in the generated API
+	goType string
+	// Call of the generated encoder appending a value, formatted with the parameter name,
+	// an expression of goType and the length of the parameter
+	encode string
+	// Call of the generated decoder reading a value of goType, formatted with the length of the parameter if sized
+	decode string
+	// Whether the length of a parameter sets the size of its values, instead of making it an array
+	sized bool
+}
+
+var goPrimitives = map[string]goPrimitive{
+	"uint8":          {goType: "uint8", encode: "e.uint8(%[2]s)", decode: "d.uint8()"},
+	"uint16":         {goType: "uint16", encode: "e.uint16(%[2]s)", decode: "d.uint16()"},
+	"uint32":         {goType: "uint32", encode: "e.uint32(%[2]s)", decode: "d.uint32()"},
+	"uint64":         {goType: "uint64", encode: "e.uint64(%[2]s)", decode: "d.uint64()"},
+	"sint8":          {goType: "int8", encode: "e.uint8(uint8(%[2]s))", decode: "int8(d.uint8())"},
+	"sint16":         {goType: "int16", encode: "e.uint16(uint16(%[2]s))", decode: "int16(d.uint16())"},
+	"sint32":         {goType: "int32", encode: "e.uint32(uint32(%[2]s))", decode: "int32(d.uint32())"},
+	"sint64":         {goType: "int64", encode: "e.uint64(uint64(%[2]s))", decode: "int64(d.uint64())"},
+	"bool":           {goType: "bool", encode: "e.bool(%[2]s)", decode: "d.bool()"},
+	"float":          {goType: "float32", encode: "e.float(%[2]s)", decode: "d.float()"},
+	"bit":            {goType: "uint8", encode: "e.uint8(%[2]s)", decode: "d.uint8()"},
+	"tSimpleVersion": {goType: "uint16", encode: "e.uint16(%[2]s)", decode: "d.uint16()"},
+	"tUnixTime":      {goType: "time.Time", encode: "e.unixTime(%[1]q, %[2]s)", decode: "d.unixTime()"},
+	"dateTime":       {goType: "time.Time", encode: "e.dateTime(%[1]q, %[2]s)", decode: "d.dateTime()"},
+	"ascii":          {goType: "string", encode: "e.ascii(%[1]q, %[2]s, %[3]d)", decode: "d.ascii(%d)", sized: true},
+	"tUCS2":          {goType: "string", encode: "e.ucs2(%[1]q, %[2]s, %[3]d)", decode: "d.ucs2(%d)", sized: true},
+	"byteArray":      {goType: "[]byte", encode: "e.bytes(%[1]q, %[2]s, %[3]d)", decode: "d.bytes(%d)", sized: true},
+}
+
+// Special values of the length of a string or array parameter, like in tif.
+const (
+	lengthNullTerminated = 0
+	lengthRemaining      = -1
+)
+
+type goFile struct {
+	Package  string
+	Versions string
+	Types    []goType
+	Families []goFamily
+}
+
+type goType struct {
+	Name       string
+	Doc        []string
+	Underlying string
+	Enums      []goEnum
+}
+
+type goEnum struct {
+	Name  string
+	Key   string
+	Value int
+}
+
+type goFamily struct {
+	Name    string
+	Type    string
+	Methods []goMethod
+}
+
+type goMethod struct {
+	Name    string
+	TifName string
+	Doc     []string
+	// Literal of the generated method struct, with the ids and maximum response time of the method
+	Method   string
+	Params   []goParam
+	Response string
+	Fields   []goField
+}
+
+type goParam struct {
+	Name   string
+	Type   string
+	Encode string
+}
+
+type goField struct {
+	Name    string
+	Type    string
+	Comment string
+	Decode  string
+}
+
+// goGenerator resolves the types of a definition to their Go types while building a goFile.
+type goGenerator struct {
+	def   *tif.TifDefinition
+	types *tif.TypeRegistry
+	names nameSet
+	// Go type names of types-v2 types
+	typeNames map[string]string
+}
+
+// Exported identifiers of the generated code that do not depend on the definition.
+var reservedNames = []string{"Client", "NewClient", "Link", "DefaultResponseTimeout", "ErrStatus", "ErrTimeout"}
+
+// GenerateGo returns the source of a Go package with a typed function for each method of the indexed definition,
+// and a Go type for each types-v2 type. The ids and parameters of methods are part of the generated code, which
+// encodes calls and decodes responses itself and sends them on a Link, so it only imports the standard library.
+//
+// Types that have no Go type, such as stale types of an unknown primitive, are left out.
+// Generating only fails because of them if a method has a parameter of one.
+func GenerateGo(idx *tif.Index, pkg string) ([]byte, error) {
+	def := idx.Definition()
+	g := &goGenerator{
+		def:       def,
+		types:     tif.NewTypeRegistry(def),
+		names:     nameSet{},
+		typeNames: make(map[string]string),
+	}
+	for _, name := range reservedNames {
+		g.names[name] = true
+	}
+
+	file := goFile{
+		Package:  pkg,
+		Versions: strings.Join(def.Header.TifVersions, ", "),
+	}
+	for _, t := range def.TypesV2 {
+		if _, ok := g.typeNames[t.Name]; ok {
+			continue
+		}
+		goType, err := g.goType(t)
+		if err != nil {
+			continue
+		}
+		file.Types = append(file.Types, goType)
+	}
+
+	families, err := g.families()
+	if err != nil {
+		return nil, err
+	}
+	file.Families = families
+
+	var buf bytes.Buffer
+	err = goTemplate.Execute(&buf, file)
+	if err != nil {
+		return nil, err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return source, nil
+}
+
+func (g *goGenerator) goType(t tif.TypeV2Definition) (goType, error) {
+	primitiveName, err := g.types.Primitive(t.Name)
+	if err != nil {
+		return goType{}, fmt.Errorf("type %s: %w", t.Name, err)
+	}
+	primitive, ok := goPrimitives[primitiveName]
+	if !ok {
+		return goType{}, fmt.Errorf("type %s: primitive %s has no Go type", t.Name, primitiveName)
+	}
+
+	name := g.names.unique(exportedName(t.Name))
+	g.typeNames[t.Name] = name
+	generated := goType{
+		Name:       name,
+		Underlying: primitive.goType,
+		Doc:        commentLines(fmt.Sprintf("%s is %s", name, describeType(t)), t.Description),
+	}
+
+	if _, ok := g.types.Enum(t.Name); !ok || !isIntegerPrimitive(primitiveName) {
+		return generated, nil
+	}
+	for _, enumerator := range t.Range.Enums {
+		generated.Enums = append(generated.Enums, goEnum{
+			Name:  g.names.unique(name + exportedName(enumerator.Key)),
+			Key:   enumerator.Key,
+			Value: enumerator.Value,
+		})
+	}
+	return generated, nil
+}
+
+func describeType(t tif.TypeV2Definition) string {
+	switch {
+	case len(t.Range.Enums) > 0 || t.Range.Type == "enum":
+		return fmt.Sprintf("the tif enum %s.", t.Name)
+	case t.Range.Type == "range":
+		unit := ""
+		if t.Postfix != "" {
+			unit = " " + t.Postfix
+		}
+		return fmt.Sprintf("the tif type %s, from %d to %d%s.", t.Name, t.Range.Start, t.Range.Stop, unit)
+	case t.Postfix != "":
+		return fmt.Sprintf("the tif type %s, in %s.", t.Name, t.Postfix)
+	default:
+		return fmt.Sprintf("the tif type %s.", t.Name)
+	}
+}
+
+func isIntegerPrimitive(primitive string) bool {
+	switch primitive {
+	case "uint8", "uint16", "uint32", "uint64", "sint8", "sint16", "sint32", "sint64":
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *goGenerator) families() ([]goFamily, error) {
+	var families []goFamily
+	seen := make(map[string]bool, len(g.def.Methods))
+	for _, method := range g.def.Methods {
+		// Like lookups, only the first of methods with the same name is used
+		key := strings.ToLower(method.Name())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		i := slices.IndexFunc(families, func(f goFamily) bool { return f.Name == exportedName(method.Family) })
+		if i < 0 {
+			families = append(families, goFamily{
+				Name: exportedName(method.Family),
+				Type: g.names.unique(exportedName(method.Family) + "Family"),
+			})
+			i = len(families) - 1
+		}
+
+		generated, err := g.method(method, families[i].Methods)
+		if err != nil {
+			return nil, err
+		}
+		families[i].Methods = append(families[i].Methods, generated)
+	}
+	return families, nil
+}
+
+func (g *goGenerator) method(method tif.MethodDefinition, siblings []goMethod) (goMethod, error) {
+	methodNames := nameSet{}
+	for _, sibling := range siblings {
+		methodNames[sibling.Name] = true
+	}
+	literal, err := methodLiteral(method)
+	if err != nil {
+		return goMethod{}, err
+	}
+	generated := goMethod{
+		Name:    methodNames.unique(exportedName(method.Command)),
+		TifName: method.Name(),
+		Doc:     commentLines(fmt.Sprintf("%s calls %s.", exportedName(method.Command), method.Name()), method.Description),
+		Method:  literal,
+	}
+
+	paramNames := nameSet{}
+	for i, param := range method.InParams {
+		goType, primitive, length, err := g.param(param.Type, param.Length, i == len(method.InParams)-1)
+		if err != nil {
+			return goMethod{}, fmt.Errorf("parameter %s of %s: %w", param.Name, method.Name(), err)
+		}
+		name := paramNames.unique(localName(param.Name))
+		value := name
+		if goType != primitive.goType {
+			// Values of named types are encoded through the Go type of their primitive
+			value = primitive.goType + "(" + name + ")"
+		}
+		generated.Params = append(generated.Params, goParam{
+			Name:   name,
+			Type:   goType,
+			Encode: fmt.Sprintf(primitive.encode, param.Name, value, length),
+		})
+	}
+
+	outParams := method.ResponseParams()
+	if len(outParams) == 0 {
+		return generated, nil
+	}
+	generated.Response = g.names.unique(exportedName(method.Family) + exportedName(method.Command) + "Response")
+	fieldNames := nameSet{}
+	for i, param := range outParams {
+		goType, primitive, length, err := g.param(param.Type, param.Length, i == len(outParams)-1)
+		if err != nil {
+			return goMethod{}, fmt.Errorf("out parameter %s of %s: %w", param.Name, method.Name(), err)
+		}
+		decode := primitive.decode
+		if primitive.sized {
+			decode = fmt.Sprintf(primitive.decode, length)
+		}
+		if goType != primitive.goType {
+			decode = goType + "(" + decode + ")"
+		}
+		field := goField{
+			Name:   fieldNames.unique(exportedName(param.Name)),
+			Type:   goType,
+			Decode: decode,
+		}
+		if postfix := g.types.Postfix(param.Type); postfix != "" {
+			field.Comment = "In " + postfix
+		}
+		generated.Fields = append(generated.Fields, field)
+	}
+	return generated, nil
+}
+
+// methodLiteral returns the literal of the generated method struct that calls method.
+func methodLiteral(method tif.MethodDefinition) (string, error) {
+	fields := []string{fmt.Sprintf("name: %q", method.Name())}
+	if method.IsLinked() {
+		requestId, responseId, err := method.RequestId()
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, "linked: true", fmt.Sprintf("requestId: %d", requestId), fmt.Sprintf("responseId: %d", responseId))
+	} else {
+		id, err := method.MethodId()
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, fmt.Sprintf("msgType: 0x%04X", id.MsgType), fmt.Sprintf("subCmd: %d", id.SubCmd))
+	}
+	if timeout, ok := method.ResponseTimeout(); ok {
+		if timeout%time.Millisecond == 0 {
+			fields = append(fields, fmt.Sprintf("timeout: %d * time.Millisecond", timeout/time.Millisecond))
+		} else {
+			fields = append(fields, fmt.Sprintf("timeout: %d", timeout))
+		}
+	}
+	return "method{" + strings.Join(fields, ", ") + "}", nil
+}
+
+// param returns the Go type and primitive of a parameter of a tif type, and the length its values are
+// encoded and decoded with. Only the last parameter may take up the rest of the payload.
+func (g *goGenerator) param(typeName string, lengthValue tif.FlexString, last bool) (goType string, primitive goPrimitive, length int, err error) {
+	primitiveName, err := g.types.Primitive(typeName)
+	if err != nil {
+		return "", goPrimitive{}, 0, err
+	}
+	primitive, ok := goPrimitives[primitiveName]
+	if !ok {
+		return "", goPrimitive{}, 0, fmt.Errorf("primitive %s has no Go type", primitiveName)
+	}
+
+	length = lengthNullTerminated
+	if lengthValue != "" {
+		length, err = strconv.Atoi(string(lengthValue))
+		if err != nil || length < lengthRemaining {
+			return "", goPrimitive{}, 0, fmt.Errorf("invalid length %q", lengthValue)
+		}
+	}
+	if !primitive.sized && length > 0 {
+		return "", goPrimitive{}, 0, fmt.Errorf("arrays of %s are not supported", primitiveName)
+	}
+	// Byte arrays have no terminator, without a length they take up the rest of the payload
+	if primitiveName == "byteArray" && length <= 0 {
+		length = lengthRemaining
+	}
+	if primitive.sized && length == lengthRemaining && !last {
+		return "", goPrimitive{}, 0, errors.New("takes up the rest of the payload but is not the last parameter")
+	}
+
+	goType = primitive.goType
+	if name, ok := g.typeNames[typeName]; ok {
+		goType = name
+	}
+	return goType, primitive, length, nil
+}
+
+// commentLines returns the lines of a doc comment starting with summary, followed by a description.
+func commentLines(summary, description string) []string {
+	lines := []string{summary}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return lines
+	}
+	lines = append(lines, "")
+	for _, line := range strings.Split(description, "\n") {
+		lines = append(lines, strings.TrimRight(line, " \r\t"))
+	}
+	return lines
+}
@@ -0,0 +1,164 @@
+package tifgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "update the golden files of generated code")
+
+// The golden file of the sample definition is a package of its own, so that it is also compiled and tested.
+const sampleGoldenPath = "sampleclient/client.go"
+
+func loadSampleIndex(t *testing.T) *tif.Index {
+	t.Helper()
+	data, err := os.ReadFile("testdata/sample-def.json")
+	if err != nil {
+		t.Fatalf("failed to read sample definition: %v", err)
+	}
+	var def tif.TifDefinition
+	err = json.Unmarshal(data, &def)
+	if err != nil {
+		t.Fatalf("failed to parse sample definition: %v", err)
+	}
+	return tif.NewIndex(&def)
+}
+
+func TestGenerateGoGolden(t *testing.T) {
+	t.Parallel()
+
+	source, err := GenerateGo(loadSampleIndex(t), "sampleclient")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if *update {
+		err := os.WriteFile(sampleGoldenPath, source, 0644)
+		if err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	golden, err := os.ReadFile(sampleGoldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(golden, source) {
+		t.Errorf("generated code does not match %s, run go test -update if the change is intended (-golden +generated):\n%s", sampleGoldenPath, cmp.Diff(string(golden), string(source)))
+	}
+}
+
+// The generated code must compile outside of this module, so it may only import the standard library.
+func TestGenerateGoImports(t *testing.T) {
+	t.Parallel()
+
+	source, err := GenerateGo(loadSampleIndex(t), "sampleclient")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "client.go", source, parser.ImportsOnly)
+	if err != nil {
+		t.Fatalf("failed to parse generated code: %v", err)
+	}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			t.Errorf("expected only imports of the standard library but got %s", path)
+		}
+	}
+}
+
+func TestGenerateGoErrors(t *testing.T) {
+	t.Parallel()
+
+	staleType := []tif.TypeV2Definition{{Name: "tStale", Type: "uint24"}}
+	tests := map[string]struct {
+		params []tif.InputParameter
+		types  []tif.TypeV2Definition
+		// Whether the definition generates, without the broken types
+		generates bool
+	}{
+		"unknown type": {params: []tif.InputParameter{{Name: "height", Type: "tHeight"}}},
+		"parameter of a broken type": {
+			params: []tif.InputParameter{{Name: "height", Type: "tStale"}},
+			types:  staleType,
+		},
+		"unused broken type": {
+			params:    []tif.InputParameter{{Name: "height", Type: "uint8"}},
+			types:     staleType,
+			generates: true,
+		},
+		"array of integers": {params: []tif.InputParameter{{Name: "heights", Type: "uint8", Length: "4"}}},
+		"byte array before the last parameter": {params: []tif.InputParameter{
+			{Name: "key", Type: "byteArray"},
+			{Name: "crc", Type: "uint8"},
+		}},
+		"string taking the rest before the last parameter": {params: []tif.InputParameter{
+			{Name: "name", Type: "ascii", Length: "-1"},
+			{Name: "crc", Type: "uint8"},
+		}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			def := &tif.TifDefinition{
+				Methods: []tif.MethodDefinition{{
+					Family:   "Mower",
+					Command:  "SetHeight",
+					InParams: test.params,
+					Protocol: []tif.ProtocolEntry{{Key: "msgType", Value: "0x2000"}, {Key: "subCmd", Value: "1"}},
+				}},
+				TypesV2: test.types,
+			}
+			source, err := GenerateGo(tif.NewIndex(def), "client")
+			if !test.generates {
+				if err == nil {
+					t.Error("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if bytes.Contains(source, []byte("TStale")) {
+				t.Errorf("expected the broken type to be left out but got:\n%s", source)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		exported string
+		local    string
+	}{
+		"tCuttingHeight":            {exported: "TCuttingHeight", local: "tCuttingHeight"},
+		"cutting_height":            {exported: "CuttingHeight", local: "cuttingHeight"},
+		"Park until further notice": {exported: "ParkUntilFurtherNotice", local: "parkUntilFurtherNotice"},
+		"2.4GHz":                    {exported: "X24GHz", local: "x24GHz"},
+		"type":                      {exported: "Type", local: "typeArg"},
+		"ctx":                       {exported: "Ctx", local: "ctxArg"},
+	}
+	for name, test := range tests {
+		if exported := exportedName(name); exported != test.exported {
+			t.Errorf("exportedName(%q) returned %s; expected %s", name, exported, test.exported)
+		}
+		if local := localName(name); local != test.local {
+			t.Errorf("localName(%q) returned %s; expected %s", name, local, test.local)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package tifgen
+
+import (
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exportedName turns a name from a definition, such as tCuttingHeight or cutting_height,
+// into an exported Go identifier, such as TCuttingHeight or CuttingHeight.
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	ident := b.String()
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// localName turns a name from a definition into an unexported Go identifier,
+// that does not shadow the identifiers used by generated method bodies.
+func localName(name string) string {
+	runes := []rune(exportedName(name))
+	runes[0] = unicode.ToLower(runes[0])
+	ident := string(runes)
+	if token.IsKeyword(ident) || reservedLocals[ident] {
+		return ident + "Arg"
+	}
+	return ident
+}
+
+// Identifiers in scope of the bodies of generated methods.
+var reservedLocals = map[string]bool{
+	"ctx":      true,
+	"f":        true,
+	"e":        true,
+	"d":        true,
+	"params":   true,
+	"response": true,
+	"err":      true,
+	"method":   true,
+	"encoder":  true,
+	"decoder":  true,
+	"binary":   true,
+	"errors":   true,
+	"math":     true,
+	"utf16":    true,
+	"time":     true,
+	"fmt":      true,
+	"context":  true,
+}
+
+// nameSet hands out unique identifiers, numbering names that are already taken.
+type nameSet map[string]bool
+
+func (names nameSet) unique(ident string) string {
+	unique := ident
+	for i := 2; names[unique]; i++ {
+		unique = ident + strconv.Itoa(i)
+	}
+	names[unique] = true
+	return unique
+}
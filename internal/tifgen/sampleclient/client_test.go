@@ -0,0 +1,164 @@
+package sampleclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Tifufu/tools-cli/internal/automower"
+	"github.com/Tifufu/tools-cli/internal/emulator"
+	"github.com/Tifufu/tools-cli/internal/protocol/linking"
+	"github.com/Tifufu/tools-cli/internal/tif"
+	"github.com/Tifufu/tools-cli/internal/tifgen/sampleclient"
+	"github.com/charmbracelet/log"
+	"github.com/google/go-cmp/cmp"
+)
+
+// openSampleClient connects a generated client to an emulator of the sample definition.
+func openSampleClient(t *testing.T, opts ...emulator.Option) *sampleclient.Client {
+	t.Helper()
+
+	data, err := os.ReadFile("../testdata/sample-def.json")
+	if err != nil {
+		t.Fatalf("failed to read sample definition: %v", err)
+	}
+	var def tif.TifDefinition
+	err = json.Unmarshal(data, &def)
+	if err != nil {
+		t.Fatalf("failed to parse sample definition: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	opts = append(opts, emulator.WithDefinition(&def))
+	served := make(chan error, 1)
+	go func() {
+		served <- emulator.NewEmulator(log.New(io.Discard), opts...).Serve(ctx, listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to emulator: %v", err)
+	}
+	device := automower.NewDevice(conn, context.Background())
+	mux := linking.NewLinkMux(device, log.New(io.Discard))
+	go mux.Start()
+	t.Cleanup(func() {
+		mux.Stop()
+		cancel()
+		<-served
+	})
+
+	openCtx, openCancel := context.WithTimeout(context.Background(), time.Second)
+	defer openCancel()
+	link, err := mux.OpenLink(openCtx)
+	if err != nil {
+		t.Fatalf("expected no error opening link but got %v", err)
+	}
+	err = link.SetProtocol(openCtx, linking.RoboticsProtocol2)
+	if err != nil {
+		t.Fatalf("expected no error setting protocol but got %v", err)
+	}
+	return sampleclient.NewClient(link)
+}
+
+func TestGeneratedClient(t *testing.T) {
+	t.Parallel()
+
+	status := []byte{
+		0x01,                   // mode
+		0x28,                   // height
+		0x50,                   // battery
+		0x00, 0x00, 0x00, 0x65, // since
+	}
+	status = append(status, []byte("ABC12345")...)
+	info := []byte{
+		0x03, 0x02, // version
+		0xE8, 0x07, 5, 17, 13, 45, 30, // built
+		0x05,                   // flags
+		0x00, 0x00, 0xC0, 0x3F, // temperature
+		0x01, 0x00, 0x00, 0x00, // uptime
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // counter
+		0xFF,       // offset
+		0xFE, 0xFF, // drift
+		0xFD, 0xFF, 0xFF, 0xFF, // delta
+		0xFC, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // total
+		0x93, 0x10, // port
+		0xDE, 0xAD, // key
+	}
+	client := openSampleClient(t,
+		emulator.WithResponses("Mower.GetStatus", emulator.Response{Params: status}),
+		emulator.WithResponses("Mower.SetMode", emulator.Response{}),
+		emulator.WithResponses("Mower.SetName", emulator.Response{Status: 0x02}),
+		emulator.WithResponses("System.GetInfo", emulator.Response{Params: info}),
+		emulator.WithResponses("LinkManager.GetNodeInfo", emulator.Response{Params: []byte{0x07}}),
+	)
+	ctx := context.Background()
+
+	gotStatus, err := client.Mower.GetStatus(ctx)
+	if err != nil {
+		t.Fatalf("expected no error getting status but got %v", err)
+	}
+	expectedStatus := sampleclient.MowerGetStatusResponse{
+		Mode:    sampleclient.TModeManual,
+		Height:  40,
+		Battery: 80,
+		Since:   time.Unix(0x65000000, 0),
+		Serial:  "ABC12345",
+	}
+	if diff := cmp.Diff(expectedStatus, gotStatus); diff != "" {
+		t.Errorf("GetStatus mismatch (-expected +got):\n%s", diff)
+	}
+	if gotStatus.Mode.String() != "Manual" {
+		t.Errorf("expected mode to print as Manual but got %s", gotStatus.Mode)
+	}
+
+	err = client.Mower.SetMode(ctx, sampleclient.TModeAuto)
+	if err != nil {
+		t.Errorf("expected no error setting mode but got %v", err)
+	}
+
+	_, err = client.Mower.SetName(ctx, "Robin", 1)
+	var statusErr *sampleclient.ErrStatus
+	if !errors.As(err, &statusErr) || statusErr.Status != 0x02 {
+		t.Errorf("expected error status 2 setting name but got %v", err)
+	}
+
+	gotInfo, err := client.System.GetInfo(ctx)
+	if err != nil {
+		t.Fatalf("expected no error getting info but got %v", err)
+	}
+	expectedInfo := sampleclient.SystemGetInfoResponse{
+		Version:     0x0203,
+		Built:       time.Date(2024, 5, 17, 13, 45, 30, 0, time.UTC),
+		Flags:       0x05,
+		Temperature: 1.5,
+		Uptime:      1,
+		Counter:     2,
+		Offset:      -1,
+		Drift:       -2,
+		Delta:       -3,
+		Total:       -4,
+		Port:        4243,
+		Key:         []byte{0xDE, 0xAD},
+	}
+	if diff := cmp.Diff(expectedInfo, gotInfo); diff != "" {
+		t.Errorf("GetInfo mismatch (-expected +got):\n%s", diff)
+	}
+
+	gotNode, err := client.LinkManager.GetNodeInfo(ctx)
+	if err != nil {
+		t.Fatalf("expected no error getting node info but got %v", err)
+	}
+	if gotNode.NodeType != 7 {
+		t.Errorf("expected node type 7 but got %d", gotNode.NodeType)
+	}
+}
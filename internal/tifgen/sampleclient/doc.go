@@ -0,0 +1,3 @@
+// Package sampleclient is generated by tifgen from testdata/sample-def.json,
+// as the golden file of the generator and to test the generated code against an emulator.
+package sampleclient
@@ -0,0 +1,93 @@
+// Searches the index in search-index.js as the query is typed, ranking names that start with it first.
+(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("search-results");
+  var index = window.tifSearchIndex || [];
+  var maxResults = 50;
+  var selected = -1;
+
+  function score(entry, terms) {
+    var name = entry.name.toLowerCase();
+    var text = name + " " + (entry.description || "").toLowerCase();
+    var total = 0;
+    for (var i = 0; i < terms.length; i++) {
+      var at = name.indexOf(terms[i]);
+      if (at === 0 || name.charAt(at - 1) === ".") {
+        total += 3;
+      } else if (at > 0) {
+        total += 2;
+      } else if (text.indexOf(terms[i]) >= 0) {
+        total += 1;
+      } else {
+        return 0;
+      }
+    }
+    return total;
+  }
+
+  function render() {
+    var terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.innerHTML = "";
+    selected = -1;
+    if (terms.length === 0) {
+      return;
+    }
+    var matches = [];
+    for (var i = 0; i < index.length; i++) {
+      var s = score(index[i], terms);
+      if (s > 0) {
+        matches.push({ entry: index[i], score: s });
+      }
+    }
+    matches.sort(function (a, b) {
+      return b.score - a.score || a.entry.name.length - b.entry.name.length;
+    });
+    matches.slice(0, maxResults).forEach(function (match) {
+      var item = document.createElement("li");
+      var link = document.createElement("a");
+      link.href = match.entry.href;
+      link.textContent = match.entry.name;
+      var kind = document.createElement("span");
+      kind.className = "kind";
+      kind.textContent = match.entry.kind;
+      link.appendChild(kind);
+      if (match.entry.description) {
+        var description = document.createElement("span");
+        description.className = "description";
+        description.textContent = match.entry.description;
+        link.appendChild(description);
+      }
+      item.appendChild(link);
+      results.appendChild(item);
+    });
+  }
+
+  function select(i) {
+    var items = results.children;
+    if (items.length === 0) {
+      return;
+    }
+    if (selected >= 0) {
+      items[selected].className = "";
+    }
+    selected = (i + items.length) % items.length;
+    items[selected].className = "selected";
+    items[selected].scrollIntoView({ block: "nearest" });
+  }
+
+  input.addEventListener("input", render);
+  input.addEventListener("keydown", function (e) {
+    if (e.key === "ArrowDown") {
+      select(selected + 1);
+      e.preventDefault();
+    } else if (e.key === "ArrowUp") {
+      select(selected - 1);
+      e.preventDefault();
+    } else if (e.key === "Enter" && results.children.length > 0) {
+      window.location.href = results.children[Math.max(selected, 0)].firstChild.href;
+    } else if (e.key === "Escape") {
+      input.value = "";
+      render();
+    }
+  });
+})();
@@ -69,7 +69,16 @@ func (r *BundleRegistry) FetchBundleTypes(ctx context.Context) ([]BundleType, er
 }
 
 func (r *BundleRegistry) FetchLatestRelease(ctx context.Context, bundleType string) (*Build, error) {
-	url := fmt.Sprintf("%s/bundles/indexes/%s?count=1", r.baseUrl, bundleType)
+	builds, err := r.FetchReleases(ctx, bundleType, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &builds[0], nil
+}
+
+// FetchReleases returns up to count of the latest builds of a bundle type, newest first.
+func (r *BundleRegistry) FetchReleases(ctx context.Context, bundleType string, count int) ([]Build, error) {
+	url := fmt.Sprintf("%s/bundles/indexes/%s?count=%d", r.baseUrl, bundleType, count)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -100,8 +109,10 @@ func (r *BundleRegistry) FetchLatestRelease(ctx context.Context, bundleType stri
 		return nil, errors.New("no builds found")
 	}
 
-	builds[0].BlobUrl = fmt.Sprintf("%s/bundles/blob/%s", r.baseUrl, builds[0].BlobUrl)
-	return &builds[0], nil
+	for i := range builds {
+		builds[i].BlobUrl = fmt.Sprintf("%s/bundles/blob/%s", r.baseUrl, builds[i].BlobUrl)
+	}
+	return builds, nil
 }
 
 func FilterBundleTypes(types []BundleType, platform Platform) []BundleType {
@@ -12,6 +12,12 @@ import (
 )
 
 func DownloadAndUnpack(req *http.Request, client *http.Client, dest string) error {
+	return DownloadAndUnpackMatching(req, client, dest, nil)
+}
+
+// DownloadAndUnpackMatching downloads a zip archive and unpacks the files for which match
+// returns true, given their path in the archive. A nil match unpacks every file.
+func DownloadAndUnpackMatching(req *http.Request, client *http.Client, dest string, match func(name string) bool) error {
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Println(err)
@@ -39,7 +45,7 @@ func DownloadAndUnpack(req *http.Request, client *http.Client, dest string) erro
 		return err
 	}
 
-	err = Unzip(tmpFile.Name(), dest)
+	err = UnzipMatching(tmpFile.Name(), dest, match)
 	if err != nil {
 		return err
 	}
@@ -48,6 +54,12 @@ func DownloadAndUnpack(req *http.Request, client *http.Client, dest string) erro
 }
 
 func Unzip(zipFile string, dest string) error {
+	return UnzipMatching(zipFile, dest, nil)
+}
+
+// UnzipMatching unpacks the files for which match returns true, given their path in the archive.
+// A nil match unpacks every file.
+func UnzipMatching(zipFile string, dest string, match func(name string) bool) error {
 	archive, err := zip.OpenReader(zipFile)
 	if err != nil {
 		return err
@@ -55,6 +67,10 @@ func Unzip(zipFile string, dest string) error {
 	defer archive.Close()
 
 	for _, file := range archive.File {
+		// Directories of matching files are created along with them
+		if match != nil && (file.FileInfo().IsDir() || !match(file.Name)) {
+			continue
+		}
 		outputPath := filepath.Join(dest, file.Name)
 
 		// Check for ZipSlip (Directory traversal)
@@ -0,0 +1,366 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// Number of the latest builds searched for a firmware version that is not cached.
+const maxDefinitionBuilds = 10
+
+// Name of the file in the cache dir of a platform that maps build ids to the version of their
+// definition, so that builds are only downloaded once. Builds without a definition map to "".
+const definitionBuildsFile = "builds.json"
+
+type TifDefinitionRegistry struct {
+	CacheDir         string
+	bundleRegistry   *BundleRegistry
+	winMowerRegistry *WinMowerRegistry
+	client           http.Client
+	logger           *log.Logger
+}
+
+// TifDefinition is the tif definition file of a firmware version.
+type TifDefinition struct {
+	Path     string
+	Platform Platform
+	Version  string
+}
+
+func NewTifDefinitionRegistry(cacheDir string, bundleRegistry *BundleRegistry, winMowerRegistry *WinMowerRegistry, logger *log.Logger) *TifDefinitionRegistry {
+	return &TifDefinitionRegistry{
+		CacheDir:         cacheDir,
+		bundleRegistry:   bundleRegistry,
+		winMowerRegistry: winMowerRegistry,
+		client:           *http.DefaultClient,
+		logger:           logger,
+	}
+}
+
+func (r *TifDefinitionRegistry) WithClient(client http.Client) {
+	r.client = client
+}
+
+// FetchDefinition returns the definition of a firmware version of platform, such as 47.35,
+// or of the latest version if version is empty. Versions match exactly or by prefix, so 47
+// matches 47.35. The definition in a downloaded WinMower bundle is used if its version matches,
+// then definitions cached by earlier fetches, and only then are the latest bundles downloaded.
+// Bundles are downloaded once, and the latest version falls back to the newest cached one when
+// the latest bundles can not be listed.
+func (r *TifDefinitionRegistry) FetchDefinition(ctx context.Context, platform Platform, version string) (*TifDefinition, error) {
+	def, err := r.GetWinMowerDefinition(ctx, platform, version)
+	if err != nil {
+		r.logger.Debug("Could not use the definition of the winmower bundle", "err", err)
+	}
+	if def != nil {
+		r.logger.Debug("Using definition of the winmower bundle", "version", def.Version)
+		return def, nil
+	}
+
+	// The latest version is not known without asking the registry
+	if version != "" {
+		def, err = r.GetCachedDefinition(platform, version)
+		if err != nil {
+			return nil, err
+		}
+		if def != nil {
+			r.logger.Debug("Using cached definition", "version", def.Version)
+			return def, nil
+		}
+	}
+
+	def, err = r.downloadDefinition(ctx, platform, version)
+	if err == nil || version != "" {
+		return def, err
+	}
+	cached, cacheErr := r.GetCachedDefinition(platform, "")
+	if cacheErr != nil || cached == nil {
+		return nil, err
+	}
+	r.logger.Warn("Could not fetch the latest definition, using the newest cached one", "version", cached.Version, "err", err)
+	return cached, nil
+}
+
+// GetWinMowerDefinition returns the definition shipped in the downloaded WinMower bundle of
+// platform, if there is one of a matching version.
+func (r *TifDefinitionRegistry) GetWinMowerDefinition(ctx context.Context, platform Platform, version string) (*TifDefinition, error) {
+	if r.winMowerRegistry == nil {
+		return nil, nil
+	}
+	wm, err := r.winMowerRegistry.GetCachedWinMower(platform, ctx)
+	if err != nil || wm == nil {
+		return nil, err
+	}
+	return r.bundleDefinition(filepath.Dir(wm.Path), platform, version)
+}
+
+// GetCachedDefinition returns the newest cached definition of platform matching version.
+func (r *TifDefinitionRegistry) GetCachedDefinition(platform Platform, version string) (*TifDefinition, error) {
+	entries, err := os.ReadDir(filepath.Join(r.CacheDir, platform.String()))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions are directories, searched newest first
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return compareVersions(b.Name(), a.Name()) })
+	for _, entry := range entries {
+		if !entry.IsDir() || !matchesVersion(entry.Name(), version) {
+			continue
+		}
+		def, err := r.bundleDefinition(filepath.Join(r.CacheDir, platform.String(), entry.Name()), platform, version)
+		if err != nil {
+			r.logger.Debug("Skipping broken cached definition", "version", entry.Name(), "err", err)
+			continue
+		}
+		if def != nil {
+			return def, nil
+		}
+	}
+	return nil, nil
+}
+
+// downloadDefinition searches the latest bundles of platform for version, caching the definition of each.
+func (r *TifDefinitionRegistry) downloadDefinition(ctx context.Context, platform Platform, version string) (*TifDefinition, error) {
+	btypes, err := r.bundleRegistry.FetchBundleTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	btypes = FilterBundleTypes(btypes, platform)
+	if len(btypes) == 0 {
+		return nil, fmt.Errorf("no bundle types found for platform %s", platform)
+	}
+
+	// Endpoint returns them sorted by date, like for winmowers
+	builds, err := r.bundleRegistry.FetchReleases(ctx, btypes[0].Name, maxDefinitionBuilds)
+	if err != nil {
+		return nil, err
+	}
+
+	known := r.readDefinitionBuilds(platform)
+	for _, build := range builds {
+		def, err := r.buildDefinition(ctx, platform, build, known)
+		if err != nil {
+			r.logger.Warn("Skipping bundle", "build", build.Id, "err", err)
+			continue
+		}
+		if def == nil {
+			r.logger.Debug("Bundle has no definition", "build", build.Id)
+			continue
+		}
+		if matchesVersion(def.Version, version) {
+			return def, nil
+		}
+		r.logger.Debug("Skipping bundle of another version", "build", build.Id, "version", def.Version)
+	}
+
+	if version == "" {
+		return nil, fmt.Errorf("none of the latest %d bundles of platform %s has a tif definition", len(builds), platform)
+	}
+	return nil, fmt.Errorf("none of the latest %d bundles of platform %s has version %s", len(builds), platform, version)
+}
+
+// buildDefinition returns the definition of a build, from the cache if the build was downloaded before.
+// Downloaded builds are added to known, the build ids and versions of builds that were downloaded.
+func (r *TifDefinitionRegistry) buildDefinition(ctx context.Context, platform Platform, build Build, known map[string]string) (*TifDefinition, error) {
+	if version, ok := known[build.Id]; ok {
+		if version == "" {
+			return nil, nil
+		}
+		def, err := r.bundleDefinition(filepath.Join(r.CacheDir, platform.String(), version), platform, version)
+		if err == nil && def != nil {
+			return def, nil
+		}
+		r.logger.Debug("Cached bundle is gone, downloading it again", "build", build.Id, "version", version, "err", err)
+	}
+
+	def, err := r.downloadBuildDefinition(ctx, platform, build)
+	if err != nil {
+		return nil, err
+	}
+	known[build.Id] = ""
+	if def != nil {
+		known[build.Id] = def.Version
+	}
+	err = r.writeDefinitionBuilds(platform, known)
+	if err != nil {
+		r.logger.Debug("Could not remember downloaded bundle", "build", build.Id, "err", err)
+	}
+	return def, nil
+}
+
+// readDefinitionBuilds returns the builds of platform downloaded before. A missing
+// or broken file only means that builds are downloaded again.
+func (r *TifDefinitionRegistry) readDefinitionBuilds(platform Platform) map[string]string {
+	known := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(r.CacheDir, platform.String(), definitionBuildsFile))
+	if err != nil {
+		return known
+	}
+	err = json.Unmarshal(data, &known)
+	if err != nil {
+		r.logger.Debug("Ignoring broken list of downloaded bundles", "err", err)
+		return make(map[string]string)
+	}
+	return known
+}
+
+// writeDefinitionBuilds writes a temporary file first, so that a concurrent fetch never reads a partially written file.
+func (r *TifDefinitionRegistry) writeDefinitionBuilds(platform Platform, known map[string]string) error {
+	data, err := json.MarshalIndent(known, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.CacheDir, platform.String(), definitionBuildsFile)
+	temp, err := os.CreateTemp(filepath.Dir(path), definitionBuildsFile+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// downloadBuildDefinition unpacks the manifest and definition of a build into the cache dir of its version.
+func (r *TifDefinitionRegistry) downloadBuildDefinition(ctx context.Context, platform Platform, build Build) (*TifDefinition, error) {
+	platformDir := filepath.Join(r.CacheDir, platform.String())
+	err := os.MkdirAll(platformDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	// Unpacked next to the cache, so that it can be moved in place once its version is known
+	tmpDir, err := os.MkdirTemp(platformDir, "download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", build.BlobUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debug("Downloading bundle", "build", build.Id)
+	err = DownloadAndUnpackMatching(req, &r.client, tmpDir, func(name string) bool {
+		return name == "index.json" || isDefinitionFile(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := decodeManifest(filepath.Join(tmpDir, "index.json"), r.logger)
+	if err != nil {
+		return nil, err
+	}
+	definitionPath, err := findDefinitionFile(tmpDir)
+	if err != nil || definitionPath == "" {
+		return nil, err
+	}
+
+	versionDir := filepath.Join(platformDir, manifest.Metadata.Version)
+	if manifest.Metadata.Version == "" || filepath.Base(versionDir) != manifest.Metadata.Version {
+		return nil, fmt.Errorf("bundle %s has an invalid version %q", build.Id, manifest.Metadata.Version)
+	}
+	if _, err := os.Stat(versionDir); errors.Is(err, fs.ErrNotExist) {
+		err = os.Rename(tmpDir, versionDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.bundleDefinition(versionDir, platform, manifest.Metadata.Version)
+}
+
+// bundleDefinition returns the definition in an unpacked bundle, if the bundle has one of a matching version.
+func (r *TifDefinitionRegistry) bundleDefinition(dir string, platform Platform, version string) (*TifDefinition, error) {
+	manifest, err := decodeManifest(filepath.Join(dir, "index.json"), r.logger)
+	if err != nil {
+		return nil, err
+	}
+	if !matchesVersion(manifest.Metadata.Version, version) {
+		return nil, nil
+	}
+	definitionPath, err := findDefinitionFile(dir)
+	if err != nil || definitionPath == "" {
+		return nil, err
+	}
+	return &TifDefinition{
+		Path:     definitionPath,
+		Platform: platform,
+		Version:  manifest.Metadata.Version,
+	}, nil
+}
+
+// isDefinitionFile reports whether a file of a bundle is its tif definition,
+// which bundles ship as a JSON file with tif in its name.
+func isDefinitionFile(name string) bool {
+	base := strings.ToLower(path.Base(filepath.ToSlash(name)))
+	return base != "index.json" && path.Ext(base) == ".json" && strings.Contains(base, "tif")
+}
+
+// findDefinitionFile returns the path of the definition in an unpacked bundle, or an empty path if it has none.
+// If there are several, the one closest to the root of the bundle is used.
+func findDefinitionFile(dir string) (string, error) {
+	var found []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isDefinitionFile(path) {
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil || len(found) == 0 {
+		return "", err
+	}
+	slices.SortStableFunc(found, func(a, b string) int {
+		return strings.Count(a, string(filepath.Separator)) - strings.Count(b, string(filepath.Separator))
+	})
+	return found[0], nil
+}
+
+// matchesVersion reports whether version is a wanted version, either exactly or by
+// prefix such as 47 of 47.35. Every version matches an empty wanted version.
+func matchesVersion(version, wanted string) bool {
+	return wanted == "" || version == wanted || strings.HasPrefix(version, wanted+".")
+}
+
+// compareVersions orders dotted versions by their numeric parts, so that 47.9 comes before 47.35.
+func compareVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(aParts), len(bParts)) {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		if aErr == nil && bErr == nil && aNum != bNum {
+			return aNum - bNum
+		}
+		if aErr != nil || bErr != nil {
+			if c := strings.Compare(aParts[i], bParts[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(aParts) - len(bParts)
+}
@@ -0,0 +1,291 @@
+package pkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchesVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		version  string
+		wanted   string
+		expected bool
+	}{
+		"exact":            {version: "47.35", wanted: "47.35", expected: true},
+		"prefix":           {version: "47.35", wanted: "47", expected: true},
+		"longer prefix":    {version: "47.35.2", wanted: "47.35", expected: true},
+		"latest":           {version: "47.35", wanted: "", expected: true},
+		"prefix of number": {version: "470.1", wanted: "47", expected: false},
+		"partial number":   {version: "47.35", wanted: "47.3", expected: false},
+		"more specific":    {version: "47", wanted: "47.35", expected: false},
+		"other version":    {version: "48.1", wanted: "47", expected: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			if got := matchesVersion(test.version, test.wanted); got != test.expected {
+				t.Errorf("expected matchesVersion(%s, %s) to be %t but got %t", test.version, test.wanted, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"47.35", "48", "47.9", "47.35.1", "470.1", "47", "47.beta", "47.10"}
+	slices.SortFunc(versions, compareVersions)
+
+	expected := []string{"47", "47.9", "47.10", "47.35", "47.35.1", "47.beta", "48", "470.1"}
+	if diff := cmp.Diff(expected, versions); diff != "" {
+		t.Errorf("sorted versions mismatch (-expected +got):\n%s", diff)
+	}
+	if c := compareVersions("47.35", "47.35"); c != 0 {
+		t.Errorf("expected equal versions to compare 0 but got %d", c)
+	}
+}
+
+// writeBundle writes the manifest and definition of an unpacked bundle of version to dir.
+func writeBundle(t *testing.T, dir string, version string) {
+	t.Helper()
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "index.json"), []byte(fmt.Sprintf(`{"metadata": {"version": %q}}`, version)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "tif-definition.json"), []byte(`{"methods": []}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// bundleZip returns a zipped bundle of version with a definition.
+func bundleZip(t *testing.T, version string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := map[string]string{
+		"index.json":               fmt.Sprintf(`{"metadata": {"version": %q}}`, version),
+		"data/tif-definition.json": `{"methods": []}`,
+		"data/firmware.bin":        "firmware",
+	}
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	err := archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// bundleServer serves the bundle types and latest builds of P25, newest first, and the blobs of builds.
+// Builds without a blob fail to download. It counts the requests it answers by path.
+type bundleServer struct {
+	*httptest.Server
+	builds      []Build
+	blobs       map[string][]byte
+	failListing bool
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newBundleServer(t *testing.T, builds []Build, blobs map[string][]byte) *bundleServer {
+	t.Helper()
+
+	s := &bundleServer{builds: builds, blobs: blobs, requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.mu.Unlock()
+
+		switch {
+		case s.failListing:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case r.URL.Path == "/bundles/types":
+			fmt.Fprint(w, `[{"id": "1", "name": "Bundle-P25-Win"}]`)
+		case r.URL.Path == "/bundles/indexes/Bundle-P25-Win":
+			fmt.Fprint(w, "[")
+			for i, build := range s.builds {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"id": %q, "blob": %q}`, build.Id, build.BlobUrl)
+			}
+			fmt.Fprint(w, "]")
+		case strings.HasPrefix(r.URL.Path, "/bundles/blob/"):
+			blob, ok := s.blobs[strings.TrimPrefix(r.URL.Path, "/bundles/blob/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(blob)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requestCount returns the number of requests answered for path, or for any path if path is empty.
+func (s *bundleServer) requestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if path != "" {
+		return s.requests[path]
+	}
+	count := 0
+	for _, n := range s.requests {
+		count += n
+	}
+	return count
+}
+
+func TestFetchDefinition(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		// Versions of the bundles in the cache before fetching
+		cached []string
+		// Versions of the bundles whose index.json is broken in the cache
+		broken      []string
+		builds      []Build
+		failListing bool
+		version     string
+		expected    string
+		// Number of requests to the registry
+		requests int
+	}{
+		"cache hit": {
+			cached:   []string{"47.9", "47.35"},
+			builds:   []Build{{Id: "b1", BlobUrl: "b1"}},
+			version:  "47",
+			expected: "47.35",
+			requests: 0,
+		},
+		"broken cached bundle": {
+			cached:   []string{"47.35"},
+			broken:   []string{"47.40"},
+			builds:   []Build{{Id: "b1", BlobUrl: "b1"}},
+			version:  "47",
+			expected: "47.35",
+			requests: 0,
+		},
+		"download": {
+			builds:   []Build{{Id: "b2", BlobUrl: "b2"}, {Id: "b1", BlobUrl: "b1"}},
+			version:  "47.35",
+			expected: "47.35",
+			requests: 4,
+		},
+		"broken download": {
+			builds:   []Build{{Id: "b3", BlobUrl: "missing"}, {Id: "b2", BlobUrl: "b2"}},
+			expected: "48.1",
+			requests: 4,
+		},
+		"latest falls back to cache": {
+			cached:      []string{"47.35", "47.9"},
+			builds:      []Build{{Id: "b2", BlobUrl: "b2"}},
+			failListing: true,
+			expected:    "47.35",
+			requests:    1,
+		},
+	}
+
+	blobs := map[string][]byte{
+		"b1": bundleZip(t, "47.35"),
+		"b2": bundleZip(t, "48.1"),
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test := test
+			t.Parallel()
+
+			server := newBundleServer(t, test.builds, blobs)
+			server.failListing = test.failListing
+			cacheDir := t.TempDir()
+			for _, version := range test.cached {
+				writeBundle(t, filepath.Join(cacheDir, P25.String(), version), version)
+			}
+			for _, version := range test.broken {
+				dir := filepath.Join(cacheDir, P25.String(), version)
+				writeBundle(t, dir, version)
+				os.WriteFile(filepath.Join(dir, "index.json"), []byte("{"), 0644)
+			}
+
+			registry := NewTifDefinitionRegistry(cacheDir, NewBundleRegistry(server.URL), nil, log.New(io.Discard))
+			def, err := registry.FetchDefinition(context.Background(), P25, test.version)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if def.Version != test.expected {
+				t.Errorf("expected version %s but got %s", test.expected, def.Version)
+			}
+			if def.Platform != P25 || filepath.Base(def.Path) != "tif-definition.json" {
+				t.Errorf("expected the definition of P25 but got %+v", def)
+			}
+
+			if requests := server.requestCount(""); requests != test.requests {
+				t.Errorf("expected %d requests to the registry but got %d", test.requests, requests)
+			}
+		})
+	}
+}
+
+func TestFetchDefinitionDownloadsBundlesOnce(t *testing.T) {
+	t.Parallel()
+
+	builds := []Build{{Id: "b2", BlobUrl: "b2"}, {Id: "b1", BlobUrl: "b1"}}
+	server := newBundleServer(t, builds, map[string][]byte{"b1": bundleZip(t, "47.35"), "b2": bundleZip(t, "48.1")})
+	registry := NewTifDefinitionRegistry(t.TempDir(), NewBundleRegistry(server.URL), nil, log.New(io.Discard))
+
+	// The latest bundle is downloaded by the first fetch, the other one only when searching for 46
+	for range 2 {
+		def, err := registry.FetchDefinition(context.Background(), P25, "")
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if def.Version != "48.1" {
+			t.Errorf("expected the latest version 48.1 but got %s", def.Version)
+		}
+	}
+	def, err := registry.FetchDefinition(context.Background(), P25, "46")
+	if err == nil {
+		t.Errorf("expected no definition of version 46 but got %+v", def)
+	}
+
+	for _, blob := range []string{"b1", "b2"} {
+		if count := server.requestCount("/bundles/blob/" + blob); count != 1 {
+			t.Errorf("expected bundle %s to be downloaded once but it was downloaded %d times", blob, count)
+		}
+	}
+}